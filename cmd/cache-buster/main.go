@@ -20,16 +20,18 @@ var rootCmd = &cobra.Command{
 	RunE:    runRoot,
 }
 
-func runRoot(_ *cobra.Command, _ []string) error {
-	loader := config.NewLoader()
-	return cli.RunInteractiveWithLoader(loader, false, true)
+func runRoot(cmd *cobra.Command, _ []string) error {
+	return cli.RunInteractiveWithLoader(cli.NewLoader(cmd), false, true)
 }
 
 func init() {
+	rootCmd.PersistentFlags().String(cli.ConfigFlag, "",
+		"config file (default $"+config.EnvConfig+", $"+config.EnvXDGConfigHome+"/cache-buster/config.yaml, or ~/.config/cache-buster/config.yaml)")
 	rootCmd.AddCommand(cli.StatusCmd)
 	rootCmd.AddCommand(cli.CleanCmd)
 	rootCmd.AddCommand(cli.ConfigCmd)
 	rootCmd.AddCommand(cli.InteractiveCmd)
+	rootCmd.AddCommand(cli.TrackCmd)
 }
 
 func main() {
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AccessLog records when files were last opened, and how often, as Tracker
+// sees it. It is saved as a gob-encoded map of paths to accessRecord. An
+// AccessLog is not safe for concurrent use; a nil AccessLog records nothing.
+type AccessLog struct {
+	opened map[string]accessRecord
+	path   string
+	dirty  bool
+}
+
+// accessRecord is what an AccessLog keeps per file.
+type accessRecord struct {
+	Last  int64 // Unix seconds of the latest open
+	Opens int   // opens seen
+}
+
+// LoadAccessLog reads the access log at path. A missing file is an empty log.
+func LoadAccessLog(path string) (*AccessLog, error) {
+	log := &AccessLog{path: path, opened: make(map[string]accessRecord)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return log, nil
+		}
+		return nil, fmt.Errorf("read access log: %w", err)
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&log.opened); err != nil {
+		return nil, fmt.Errorf("decode access log %s: %w", path, err)
+	}
+	return log, nil
+}
+
+// Len returns how many files the log has a time for.
+func (l *AccessLog) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.opened)
+}
+
+// LastOpened returns when path was last seen opened.
+func (l *AccessLog) LastOpened(path string) (time.Time, bool) {
+	if l == nil {
+		return time.Time{}, false
+	}
+	rec, ok := l.opened[path]
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(rec.Last, 0), true
+}
+
+// Opens returns how often path was seen opened.
+func (l *AccessLog) Opens(path string) int {
+	if l == nil {
+		return 0
+	}
+	return l.opened[path].Opens
+}
+
+// Record notes that path was opened at t.
+func (l *AccessLog) Record(path string, t time.Time) {
+	rec := l.opened[path]
+	rec.Last = max(rec.Last, t.Unix())
+	rec.Opens++
+	l.opened[path] = rec
+	l.dirty = true
+}
+
+// Forget drops path, once the file is gone.
+func (l *AccessLog) Forget(path string) {
+	if _, ok := l.opened[path]; ok {
+		delete(l.opened, path)
+		l.dirty = true
+	}
+}
+
+// Apply moves the LastUsed of files up to when they were last opened, where
+// the log saw a later use than their file times show, and sets their Opens.
+func (l *AccessLog) Apply(files []FileInfo) {
+	if l.Len() == 0 {
+		return
+	}
+	for i := range files {
+		rec, ok := l.opened[files[i].Path]
+		if !ok {
+			continue
+		}
+		if t := time.Unix(rec.Last, 0); t.After(files[i].LastUsed) {
+			files[i].LastUsed = t
+		}
+		files[i].Opens = rec.Opens
+	}
+}
+
+// Save writes the log back if it changed, replacing the file atomically so
+// cleans reading it never see a partial write.
+func (l *AccessLog) Save() error {
+	if !l.dirty {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(l.opened); err != nil {
+		return fmt.Errorf("encode access log: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("save access log: %w", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("save access log: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("save access log: %w", err)
+	}
+	l.dirty = false
+	return nil
+}
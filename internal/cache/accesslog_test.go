@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLog_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "access.db")
+	accessLog, err := LoadAccessLog(path)
+	require.NoError(t, err)
+	assert.Zero(t, accessLog.Len())
+
+	opened := time.Now().Truncate(time.Second)
+	accessLog.Record("/cache/a.whl", opened)
+	accessLog.Record("/cache/a.whl", opened.Add(-time.Hour))
+	accessLog.Record("/cache/b.whl", opened)
+	accessLog.Forget("/cache/b.whl")
+	require.NoError(t, accessLog.Save())
+
+	loaded, err := LoadAccessLog(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loaded.Len())
+	got, ok := loaded.LastOpened("/cache/a.whl")
+	assert.True(t, ok)
+	assert.True(t, got.Equal(opened), "the latest open wins")
+	assert.Equal(t, 2, loaded.Opens("/cache/a.whl"))
+	_, ok = loaded.LastOpened("/cache/b.whl")
+	assert.False(t, ok)
+
+	require.NoError(t, os.WriteFile(path, []byte("not gob"), 0o600))
+	_, err = LoadAccessLog(path)
+	require.ErrorContains(t, err, "decode access log")
+}
+
+func TestAccessLog_Apply(t *testing.T) {
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour).Truncate(time.Second)
+	files := []FileInfo{
+		{Path: "/cache/used.jar", LastUsed: old},
+		{Path: "/cache/unused.jar", LastUsed: old},
+		{Path: "/cache/touched.jar", LastUsed: time.Now()},
+	}
+
+	accessLog, err := LoadAccessLog(filepath.Join(t.TempDir(), "access.db"))
+	require.NoError(t, err)
+	accessLog.Record("/cache/used.jar", recent)
+	accessLog.Record("/cache/touched.jar", recent)
+	accessLog.Apply(files)
+
+	assert.True(t, files[0].LastUsed.Equal(recent))
+	assert.True(t, files[1].LastUsed.Equal(old))
+	assert.True(t, files[2].LastUsed.After(recent), "a later file time wins")
+	assert.Equal(t, 1, files[0].Opens)
+	assert.Zero(t, files[1].Opens)
+
+	var none *AccessLog
+	none.Apply(files)
+	_, ok := none.LastOpened("/cache/used.jar")
+	assert.False(t, ok)
+}
+
+func TestTrim_AccessLog(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, filepath.Join(dir, "used.jar"), 1000, 40*24*time.Hour)
+	createTestFile(t, filepath.Join(dir, "unused.jar"), 1000, 40*24*time.Hour)
+
+	accessLog, err := LoadAccessLog(filepath.Join(t.TempDir(), "access.db"))
+	require.NoError(t, err)
+	accessLog.Record(filepath.Join(dir, "used.jar"), time.Now())
+
+	result, err := Trim(t.Context(), []string{dir}, TrimOptions{
+		MaxSize:   10000,
+		MaxAge:    30 * 24 * time.Hour,
+		AccessLog: accessLog,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.DeletedCount)
+	assert.FileExists(t, filepath.Join(dir, "used.jar"))
+	assert.NoFileExists(t, filepath.Join(dir, "unused.jar"))
+}
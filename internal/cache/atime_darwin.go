@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// AccessTime returns the file's last access time, or its ModTime when the
+// platform does not expose one.
+func AccessTime(info fs.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Atimespec.Unix())
+	}
+	return info.ModTime()
+}
+
+// ChangeTime returns the file's inode change time, or its ModTime when the
+// platform does not expose one.
+func ChangeTime(info fs.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Ctimespec.Unix())
+	}
+	return info.ModTime()
+}
+
+// mntNoatime is MNT_NOATIME from <sys/mount.h>.
+const mntNoatime = 0x10000000
+
+// AtimePolicy returns the mount point holding path and how it updates access
+// times: AtimeNever, or "" otherwise; macOS has no relatime.
+func AtimePolicy(path string) (mount, policy string, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return "", "", err
+	}
+
+	name := make([]byte, 0, len(st.Mntonname))
+	for _, c := range st.Mntonname {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c)) //nolint:gosec // C chars of a mount path
+	}
+	if st.Flags&mntNoatime != 0 {
+		return string(name), AtimeNever, nil
+	}
+	return string(name), "", nil
+}
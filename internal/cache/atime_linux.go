@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// AccessTime returns the file's last access time, or its ModTime when the
+// platform does not expose one.
+func AccessTime(info fs.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Atim.Unix())
+	}
+	return info.ModTime()
+}
+
+// ChangeTime returns the file's inode change time, or its ModTime when the
+// platform does not expose one.
+func ChangeTime(info fs.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Ctim.Unix())
+	}
+	return info.ModTime()
+}
+
+// mountPathUnescaper undoes the octal escapes of paths in /proc/self/mountinfo.
+var mountPathUnescaper = strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+
+// AtimePolicy returns the mount point holding path and how it updates access
+// times: AtimeNever, AtimeRelative, or "" when every read updates them. The
+// mount comes from /proc/self/mountinfo.
+func AtimePolicy(path string) (mount, policy string, err error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	f, err := os.Open(filepath.Join(procRoot, "self", "mountinfo"))
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	// Lines read "36 35 98:0 /mnt1 /mnt/parent rw,noatime master:1 - ext3
+	// /dev/root rw"; the fifth field is the mount point and the sixth its
+	// options. Of the mounts holding path, the deepest wins, then the latest.
+	var options string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		point := mountPathUnescaper.Replace(fields[4])
+		if len(point) < len(mount) || !underMount(path, point) {
+			continue
+		}
+		mount, options = point, fields[5]
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+
+	for opt := range strings.SplitSeq(options, ",") {
+		if opt == AtimeNever || opt == AtimeRelative {
+			return mount, opt, nil
+		}
+	}
+	return mount, "", nil
+}
+
+func underMount(path, point string) bool {
+	return point == "/" || path == point || strings.HasPrefix(path, point+"/")
+}
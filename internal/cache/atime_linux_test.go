@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtimePolicy(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "self"), 0o755))
+	mountinfo := `22 1 8:2 / / rw,relatime shared:1 - ext4 /dev/sda2 rw
+30 22 8:3 / /home rw,noatime shared:2 - ext4 /dev/sda3 rw
+31 30 0:40 / /home/dev/My\040Caches rw,nosuid shared:3 - tmpfs tmpfs rw
+32 22 8:4 / /var/cache rw shared:4 - xfs /dev/sda4 rw
+33 22 8:5 / /var/cache rw,nodiratime,noatime shared:5 - xfs /dev/sda5 rw
+`
+	require.NoError(t, os.WriteFile(filepath.Join(root, "self", "mountinfo"), []byte(mountinfo), 0o600))
+	orig := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = orig })
+
+	for path, want := range map[string][2]string{
+		"/srv/cache-buster-test":           {"/", AtimeRelative},
+		"/home/dev/.gradle":                {"/home", AtimeNever},
+		"/home/dev/My Caches/pip":          {"/home/dev/My Caches", ""},
+		"/var/cache/apt":                   {"/var/cache", AtimeNever}, // the later mount hides the first
+		"/homework":                        {"/", AtimeRelative},
+		"/home/dev/My Caches2/not-a-mount": {"/home", AtimeNever},
+	} {
+		mount, policy, err := AtimePolicy(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, [2]string{mount, policy}, path)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !linux && !darwin
+
+package cache
+
+import (
+	"io/fs"
+	"time"
+)
+
+// AccessTime returns the file's ModTime: access times are only read on Linux
+// and macOS.
+func AccessTime(info fs.FileInfo) time.Time {
+	return info.ModTime()
+}
+
+// ChangeTime returns the file's ModTime: inode change times are only read on
+// Linux and macOS.
+func ChangeTime(info fs.FileInfo) time.Time {
+	return info.ModTime()
+}
+
+// AtimePolicy reports no mount and no policy: mount options are only read on
+// Linux and macOS.
+func AtimePolicy(string) (mount, policy string, err error) {
+	return "", "", nil
+}
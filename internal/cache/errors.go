@@ -22,6 +22,9 @@ func (e AccessError) Unwrap() error {
 	return e.Err
 }
 
+// ErrFileOpen marks files kept because another process has them open.
+var ErrFileOpen = errors.New("open in another process")
+
 // Reason constants for error classification.
 const (
 	ReasonPermissionDenied = "permission denied"
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+)
+
+// EvictionStrategy decides which files a trim deletes first to get under
+// its size target. Files past max_age go regardless of the strategy.
+type EvictionStrategy interface {
+	// Order sorts files into the order they are deleted in.
+	Order(files []FileInfo, now time.Time)
+}
+
+// NewEvictionStrategy returns the strategy named name, one of the
+// config.Evict* values; an empty name is config.EvictLRU.
+func NewEvictionStrategy(name string) (EvictionStrategy, error) {
+	switch name {
+	case "", config.EvictLRU:
+		return lruStrategy{}, nil
+	case config.EvictLargest:
+		return largestStrategy{}, nil
+	case config.EvictAgeSize:
+		return ageSizeStrategy{}, nil
+	case config.EvictLFU:
+		return lfuStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown eviction strategy %q", name)
+	}
+}
+
+type lruStrategy struct{}
+
+func (lruStrategy) Order(files []FileInfo, _ time.Time) {
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].LastUsed.Before(files[j].LastUsed)
+	})
+}
+
+type largestStrategy struct{}
+
+func (largestStrategy) Order(files []FileInfo, _ time.Time) {
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Size != files[j].Size {
+			return files[i].Size > files[j].Size
+		}
+		return files[i].LastUsed.Before(files[j].LastUsed)
+	})
+}
+
+// ageSizeStrategy scores each file by how long it has gone unused times its
+// size, so a large file idle for a week goes before a small one idle for a month.
+type ageSizeStrategy struct{}
+
+func (ageSizeStrategy) Order(files []FileInfo, now time.Time) {
+	score := func(f *FileInfo) float64 {
+		return max(now.Sub(f.LastUsed).Seconds(), 0) * float64(f.Size)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return score(&files[i]) > score(&files[j])
+	})
+}
+
+// lfuStrategy ranks by the opens an AccessLog counted. Without recorded
+// opens every count is zero and it orders like lruStrategy.
+type lfuStrategy struct{}
+
+func (lfuStrategy) Order(files []FileInfo, _ time.Time) {
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Opens != files[j].Opens {
+			return files[i].Opens < files[j].Opens
+		}
+		return files[i].LastUsed.Before(files[j].LastUsed)
+	})
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvictionStrategy_Order(t *testing.T) {
+	now := time.Now()
+	files := []FileInfo{
+		{Path: "small-old", Size: 100, LastUsed: now.Add(-30 * 24 * time.Hour), Opens: 9},
+		{Path: "big-recent", Size: 10000, LastUsed: now.Add(-1 * time.Hour), Opens: 1},
+		{Path: "mid-week", Size: 1000, LastUsed: now.Add(-7 * 24 * time.Hour)},
+		{Path: "mid-day", Size: 1000, LastUsed: now.Add(-24 * time.Hour)},
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"", []string{"small-old", "mid-week", "mid-day", "big-recent"}},
+		{config.EvictLRU, []string{"small-old", "mid-week", "mid-day", "big-recent"}},
+		{config.EvictLargest, []string{"big-recent", "mid-week", "mid-day", "small-old"}},
+		{config.EvictAgeSize, []string{"mid-week", "small-old", "mid-day", "big-recent"}},
+		{config.EvictLFU, []string{"mid-week", "mid-day", "big-recent", "small-old"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := NewEvictionStrategy(tt.name)
+			require.NoError(t, err)
+
+			ordered := append([]FileInfo(nil), files...)
+			strategy.Order(ordered, now)
+			var got []string
+			for _, f := range ordered {
+				got = append(got, f.Path)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewEvictionStrategy_Unknown(t *testing.T) {
+	_, err := NewEvictionStrategy("fifo")
+	assert.EqualError(t, err, `unknown eviction strategy "fifo"`)
+}
+
+func TestTrim_EvictLargest(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, filepath.Join(dir, "old-a.txt"), 500, 20*24*time.Hour)
+	createTestFile(t, filepath.Join(dir, "old-b.txt"), 500, 15*24*time.Hour)
+	createTestFile(t, filepath.Join(dir, "big.txt"), 2000, 24*time.Hour)
+
+	strategy, err := NewEvictionStrategy(config.EvictLargest)
+	require.NoError(t, err)
+	result, err := Trim(t.Context(), []string{dir}, TrimOptions{
+		MaxSize:  2500,
+		MaxAge:   60 * 24 * time.Hour,
+		Strategy: strategy,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.DeletedCount)
+	assert.NoFileExists(t, filepath.Join(dir, "big.txt"))
+	assert.FileExists(t, filepath.Join(dir, "old-a.txt"))
+	assert.FileExists(t, filepath.Join(dir, "old-b.txt"))
+}
+
+func TestTrim_Headroom(t *testing.T) {
+	tests := []struct {
+		name     string
+		headroom float64
+		deleted  int64
+	}{
+		{"default", 0, 3},              // target 900 bytes
+		{"none", config.NoHeadroom, 2}, // target 1000 bytes
+		{"half", 0.5, 7},               // target 500 bytes
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i := range 12 { // 1200 bytes
+				createTestFile(t, filepath.Join(dir, fmt.Sprintf("%02d.txt", i)), 100, time.Duration(20-i)*24*time.Hour)
+			}
+
+			result, err := Trim(t.Context(), []string{dir}, TrimOptions{
+				MaxSize:  1000,
+				MaxAge:   60 * 24 * time.Hour,
+				Headroom: tt.headroom,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.deleted, result.DeletedCount)
+		})
+	}
+}
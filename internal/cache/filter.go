@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Filter restricts which files under a provider's roots are scanned and deleted.
+//
+// Patterns use doublestar syntax. Absolute patterns match full paths; relative
+// patterns match the path relative to the root being walked (use **/ for any
+// depth). A pattern matching a directory applies to everything beneath it.
+type Filter struct {
+	Exclude []string // matching paths are skipped entirely: not counted, not deleted
+	Protect []string // matching paths are counted toward size but never deleted
+}
+
+// IsZero reports whether the filter has no patterns.
+func (f Filter) IsZero() bool {
+	return len(f.Exclude) == 0 && len(f.Protect) == 0
+}
+
+// Excluded reports whether path under root matches an exclude pattern.
+func (f Filter) Excluded(root, path string) bool {
+	return matchAny(f.Exclude, root, path)
+}
+
+// Protected reports whether path under root matches a protect pattern.
+func (f Filter) Protected(root, path string) bool {
+	return matchAny(f.Protect, root, path)
+}
+
+// Guards reports whether dir (under root) or anything beneath it is excluded
+// or protected, meaning dir must not be removed wholesale.
+func (f Filter) Guards(root, dir string) (bool, error) {
+	if f.IsZero() {
+		return false, nil
+	}
+
+	guarded := false
+	err := filepath.WalkDir(dir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if f.Excluded(root, path) || f.Protected(root, path) {
+			guarded = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("check filter under %s: %w", dir, err)
+	}
+	return guarded, nil
+}
+
+// matchAny reports whether path or any of its ancestors up to root matches one of patterns.
+func matchAny(patterns []string, root, path string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+
+	cur := path
+	for {
+		rel, relErr := filepath.Rel(root, cur)
+		underRoot := relErr == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+
+		for _, pattern := range patterns {
+			target := cur
+			if !filepath.IsAbs(pattern) {
+				if !underRoot {
+					continue
+				}
+				target = rel
+			}
+			if ok, _ := doublestar.PathMatch(pattern, target); ok {
+				return true
+			}
+		}
+
+		if cur == root {
+			return false
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return false
+		}
+		cur = parent
+	}
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_Match(t *testing.T) {
+	root := "/home/u/.gradle/caches"
+	f := Filter{
+		Exclude: []string{"**/*.lock", "/home/u/.gradle/caches/journal-1"},
+		Protect: []string{"jars-*"},
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		excluded  bool
+		protected bool
+	}{
+		{"plain file", root + "/modules-2/files/a.jar", false, false},
+		{"relative doublestar", root + "/modules-2/gc.lock", true, false},
+		{"absolute dir covers subtree", root + "/journal-1/file", true, false},
+		{"protected dir covers subtree", root + "/jars-9/x/y.jar", false, true},
+		{"protected dir itself", root + "/jars-9", false, true},
+		{"relative pattern anchored at root", root + "/nested/jars-9/y.jar", false, false},
+		{"root itself", root, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.excluded, f.Excluded(root, tt.path), "Excluded")
+			assert.Equal(t, tt.protected, f.Protected(root, tt.path), "Protected")
+		})
+	}
+}
+
+func TestFilter_ZeroMatchesNothing(t *testing.T) {
+	var f Filter
+	assert.True(t, f.IsZero())
+	assert.False(t, f.Excluded("/r", "/r/a"))
+	assert.False(t, f.Protected("/r", "/r/a"))
+}
+
+func TestFilter_Guards(t *testing.T) {
+	root := t.TempDir()
+	guardedDir := filepath.Join(root, "v1")
+	plainDir := filepath.Join(root, "v2")
+	require.NoError(t, os.MkdirAll(filepath.Join(guardedDir, "index"), 0o750))
+	require.NoError(t, os.MkdirAll(plainDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(guardedDir, "index", "keep.db"), []byte("x"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(plainDir, "a"), []byte("x"), 0o600))
+
+	f := Filter{Protect: []string{"**/keep.db"}}
+
+	guarded, err := f.Guards(root, guardedDir)
+	require.NoError(t, err)
+	assert.True(t, guarded)
+
+	guarded, err = f.Guards(root, plainDir)
+	require.NoError(t, err)
+	assert.False(t, guarded)
+}
+
+func TestListFilesFiltered(t *testing.T) {
+	root := t.TempDir()
+	createTestFile(t, filepath.Join(root, "keep", "a.bin"), 100, 0)
+	createTestFile(t, filepath.Join(root, "skip", "b.bin"), 200, 0)
+	createTestFile(t, filepath.Join(root, "c.bin"), 300, 0)
+
+	f := Filter{Exclude: []string{"skip"}, Protect: []string{"keep"}}
+
+	result, err := ListFilesFiltered(t.Context(), []string{root}, f)
+	require.NoError(t, err)
+	require.Len(t, result.Files, 2)
+
+	protected := map[string]bool{}
+	for _, fi := range result.Files {
+		protected[filepath.Base(fi.Path)] = fi.Protected
+	}
+	assert.Equal(t, map[string]bool{"a.bin": true, "c.bin": false}, protected)
+
+	size, err := CalculateSizeFiltered(t.Context(), []string{root}, f)
+	require.NoError(t, err)
+	assert.Equal(t, int64(400), size.Size)
+}
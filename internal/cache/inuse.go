@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Process is a running process, identified by PID and command name.
+type Process struct {
+	Name string
+	PID  int
+}
+
+func (p Process) String() string {
+	if p.Name == "" {
+		return "pid " + strconv.Itoa(p.PID)
+	}
+	return fmt.Sprintf("%s (pid %d)", p.Name, p.PID)
+}
+
+// commNameLen is how much of a command name the kernel keeps.
+const commNameLen = 15
+
+// FindProcesses returns the running processes named one of names. Names
+// match the command name, which the kernel may truncate, so a name matches
+// its truncated form too.
+func FindProcesses(names []string) ([]Process, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	all, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	self := os.Getpid()
+	var found []Process
+	for _, p := range all {
+		if p.PID != self && matchProcessName(names, p.Name) {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+func matchProcessName(names []string, comm string) bool {
+	return slices.ContainsFunc(names, func(name string) bool {
+		return comm == name || (len(name) > commNameLen && comm == name[:commNameLen])
+	})
+}
+
+// LockedBy reports whether another process holds a lock on path. The holder
+// is known only where the platform reports it; otherwise it is zero. A
+// missing path is not locked.
+func LockedBy(path string) (holder Process, locked bool, err error) {
+	known, held, lockErr := lockHolder(path)
+	if lockErr != nil || held {
+		return known, held, lockErr
+	}
+	locked, err = flockHeld(path)
+	return Process{}, locked, err
+}
+
+// OpenFiles returns the paths under roots of files another process has open.
+// Paths are reported under the root they were found in, even when the root
+// is reached through a symlink. Platforms without a readable table of open
+// files report none.
+func OpenFiles(roots []string) (map[string]bool, error) {
+	if len(roots) == 0 {
+		return nil, nil
+	}
+	paths, err := openPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	open := make(map[string]bool)
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		resolved := root
+		if r, err := filepath.EvalSymlinks(root); err == nil {
+			resolved = r
+		}
+		for _, path := range paths {
+			rel, err := filepath.Rel(resolved, path)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			open[filepath.Join(root, rel)] = true
+		}
+	}
+	return open, nil
+}
+
+// ProtectOpen marks the files of files that another process has open as
+// protected, so cleans count but keep them, and returns a ReasonFileLocked
+// error for each. roots are the paths files were listed from.
+func ProtectOpen(files []FileInfo, roots []string) ([]AccessError, error) {
+	open, err := OpenFiles(roots)
+	if err != nil {
+		return nil, fmt.Errorf("list open files: %w", err)
+	}
+
+	var kept []AccessError
+	for i := range files {
+		if files[i].Protected || !open[files[i].Path] {
+			continue
+		}
+		files[i].Protected = true
+		kept = append(kept, AccessError{Path: files[i].Path, Reason: ReasonFileLocked, Err: ErrFileOpen})
+	}
+	return kept, nil
+}
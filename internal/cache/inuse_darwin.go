@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listProcesses lists every process with ps, which prints full command paths.
+func listProcesses() ([]Process, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "ps", "-axo", "pid=,comm=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("list processes: %w", err)
+	}
+
+	var procs []Process
+	for line := range strings.SplitSeq(string(out), "\n") {
+		pidField, comm, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(pidField)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, Process{Name: filepath.Base(strings.TrimSpace(comm)), PID: pid})
+	}
+	return procs, nil
+}
+
+// lockHolder finds nothing: macOS has no lock table naming holders, so
+// LockedBy relies on its flock probe, which also sees fcntl locks here.
+func lockHolder(string) (holder Process, locked bool, err error) {
+	return Process{}, false, nil
+}
+
+// openPaths finds nothing: macOS has no /proc, and lsof takes seconds for a
+// system-wide listing.
+func openPaths() ([]string, error) {
+	return nil, nil
+}
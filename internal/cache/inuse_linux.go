@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// procRoot is where process and lock tables are read; tests point it at fixtures.
+var procRoot = "/proc"
+
+// listProcesses reads the command name of every process in /proc.
+func listProcesses() ([]Process, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, fmt.Errorf("list processes: %w", err)
+	}
+
+	var procs []Process
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		// Processes may exit between listing and reading.
+		if name := processName(pid); name != "" {
+			procs = append(procs, Process{Name: name, PID: pid})
+		}
+	}
+	return procs, nil
+}
+
+func processName(pid int) string {
+	comm, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
+
+// lockHolder finds a flock, POSIX, or open file description lock on path in
+// /proc/locks, which names the holder. Locks of processes in other PID
+// namespaces are not listed there; LockedBy's flock probe catches those.
+func lockHolder(path string) (holder Process, locked bool, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Process{}, false, nil
+		}
+		return Process{}, false, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return Process{}, false, nil
+	}
+	// Kernel device numbers as /proc/locks prints them, from the stat encoding.
+	dev := uint64(st.Dev) //nolint:unconvert // Dev is uint32 on some architectures
+	major := (dev>>8)&0xfff | (dev>>32)&^uint64(0xfff)
+	minor := dev&0xff | (dev>>12)&^uint64(0xff)
+	want := fmt.Sprintf("%02x:%02x:%d", major, minor, st.Ino)
+
+	f, err := os.Open(filepath.Join(procRoot, "locks"))
+	if err != nil {
+		// Without the lock table, the flock probe is all there is.
+		return Process{}, false, nil
+	}
+	defer f.Close()
+
+	// Lines read "1: POSIX  ADVISORY  WRITE 1234 08:02:131 0 EOF"; waiting
+	// requests carry "->" after the index and hold nothing.
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[1] == "->" || fields[5] != want {
+			continue
+		}
+		pid, _ := strconv.Atoi(fields[4])
+		if pid == os.Getpid() {
+			continue
+		}
+		if pid <= 0 {
+			// Open file description locks have no owning process.
+			return Process{}, true, nil
+		}
+		return Process{Name: processName(pid), PID: pid}, true, nil
+	}
+	return Process{}, false, scanner.Err()
+}
+
+// openPaths reads where the descriptors in /proc/*/fd of every other process
+// point. Descriptors this user may not read are left out, as are sockets,
+// pipes, and files already deleted.
+func openPaths() ([]string, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	self := strconv.Itoa(os.Getpid())
+	var paths []string
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil || e.Name() == self {
+			continue
+		}
+		fdDir := filepath.Join(procRoot, e.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !filepath.IsAbs(target) || strings.HasSuffix(target, " (deleted)") {
+				continue
+			}
+			paths = append(paths, target)
+		}
+	}
+	return paths, nil
+}
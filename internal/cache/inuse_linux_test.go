@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperHoldLock is not a test: run as a child process, it flocks the
+// file named by CACHE_BUSTER_HOLD_LOCK until its stdin closes.
+func TestHelperHoldLock(t *testing.T) {
+	path := os.Getenv("CACHE_BUSTER_HOLD_LOCK")
+	if path == "" {
+		t.Skip("helper process for TestLockedBy_OtherProcess")
+	}
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	require.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX))
+	_, _ = os.Stdout.WriteString("locked\n")
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+}
+
+func TestLockedBy_OtherProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".package-cache")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperHoldLock$")
+	cmd.Env = append(os.Environ(), "CACHE_BUSTER_HOLD_LOCK="+path)
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = stdin.Close()
+		_ = cmd.Wait()
+	})
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() && scanner.Text() != "locked" {
+	}
+
+	holder, locked, err := LockedBy(path)
+	require.NoError(t, err)
+	assert.True(t, locked)
+	assert.Equal(t, cmd.Process.Pid, holder.PID)
+	assert.NotEmpty(t, holder.Name)
+
+	require.NoError(t, stdin.Close())
+	require.NoError(t, cmd.Wait())
+	_, locked, err = LockedBy(path)
+	require.NoError(t, err)
+	assert.False(t, locked)
+}
+
+func TestLockedBy_FlockProbe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal-1.lock")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	_, locked, err := LockedBy(path)
+	require.NoError(t, err)
+	assert.False(t, locked)
+
+	// /proc/locks lists this process, which lockHolder ignores; a lock on
+	// another descriptor still conflicts with the probe.
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX))
+
+	holder, locked, err := LockedBy(path)
+	require.NoError(t, err)
+	assert.True(t, locked)
+	assert.Zero(t, holder.PID)
+
+	_, locked, err = LockedBy(filepath.Join(t.TempDir(), "missing.lock"))
+	require.NoError(t, err)
+	assert.False(t, locked)
+}
+
+func TestFindProcesses(t *testing.T) {
+	root := t.TempDir()
+	for pid, comm := range map[string]string{
+		"101": "cargo",
+		"102": "rustc",
+		"103": "gradle-launcher", // "gradle-launcher-daemon" cut to 15 bytes
+		"104": "cargo-watch",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, pid), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, pid, "comm"), []byte(comm+"\n"), 0o600))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sys"), 0o755))
+
+	orig := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = orig })
+
+	found, err := FindProcesses([]string{"cargo", "gradle"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Process{{Name: "cargo", PID: 101}}, found)
+
+	found, err = FindProcesses([]string{"gradle-launcher-daemon"})
+	require.NoError(t, err)
+	assert.Equal(t, []Process{{Name: "gradle-launcher", PID: 103}}, found)
+
+	found, err = FindProcesses(nil)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+// fakeOpenFiles points procRoot at a table where each pid has the given
+// descriptor targets open.
+func fakeOpenFiles(t *testing.T, fds map[int][]string) {
+	t.Helper()
+	root := t.TempDir()
+	for pid, targets := range fds {
+		dir := filepath.Join(root, strconv.Itoa(pid), "fd")
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		for i, target := range targets {
+			require.NoError(t, os.Symlink(target, filepath.Join(dir, strconv.Itoa(i+3))))
+		}
+	}
+	orig := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = orig })
+}
+
+func TestOpenFiles(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	link := filepath.Join(dir, "link")
+	require.NoError(t, os.MkdirAll(cacheDir, 0o755))
+	require.NoError(t, os.Symlink(cacheDir, link))
+
+	fakeOpenFiles(t, map[int][]string{
+		101:         {filepath.Join(cacheDir, "index", "a.bin"), "socket:[123]", "pipe:[456]"},
+		102:         {filepath.Join(dir, "cache-other", "b.bin"), filepath.Join(cacheDir, "gone.bin") + " (deleted)"},
+		os.Getpid(): {filepath.Join(cacheDir, "mine.bin")},
+	})
+
+	open, err := OpenFiles([]string{cacheDir})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{filepath.Join(cacheDir, "index", "a.bin"): true}, open)
+
+	open, err = OpenFiles([]string{link + "/"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{filepath.Join(link, "index", "a.bin"): true}, open)
+
+	open, err = OpenFiles(nil)
+	require.NoError(t, err)
+	assert.Empty(t, open)
+}
+
+func TestTrim_SkipOpenFiles(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, filepath.Join(dir, "index.bin"), 1000, 40*24*time.Hour)
+	createTestFile(t, filepath.Join(dir, "old.bin"), 1000, 40*24*time.Hour)
+	fakeOpenFiles(t, map[int][]string{101: {filepath.Join(dir, "index.bin")}})
+
+	opts := TrimOptions{MaxSize: 10000, MaxAge: 30 * 24 * time.Hour, DryRun: true, SkipOpenFiles: true}
+	result, err := Trim(context.Background(), []string{dir}, opts)
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "skipping open file: "+filepath.Join(dir, "index.bin"))
+	assert.Equal(t, int64(1), result.DeletedCount)
+
+	opts.DryRun = false
+	result, err = Trim(context.Background(), []string{dir}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.DeletedCount)
+	assert.FileExists(t, filepath.Join(dir, "index.bin"))
+	assert.NoFileExists(t, filepath.Join(dir, "old.bin"))
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, ReasonFileLocked, result.Errors[0].Reason)
+	assert.ErrorIs(t, result.Errors[0], ErrFileOpen)
+}
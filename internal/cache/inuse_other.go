@@ -0,0 +1,24 @@
+//go:build !linux && !darwin
+
+package cache
+
+// listProcesses finds nothing: only Linux and macOS process tables are read,
+// so cleans elsewhere never see the owning tool running.
+func listProcesses() ([]Process, error) {
+	return nil, nil
+}
+
+// lockHolder finds nothing: there is no lock table to name holders.
+func lockHolder(string) (holder Process, locked bool, err error) {
+	return Process{}, false, nil
+}
+
+// flockHeld finds nothing: flock(2) is not available.
+func flockHeld(string) (bool, error) {
+	return false, nil
+}
+
+// openPaths finds nothing: there is no /proc to read descriptors from.
+func openPaths() ([]string, error) {
+	return nil, nil
+}
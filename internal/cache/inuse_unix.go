@@ -0,0 +1,33 @@
+//go:build linux || darwin
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// flockHeld probes for a flock(2) lock by trying to take it without waiting.
+func flockHeld(path string) (bool, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	fd := int(f.Fd()) //nolint:gosec // file descriptors fit in int
+	if err := syscall.Flock(fd, syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return true, nil
+		}
+		return false, fmt.Errorf("probe lock %s: %w", path, err)
+	}
+	_ = syscall.Flock(fd, syscall.LOCK_UN)
+	return false, nil
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"io/fs"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+)
+
+// LRUKey names the file time that tells when a file was last used, one of
+// the config.LRU* values of the lru_key provider field.
+type LRUKey string
+
+// Time returns the time k reads from info. An empty or unknown key reads
+// the ModTime.
+func (k LRUKey) Time(info fs.FileInfo) time.Time {
+	switch k {
+	case config.LRUAtime:
+		return AccessTime(info)
+	case config.LRUCtime:
+		return ChangeTime(info)
+	case config.LRUMax:
+		latest := info.ModTime()
+		for _, t := range []time.Time{AccessTime(info), ChangeTime(info)} {
+			if t.After(latest) {
+				latest = t
+			}
+		}
+		return latest
+	default:
+		return info.ModTime()
+	}
+}
+
+// ReadsAtime reports whether k depends on access times.
+func (k LRUKey) ReadsAtime() bool {
+	return k == config.LRUAtime || k == config.LRUMax
+}
+
+// How a mount updates access times, as AtimePolicy reports it.
+const (
+	AtimeNever    = "noatime"
+	AtimeRelative = "relatime" // only when older than the mtime or ctime, or a day old
+)
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUKey_Time(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wheel.whl")
+	require.NoError(t, os.WriteFile(path, []byte("x"), 0o600))
+	mtime := time.Now().Add(-40 * 24 * time.Hour).Truncate(time.Second)
+	atime := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(path, atime, mtime))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	assert.True(t, LRUKey(config.LRUMtime).Time(info).Equal(mtime))
+	assert.True(t, LRUKey("").Time(info).Equal(mtime))
+	assert.True(t, LRUKey(config.LRUAtime).Time(info).Equal(atime))
+	// Chtimes itself changed the inode just now.
+	assert.WithinDuration(t, time.Now(), LRUKey(config.LRUCtime).Time(info), time.Minute)
+	assert.True(t, LRUKey(config.LRUMax).Time(info).Equal(LRUKey(config.LRUCtime).Time(info)))
+}
+
+func TestTrim_LRUKeyAtime(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, filepath.Join(dir, "used.jar"), 1000, 40*24*time.Hour)
+	createTestFile(t, filepath.Join(dir, "unused.jar"), 1000, 40*24*time.Hour)
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "used.jar"), time.Now(), old))
+
+	result, err := Trim(t.Context(), []string{dir}, TrimOptions{
+		MaxSize: 10000,
+		MaxAge:  30 * 24 * time.Hour,
+		LRUKey:  config.LRUAtime,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.DeletedCount)
+	assert.FileExists(t, filepath.Join(dir, "used.jar"))
+	assert.NoFileExists(t, filepath.Join(dir, "unused.jar"))
+}
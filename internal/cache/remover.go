@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// removeFile is os.Remove, swappable in tests since root bypasses directory permissions.
+var removeFile = os.Remove
+
+// Remover deletes files whose parent directory may be read-only, as the Go
+// module cache and Bazel/Nix-style stores leave them. When a delete is denied
+// and the parent is owned by the current user, write permission is added to
+// the parent and the delete retried. The cache roots themselves are left as
+// configured. Restore puts original permissions back on directories that
+// still exist.
+type Remover struct {
+	Roots []string               // cache roots whose permissions are never changed
+	modes map[string]fs.FileMode // directories made writable, with their original permissions
+}
+
+// Remove deletes path, unlocking a read-only parent directory if needed.
+func (r *Remover) Remove(path string) error {
+	err := removeFile(path)
+	if err == nil || !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if _, done := r.modes[dir]; done || !r.unlock(dir) {
+		return err
+	}
+	return removeFile(path)
+}
+
+// Fixups returns how many read-only directories were made writable.
+func (r *Remover) Fixups() int64 {
+	return int64(len(r.modes))
+}
+
+// Restore resets unlocked directories that survived to their original permissions,
+// deepest first so a parent stays writable until its children are done.
+func (r *Remover) Restore() []AccessError {
+	dirs := make([]string, 0, len(r.modes))
+	for dir := range r.modes {
+		dirs = append(dirs, dir)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+
+	var errs []AccessError
+	for _, dir := range dirs {
+		if err := os.Chmod(dir, r.modes[dir]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, ClassifyError(dir, err))
+		}
+	}
+	return errs
+}
+
+// unlock adds owner write permission to a read-only dir owned by the current
+// user, below the cache roots.
+func (r *Remover) unlock(dir string) bool {
+	for _, root := range r.Roots {
+		if filepath.Clean(root) == dir {
+			return false
+		}
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil || info.Mode().Perm()&0o200 != 0 {
+		return false
+	}
+	if !ownedByUser(info) {
+		return false
+	}
+
+	if err := os.Chmod(dir, info.Mode().Perm()|0o200); err != nil {
+		return false
+	}
+	if r.modes == nil {
+		r.modes = make(map[string]fs.FileMode)
+	}
+	r.modes[dir] = info.Mode().Perm()
+	return true
+}
+
+// FormatDeleted summarizes a file deletion, noting any read-only directory fixups.
+func FormatDeleted(deleted, fixups int64) string {
+	if fixups == 0 {
+		return fmt.Sprintf("deleted %d files", deleted)
+	}
+	return fmt.Sprintf("deleted %d files, made %d read-only dirs writable", deleted, fixups)
+}
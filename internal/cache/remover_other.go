@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package cache
+
+import "io/fs"
+
+// ownedByUser reports false: without Unix owners and modes, read-only
+// directories are never unlocked.
+func ownedByUser(fs.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// enforceDirPermissions makes removeFile honor directory write permission even
+// when the tests run as root.
+func enforceDirPermissions(t *testing.T) {
+	t.Helper()
+	orig := removeFile
+	removeFile = func(path string) error {
+		info, err := os.Stat(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm()&0o200 == 0 {
+			return &os.PathError{Op: "remove", Path: path, Err: syscall.EACCES}
+		}
+		return os.Remove(path)
+	}
+	t.Cleanup(func() { removeFile = orig })
+}
+
+func readOnlyDir(t *testing.T, dir string) {
+	t.Helper()
+	require.NoError(t, os.Chmod(dir, 0o500))
+	t.Cleanup(func() { _ = os.Chmod(dir, 0o700) })
+}
+
+func TestRemover_UnlocksReadOnlyParent(t *testing.T) {
+	enforceDirPermissions(t)
+	dir := filepath.Join(t.TempDir(), "ro")
+	createTestFile(t, filepath.Join(dir, "a"), 10, 0)
+	createTestFile(t, filepath.Join(dir, "b"), 10, 0)
+	createTestFile(t, filepath.Join(dir, "c"), 10, 0)
+	readOnlyDir(t, dir)
+
+	var r Remover
+	require.NoError(t, r.Remove(filepath.Join(dir, "a")))
+	require.NoError(t, r.Remove(filepath.Join(dir, "b")))
+	assert.Equal(t, int64(1), r.Fixups())
+
+	assert.Empty(t, r.Restore())
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o500), info.Mode().Perm())
+	assert.NoFileExists(t, filepath.Join(dir, "a"))
+	assert.FileExists(t, filepath.Join(dir, "c"))
+}
+
+func TestRemover_WritableParentDenied(t *testing.T) {
+	orig := removeFile
+	removeFile = func(path string) error {
+		return &os.PathError{Op: "remove", Path: path, Err: syscall.EACCES}
+	}
+	t.Cleanup(func() { removeFile = orig })
+
+	var r Remover
+	err := r.Remove(filepath.Join(t.TempDir(), "x"))
+	require.ErrorIs(t, err, os.ErrPermission)
+	assert.Zero(t, r.Fixups())
+}
+
+func TestRemover_LeavesRootsAlone(t *testing.T) {
+	enforceDirPermissions(t)
+	dir := filepath.Join(t.TempDir(), "ro")
+	createTestFile(t, filepath.Join(dir, "a"), 10, 0)
+	readOnlyDir(t, dir)
+
+	r := Remover{Roots: []string{dir + "/"}}
+	err := r.Remove(filepath.Join(dir, "a"))
+	require.ErrorIs(t, err, os.ErrPermission)
+	assert.Zero(t, r.Fixups())
+	assert.FileExists(t, filepath.Join(dir, "a"))
+}
+
+func TestRemover_RestoreSkipsRemovedDir(t *testing.T) {
+	enforceDirPermissions(t)
+	dir := filepath.Join(t.TempDir(), "ro")
+	createTestFile(t, filepath.Join(dir, "a"), 10, 0)
+	readOnlyDir(t, dir)
+
+	var r Remover
+	require.NoError(t, r.Remove(filepath.Join(dir, "a")))
+	require.NoError(t, os.Remove(dir))
+
+	assert.Empty(t, r.Restore())
+}
+
+func TestFormatDeleted(t *testing.T) {
+	assert.Equal(t, "deleted 3 files", FormatDeleted(3, 0))
+	assert.Equal(t, "deleted 3 files, made 2 read-only dirs writable", FormatDeleted(3, 2))
+}
+
+func TestTrim_ReadOnlyParent(t *testing.T) {
+	enforceDirPermissions(t)
+	dir := t.TempDir()
+	roDir := filepath.Join(dir, "pkg@v1.0.0")
+	createTestFile(t, filepath.Join(roDir, "old.go"), 1000, 40*24*time.Hour)
+	readOnlyDir(t, roDir)
+
+	result, err := Trim(context.Background(), []string{dir}, TrimOptions{
+		MaxSize: 10000,
+		MaxAge:  30 * 24 * time.Hour,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.DeletedCount)
+	assert.Equal(t, int64(1), result.Fixups)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, "deleted 1 files, made 1 read-only dirs writable", result.Output)
+
+	info, err := os.Stat(roDir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o500), info.Mode().Perm())
+}
@@ -10,13 +10,18 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
 )
 
 // FileInfo holds file metadata for cache entries.
 type FileInfo struct {
-	ModTime time.Time
-	Path    string
-	Size    int64
+	ModTime   time.Time
+	LastUsed  time.Time // per the LRU key of the listing; ModTime by default
+	Path      string
+	Size      int64
+	Opens     int  // opens an AccessLog counted; 0 without one
+	Protected bool // matched a protect pattern: counted but must not be deleted
 }
 
 // ScanResult contains size calculation results with access warnings.
@@ -36,6 +41,11 @@ func CalculateSize(paths []string) (ScanResult, error) {
 // Access errors are collected as warnings rather than stopping the scan.
 // The walk stops early and returns ctx.Err() once ctx is cancelled.
 func CalculateSizeContext(ctx context.Context, paths []string) (ScanResult, error) {
+	return CalculateSizeFiltered(ctx, paths, Filter{})
+}
+
+// CalculateSizeFiltered is CalculateSizeContext skipping paths excluded by filter.
+func CalculateSizeFiltered(ctx context.Context, paths []string, filter Filter) (ScanResult, error) {
 	var total atomic.Int64
 	var firstErr atomic.Value
 	var mu sync.Mutex
@@ -59,6 +69,12 @@ func CalculateSizeContext(ctx context.Context, paths []string) (ScanResult, erro
 					}
 					return nil
 				}
+				if filter.Excluded(p, path) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
 				if d.IsDir() || d.Type()&fs.ModeSymlink != 0 {
 					return nil
 				}
@@ -108,6 +124,17 @@ func ListFiles(paths []string) (ListResult, error) {
 // Access errors are collected as warnings rather than stopping the scan.
 // The walk stops early and returns ctx.Err() once ctx is cancelled.
 func ListFilesContext(ctx context.Context, paths []string) (ListResult, error) {
+	return ListFilesFiltered(ctx, paths, Filter{})
+}
+
+// ListFilesFiltered is ListFilesContext skipping paths excluded by filter
+// and marking files matched by its protect patterns as Protected.
+func ListFilesFiltered(ctx context.Context, paths []string, filter Filter) (ListResult, error) {
+	return ListFilesByRecency(ctx, paths, filter, config.LRUMtime)
+}
+
+// ListFilesByRecency is ListFilesFiltered setting each file's LastUsed from key.
+func ListFilesByRecency(ctx context.Context, paths []string, filter Filter, key LRUKey) (ListResult, error) {
 	var mu sync.Mutex
 	var files []FileInfo
 	var warnings []AccessError
@@ -131,6 +158,12 @@ func ListFilesContext(ctx context.Context, paths []string) (ListResult, error) {
 					}
 					return nil
 				}
+				if filter.Excluded(p, path) {
+					if d.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
 				if d.IsDir() || d.Type()&fs.ModeSymlink != 0 {
 					return nil
 				}
@@ -143,9 +176,11 @@ func ListFilesContext(ctx context.Context, paths []string) (ListResult, error) {
 					return nil
 				}
 				fi := FileInfo{
-					Path:    path,
-					Size:    info.Size(),
-					ModTime: info.ModTime(),
+					Path:      path,
+					Size:      info.Size(),
+					ModTime:   info.ModTime(),
+					LastUsed:  key.Time(info),
+					Protected: filter.Protected(p, path),
 				}
 				mu.Lock()
 				files = append(files, fi)
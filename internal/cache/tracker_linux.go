@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// trackMask is what Tracker watches each directory for: file opens, and the
+// changes that add directories to watch or files to forget.
+const trackMask = syscall.IN_OPEN | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_EXCL_UNLINK
+
+// Tracker records file opens under a set of roots into an AccessLog, using
+// an inotify watch on every directory below them.
+type Tracker struct {
+	log     *AccessLog
+	inotify *os.File
+	dirs    map[int32]string // watch descriptor to directory
+	fd      int
+}
+
+// NewTracker watches every directory under roots, skipping those that are
+// missing or unreadable, and forgets logged files that no longer exist.
+func NewTracker(roots []string, log *AccessLog) (*Tracker, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+	t := &Tracker{
+		log:     log,
+		inotify: os.NewFile(uintptr(fd), "inotify"),
+		dirs:    make(map[int32]string),
+		fd:      fd,
+	}
+	for _, root := range roots {
+		if err := t.watchTree(root); err != nil {
+			_ = t.inotify.Close()
+			return nil, err
+		}
+	}
+
+	for path := range log.opened {
+		if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+			log.Forget(path)
+		}
+	}
+	return t, nil
+}
+
+// Watches returns how many directories are watched.
+func (t *Tracker) Watches() int {
+	return len(t.dirs)
+}
+
+// watchTree adds a watch on root and every directory below it. Running out
+// of watches is an error; anything else only leaves a directory unwatched.
+func (t *Tracker) watchTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		wd, err := syscall.InotifyAddWatch(t.fd, path, trackMask|syscall.IN_ONLYDIR)
+		if err != nil {
+			if errors.Is(err, syscall.ENOSPC) {
+				return fmt.Errorf("watch %s: out of inotify watches, raise fs.inotify.max_user_watches", path)
+			}
+			return filepath.SkipDir
+		}
+		t.dirs[int32(wd)] = path //nolint:gosec // watch descriptors are int32 in events
+		return nil
+	})
+}
+
+// unwatchTree removes the watches on dir and every directory below it, and
+// forgets the files logged there.
+func (t *Tracker) unwatchTree(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for wd, path := range t.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			_, _ = syscall.InotifyRmWatch(t.fd, uint32(wd)) //nolint:gosec // watch descriptors are non-negative
+			delete(t.dirs, wd)
+		}
+	}
+	for path := range t.log.opened {
+		if strings.HasPrefix(path, prefix) {
+			t.log.Forget(path)
+		}
+	}
+}
+
+// Run records opens until ctx ends, saving the log every flush and once more
+// before it returns.
+func (t *Tracker) Run(ctx context.Context, flush time.Duration) error {
+	events := make(chan []byte)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := t.inotify.Read(buf)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case events <- append([]byte(nil), buf[:n]...):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// stop closes the inotify instance and saves the log, whatever ends the
+	// run; err, if any, is reported over a failed save.
+	stop := func(err error) error {
+		_ = t.inotify.Close()
+		if saveErr := t.log.Save(); err == nil {
+			return saveErr
+		}
+		return err
+	}
+
+	ticker := time.NewTicker(flush)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return stop(nil)
+		case err := <-readErr:
+			return stop(fmt.Errorf("read inotify events: %w", err))
+		case batch := <-events:
+			if err := t.handle(batch, time.Now()); err != nil {
+				return stop(err)
+			}
+		case <-ticker.C:
+			if err := t.log.Save(); err != nil {
+				return stop(err)
+			}
+		}
+	}
+}
+
+// handle applies a batch of inotify events: each a fixed header followed by
+// a NUL-padded name.
+func (t *Tracker) handle(batch []byte, now time.Time) error {
+	for len(batch) >= syscall.SizeofInotifyEvent {
+		wd := int32(binary.NativeEndian.Uint32(batch[0:4])) //nolint:gosec // the kernel's int32 watch descriptor
+		mask := binary.NativeEndian.Uint32(batch[4:8])
+		nameLen := int(binary.NativeEndian.Uint32(batch[12:16]))
+		end := min(syscall.SizeofInotifyEvent+nameLen, len(batch))
+		name := strings.TrimRight(string(batch[syscall.SizeofInotifyEvent:end]), "\x00")
+		batch = batch[end:]
+
+		dir, ok := t.dirs[wd]
+		if !ok {
+			continue
+		}
+		if mask&syscall.IN_IGNORED != 0 {
+			delete(t.dirs, wd)
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+
+		switch isDir := mask&syscall.IN_ISDIR != 0; {
+		case isDir && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+			if err := t.watchTree(path); err != nil {
+				return err
+			}
+		case isDir && mask&syscall.IN_MOVED_FROM != 0:
+			// Moved directories keep their watches under the old paths;
+			// IN_MOVED_TO watches them again if they stay under a root.
+			t.unwatchTree(path)
+		case isDir:
+			// A removed directory's watch ends with IN_IGNORED.
+		case mask&syscall.IN_OPEN != 0:
+			t.log.Record(path, now)
+		case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+			t.log.Forget(path)
+		}
+	}
+	return nil
+}
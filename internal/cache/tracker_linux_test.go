@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitRecorded opens path until the saved log at logPath has it.
+func waitRecorded(t *testing.T, logPath, path string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		_ = f.Close()
+		saved, err := LoadAccessLog(logPath)
+		require.NoError(t, err)
+		_, ok := saved.LastOpened(path)
+		return ok
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestTracker(t *testing.T) {
+	root := t.TempDir()
+	createTestFile(t, filepath.Join(root, "wheels", "a.whl"), 10, time.Hour)
+	createTestFile(t, filepath.Join(root, "gone.whl"), 10, time.Hour)
+	logPath := filepath.Join(t.TempDir(), "access.db")
+
+	accessLog, err := LoadAccessLog(logPath)
+	require.NoError(t, err)
+	accessLog.Record(filepath.Join(root, "gone.whl"), time.Now())
+	accessLog.Record(filepath.Join(root, "removed-while-stopped.whl"), time.Now())
+
+	tracker, err := NewTracker([]string{root, filepath.Join(root, "missing")}, accessLog)
+	require.NoError(t, err)
+	assert.Equal(t, 2, tracker.Watches())
+	_, ok := accessLog.LastOpened(filepath.Join(root, "removed-while-stopped.whl"))
+	assert.False(t, ok, "entries of missing files are forgotten at start")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- tracker.Run(ctx, 10*time.Millisecond) }()
+
+	waitRecorded(t, logPath, filepath.Join(root, "wheels", "a.whl"))
+
+	// Directories created later are watched too.
+	createTestFile(t, filepath.Join(root, "new", "b.whl"), 10, 0)
+	waitRecorded(t, logPath, filepath.Join(root, "new", "b.whl"))
+
+	require.NoError(t, os.Remove(filepath.Join(root, "gone.whl")))
+	require.Eventually(t, func() bool {
+		saved, err := LoadAccessLog(logPath)
+		require.NoError(t, err)
+		_, ok := saved.LastOpened(filepath.Join(root, "gone.whl"))
+		return !ok
+	}, 5*time.Second, 20*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestTracker_DirectoryMoves(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	createTestFile(t, filepath.Join(root, "a", "sub", "x.whl"), 10, time.Hour)
+	logPath := filepath.Join(t.TempDir(), "access.db")
+
+	accessLog, err := LoadAccessLog(logPath)
+	require.NoError(t, err)
+	tracker, err := NewTracker([]string{root}, accessLog)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- tracker.Run(ctx, 10*time.Millisecond) }()
+
+	waitRecorded(t, logPath, filepath.Join(root, "a", "sub", "x.whl"))
+
+	// A move within the tree is watched under its new path; the old one is forgotten.
+	require.NoError(t, os.Rename(filepath.Join(root, "a"), filepath.Join(root, "b")))
+	waitRecorded(t, logPath, filepath.Join(root, "b", "sub", "x.whl"))
+	saved, err := LoadAccessLog(logPath)
+	require.NoError(t, err)
+	_, ok := saved.LastOpened(filepath.Join(root, "a", "sub", "x.whl"))
+	assert.False(t, ok, "entries under a moved directory are forgotten")
+
+	// A directory moved out of the roots is no longer watched.
+	require.NoError(t, os.Rename(filepath.Join(root, "b"), filepath.Join(outside, "b")))
+	f, err := os.Open(filepath.Join(outside, "b", "sub", "x.whl"))
+	require.NoError(t, err)
+	_ = f.Close()
+	createTestFile(t, filepath.Join(root, "marker.whl"), 10, 0)
+	waitRecorded(t, logPath, filepath.Join(root, "marker.whl"))
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, 1, tracker.Watches())
+	saved, err = LoadAccessLog(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved.Len(), "only the marker is logged")
+}
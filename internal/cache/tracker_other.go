@@ -0,0 +1,27 @@
+//go:build !linux
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Tracker records file opens; only Linux has inotify, so it cannot run elsewhere.
+type Tracker struct{}
+
+// NewTracker fails: neither macOS FSEvents nor other platforms' watchers report opens.
+func NewTracker([]string, *AccessLog) (*Tracker, error) {
+	return nil, errors.New("tracking file opens needs inotify, which only Linux has")
+}
+
+// Watches returns how many directories are watched.
+func (t *Tracker) Watches() int {
+	return 0
+}
+
+// Run returns at once.
+func (t *Tracker) Run(context.Context, time.Duration) error {
+	return nil
+}
@@ -3,19 +3,30 @@ package cache
 import (
 	"context"
 	"fmt"
-	"os"
-	"sort"
 	"strings"
 	"time"
 
+	"github.com/Automaat/cache-buster/internal/config"
 	"github.com/Automaat/cache-buster/pkg/size"
 )
 
 // TrimOptions configures cache trimming.
 type TrimOptions struct {
-	MaxSize int64         // Target size (10% buffer applied internally)
-	MaxAge  time.Duration // Delete files older than this
+	MaxSize int64         // Trimmed down to this less Headroom
+	MaxAge  time.Duration // Delete files not used for this long
+	Filter  Filter        // Excluded paths are ignored; protected files count toward size but are kept
 	DryRun  bool
+	// SkipOpenFiles keeps files another process has open, reporting each
+	// as ReasonFileLocked.
+	SkipOpenFiles bool
+	LRUKey        LRUKey // File time telling when a file was last used; ModTime by default
+	// AccessLog, when set, moves LastUsed up to recorded opens.
+	AccessLog *AccessLog
+	// Strategy picks the files deleted to get under the size target; LRU when nil.
+	Strategy EvictionStrategy
+	// Headroom is the share of MaxSize freed below it, so the cache does not
+	// hit its limit again at once: config.DefaultHeadroom when 0, none when negative.
+	Headroom float64
 }
 
 // TrimResult contains trimming operation results.
@@ -24,15 +35,21 @@ type TrimResult struct {
 	Errors       []AccessError
 	DeletedCount int64
 	FreedBytes   int64
+	Fixups       int64 // read-only directories temporarily made writable
 }
 
-const trimBufferFactor = 0.9 // Keep 10% headroom below max_size
-
 // Trim deletes files that are:
-// - older than MaxAge, OR
-// - oldest files until total ≤ MaxSize (with 10% buffer).
+// - unused for longer than MaxAge, OR
+// - first in opts.Strategy's order until total ≤ MaxSize less Headroom.
+//
+// When a file was last used is read from the file time named by opts.LRUKey,
+// or from opts.AccessLog where it recorded a later open.
+//
+// Files protected by opts.Filter are never deleted; excluded ones are not
+// considered. With opts.SkipOpenFiles, files open in another process are
+// protected too.
 func Trim(ctx context.Context, paths []string, opts TrimOptions) (TrimResult, error) {
-	listResult, err := ListFilesContext(ctx, paths)
+	listResult, err := ListFilesByRecency(ctx, paths, opts.Filter, opts.LRUKey)
 	if err != nil {
 		return TrimResult{}, err
 	}
@@ -41,20 +58,16 @@ func Trim(ctx context.Context, paths []string, opts TrimOptions) (TrimResult, er
 	if len(files) == 0 {
 		return TrimResult{Output: "no files found", Errors: listResult.Warnings}, nil
 	}
-
-	// Sort by ModTime (oldest first). ModTime is appropriate for dev caches
-	// where content-addressable storage updates mtime on access.
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].ModTime.Before(files[j].ModTime)
-	})
+	opts.AccessLog.Apply(files)
 
 	var (
 		totalSize     int64
 		result        TrimResult
 		deleteErrors  []AccessError
 		output        strings.Builder
-		cutoff        = time.Now().Add(-opts.MaxAge)
-		targetSize    = int64(float64(opts.MaxSize) * trimBufferFactor)
+		now           = time.Now()
+		cutoff        = now.Add(-opts.MaxAge)
+		targetSize    = TrimTarget(opts.MaxSize, opts.Headroom)
 		toDelete      []FileInfo
 		remainingSize int64
 	)
@@ -62,56 +75,76 @@ func Trim(ctx context.Context, paths []string, opts TrimOptions) (TrimResult, er
 	// Carry forward scan warnings
 	deleteErrors = append(deleteErrors, listResult.Warnings...)
 
+	// Both phases delete in strategy order; least recently used by default.
+	strategy := opts.Strategy
+	if strategy == nil {
+		strategy = lruStrategy{}
+	}
+	strategy.Order(files, now)
+
+	if opts.SkipOpenFiles {
+		kept, err := ProtectOpen(files, paths)
+		if err != nil {
+			return TrimResult{}, err
+		}
+		if opts.DryRun {
+			for _, k := range kept {
+				fmt.Fprintf(&output, "skipping open file: %s\n", k.Path)
+			}
+		}
+		deleteErrors = append(deleteErrors, kept...)
+	}
+
 	for _, f := range files {
 		totalSize += f.Size
 	}
 
-	// Phase 1: mark files older than MaxAge for deletion
+	// Phase 1: mark files unused for longer than MaxAge for deletion
 	for _, f := range files {
-		if f.ModTime.Before(cutoff) {
+		if f.Protected {
+			remainingSize += f.Size
+			continue
+		}
+		if f.LastUsed.Before(cutoff) {
 			toDelete = append(toDelete, f)
 		} else {
 			remainingSize += f.Size
 		}
 	}
 
-	// Phase 2: if still over target, delete oldest remaining files
-	if remainingSize > targetSize {
-		var remaining []FileInfo
-		for _, f := range files {
-			if f.ModTime.Before(cutoff) {
-				continue // already marked
-			}
-			remaining = append(remaining, f)
+	// Phase 2: if still over target, delete remaining files in strategy order
+	for _, f := range files {
+		if remainingSize <= targetSize {
+			break
 		}
-
-		for _, f := range remaining {
-			if remainingSize <= targetSize {
-				break
-			}
-			toDelete = append(toDelete, f)
-			remainingSize -= f.Size
+		if f.Protected || f.LastUsed.Before(cutoff) {
+			continue // kept, or already marked
 		}
+		toDelete = append(toDelete, f)
+		remainingSize -= f.Size
 	}
 
 	// Execute deletions
+	remover := Remover{Roots: paths}
 	for _, f := range toDelete {
 		select {
 		case <-ctx.Done():
+			remover.Restore()
+			result.Fixups = remover.Fixups()
 			result.Output = "interrupted"
 			return result, ctx.Err()
 		default:
 		}
 
 		if opts.DryRun {
-			age := time.Since(f.ModTime).Truncate(time.Hour)
+			age := time.Since(f.LastUsed).Truncate(time.Hour)
 			fmt.Fprintf(&output, "would delete: %s (%s, age: %s)\n", f.Path, size.FormatSize(f.Size), age)
 			result.FreedBytes += f.Size
 			result.DeletedCount++
 			continue
 		}
 
-		if err := os.Remove(f.Path); err != nil {
+		if err := remover.Remove(f.Path); err != nil {
 			deleteErrors = append(deleteErrors, ClassifyError(f.Path, err))
 			continue
 		}
@@ -125,8 +158,23 @@ func Trim(ctx context.Context, paths []string, opts TrimOptions) (TrimResult, er
 		return result, nil
 	}
 
-	result.Output = fmt.Sprintf("deleted %d files", result.DeletedCount)
+	deleteErrors = append(deleteErrors, remover.Restore()...)
+	result.Fixups = remover.Fixups()
+
+	result.Output = FormatDeleted(result.DeletedCount, result.Fixups)
 	result.Errors = deleteErrors
 
 	return result, nil
 }
+
+// TrimTarget is the size a clean gets under: maxSize less a headroom share
+// of it, read as TrimOptions.Headroom is.
+func TrimTarget(maxSize int64, headroom float64) int64 {
+	switch {
+	case headroom == 0:
+		headroom = config.DefaultHeadroom
+	case headroom < 0:
+		headroom = 0
+	}
+	return int64(float64(maxSize) * (1 - headroom))
+}
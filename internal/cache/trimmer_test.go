@@ -236,3 +236,26 @@ func TestTrim_CarriesForwardScanWarnings(t *testing.T) {
 	// Should have warning from scan
 	assert.NotEmpty(t, result.Errors)
 }
+
+func TestTrim_HonorsFilter(t *testing.T) {
+	dir := t.TempDir()
+
+	createTestFile(t, filepath.Join(dir, "jars-9", "old.jar"), 1000, 40*24*time.Hour)
+	createTestFile(t, filepath.Join(dir, "modules", "old.jar"), 1000, 40*24*time.Hour)
+	createTestFile(t, filepath.Join(dir, "modules", "gc.lock"), 1000, 40*24*time.Hour)
+	createTestFile(t, filepath.Join(dir, "modules", "new.jar"), 1000, time.Hour)
+
+	result, err := Trim(context.Background(), []string{dir}, TrimOptions{
+		// Protected files count toward size: 2000 remaining > 0.9*2000, so new.jar goes too.
+		MaxSize: 2000,
+		MaxAge:  30 * 24 * time.Hour,
+		Filter:  Filter{Exclude: []string{"**/*.lock"}, Protect: []string{"jars-*"}},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.DeletedCount)
+	assert.FileExists(t, filepath.Join(dir, "jars-9", "old.jar"))
+	assert.FileExists(t, filepath.Join(dir, "modules", "gc.lock"))
+	assert.NoFileExists(t, filepath.Join(dir, "modules", "old.jar"))
+	assert.NoFileExists(t, filepath.Join(dir, "modules", "new.jar"))
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -41,7 +42,7 @@ func runClean(cmd *cobra.Command, args []string) error {
 	quiet, _ := cmd.Flags().GetBool("quiet")
 	smart, _ := cmd.Flags().GetBool("smart")
 
-	return runCleanWithLoader(config.NewLoader(), args, allFlag, dryRun, force, quiet, smart, os.Stdin)
+	return runCleanWithLoader(NewLoader(cmd), args, allFlag, dryRun, force, quiet, smart, os.Stdin)
 }
 
 func runCleanWithLoader(loader *config.Loader, args []string, allFlag, dryRun, force, quiet, smart bool, stdin *os.File) error {
@@ -67,7 +68,7 @@ func runCleanWithLoader(loader *config.Loader, args []string, allFlag, dryRun, f
 	}
 
 	if !force && !dryRun {
-		if !confirmClean(providers, smart, stdin) {
+		if !confirmClean(cfg, providers, smart, stdin) {
 			fmt.Println("Aborted")
 			return nil
 		}
@@ -81,7 +82,7 @@ func runCleanWithLoader(loader *config.Loader, args []string, allFlag, dryRun, f
 		mode = provider.CleanModeSmart
 	}
 
-	return executeClean(ctx, providers, dryRun, quiet, mode)
+	return executeClean(ctx, cfg, providers, dryRun, quiet, mode)
 }
 
 func resolveProviders(cfg *config.Config, args []string, allFlag bool) ([]string, error) {
@@ -134,10 +135,19 @@ func loadAndFilterProviders(cfg *config.Config, names []string) (providers []pro
 	return providers, unavailable
 }
 
-func confirmClean(providers []provider.Provider, smart bool, stdin *os.File) bool {
+// providerLabel returns name as clean prints it, tagged when the provider
+// comes from a project config.
+func providerLabel(cfg *config.Config, name string) string {
+	if p, ok := cfg.GetProvider(name); ok && p.Project != "" {
+		return name + " " + projectTag
+	}
+	return name
+}
+
+func confirmClean(cfg *config.Config, providers []provider.Provider, smart bool, stdin *os.File) bool {
 	names := make([]string, len(providers))
 	for i, p := range providers {
-		names[i] = p.Name()
+		names[i] = providerLabel(cfg, p.Name())
 	}
 
 	modeStr := "full"
@@ -153,9 +163,14 @@ func confirmClean(providers []provider.Provider, smart bool, stdin *os.File) boo
 	return response == "y" || response == "yes"
 }
 
-func executeClean(ctx context.Context, providers []provider.Provider, dryRun, quiet bool, mode provider.CleanMode) error {
+func executeClean(ctx context.Context, cfg *config.Config, providers []provider.Provider, dryRun, quiet bool, mode provider.CleanMode) error {
 	var totalCleaned int64
-	var errors []string
+	var failures []string
+
+	accessLog, err := loadAccessLog()
+	if err != nil && !quiet {
+		fmt.Fprintf(os.Stderr, "warning: %v; ignoring recorded opens\n", err)
+	}
 
 	for _, p := range providers {
 		select {
@@ -167,13 +182,28 @@ func executeClean(ctx context.Context, providers []provider.Provider, dryRun, qu
 		default:
 		}
 
+		label := providerLabel(cfg, p.Name())
 		if !quiet && !dryRun {
-			fmt.Printf("Cleaning %s... ", p.Name())
+			fmt.Printf("Cleaning %s... ", label)
+		}
+
+		proceed, err := waitIdle(ctx, p, label, dryRun, quiet)
+		if err != nil {
+			if ctx.Err() != nil {
+				if !quiet {
+					fmt.Println("\nCancelled")
+				}
+				return nil
+			}
+			return err
+		}
+		if !proceed {
+			continue
 		}
 
-		result, err := p.Clean(ctx, provider.CleanOptions{DryRun: dryRun, Mode: mode})
+		result, err := p.Clean(ctx, provider.CleanOptions{AccessLog: accessLog, DryRun: dryRun, Mode: mode})
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", p.Name(), err))
+			failures = append(failures, fmt.Sprintf("%s: %v", label, err))
 			if !quiet {
 				fmt.Println("error")
 			}
@@ -184,7 +214,7 @@ func executeClean(ctx context.Context, providers []provider.Provider, dryRun, qu
 
 		if dryRun {
 			if !quiet {
-				fmt.Printf("[dry-run] %s: %s\n", p.Name(), result.Output)
+				fmt.Printf("[dry-run] %s: %s\n", label, result.Output)
 			}
 		} else if !quiet {
 			fmt.Printf("done (freed %s)\n", size.FormatSize(result.BytesCleaned))
@@ -197,9 +227,47 @@ func executeClean(ctx context.Context, providers []provider.Provider, dryRun, qu
 		fmt.Println(size.FormatSize(totalCleaned))
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("some providers failed:\n  %s", strings.Join(errors, "\n  "))
+	if len(failures) > 0 {
+		return fmt.Errorf("some providers failed:\n  %s", strings.Join(failures, "\n  "))
 	}
 
 	return nil
 }
+
+// waitIdle applies p's on_busy policy before cleaning it, reporting whether
+// to go ahead. A dry run only notes that p is in use. The error stops the
+// whole clean: an aborting on_busy, or ctx ending while waiting. label is p
+// as clean prints it.
+func waitIdle(ctx context.Context, p provider.Provider, label string, dryRun, quiet bool) (proceed bool, err error) {
+	if dryRun {
+		if c, ok := p.(provider.InUseChecker); ok && !quiet {
+			if reason := c.InUse(); reason != "" {
+				fmt.Printf("[dry-run] %s: in use (%s), a clean would %s\n", label, reason, c.OnBusy())
+			}
+		}
+		return true, nil
+	}
+
+	err = provider.WaitIdle(ctx, p, func(reason string) {
+		if !quiet {
+			fmt.Printf("waiting, in use (%s)... ", reason)
+		}
+	})
+	var inUse *provider.InUseError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &inUse) && inUse.Abort:
+		if !quiet {
+			fmt.Printf("aborted, in use (%s)\n", inUse.Reason)
+		}
+		return false, fmt.Errorf("clean aborted: %w", err)
+	case errors.As(err, &inUse):
+		if !quiet {
+			fmt.Printf("skipped, in use (%s)\n", inUse.Reason)
+		}
+		return false, nil
+	default:
+		return false, err
+	}
+}
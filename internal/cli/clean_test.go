@@ -245,7 +245,7 @@ func TestConfirmClean_Yes(t *testing.T) {
 	stdin := createStdinWithInput(t, "y\n")
 	var result bool
 	captureStdout(t, func() {
-		result = confirmClean(nil, false, stdin)
+		result = confirmClean(nil, nil, false, stdin)
 	})
 	assert.True(t, result)
 }
@@ -254,7 +254,7 @@ func TestConfirmClean_No(t *testing.T) {
 	stdin := createStdinWithInput(t, "n\n")
 	var result bool
 	captureStdout(t, func() {
-		result = confirmClean(nil, false, stdin)
+		result = confirmClean(nil, nil, false, stdin)
 	})
 	assert.False(t, result)
 }
@@ -263,7 +263,7 @@ func TestConfirmClean_Empty(t *testing.T) {
 	stdin := createStdinWithInput(t, "\n")
 	var result bool
 	captureStdout(t, func() {
-		result = confirmClean(nil, false, stdin)
+		result = confirmClean(nil, nil, false, stdin)
 	})
 	assert.False(t, result)
 }
@@ -382,3 +382,108 @@ providers:
 	assert.Contains(t, output, "Cleaning test-provider")
 	assert.Contains(t, output, "done")
 }
+
+// busyConfig returns a loader for a provider whose lock file the test holds,
+// cleaned per onBusy, and a provider that is free.
+func busyConfig(t *testing.T, onBusy string) *config.Loader {
+	t.Helper()
+	cacheDir := t.TempDir()
+	lock := filepath.Join(cacheDir, ".package-cache")
+	require.NoError(t, os.WriteFile(lock, nil, 0o600))
+	f, err := os.Open(lock)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+	flockFile(t, f)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	cfgContent := `version: "1"
+providers:
+  busy-provider:
+    enabled: true
+    paths:
+      - ` + cacheDir + `
+    max_size: 1GB
+    clean_cmd: "echo cleaned"
+    lock_files:
+      - ` + lock + `
+    on_busy: ` + onBusy + `
+  working-provider:
+    enabled: true
+    paths:
+      - ` + cacheDir + `
+    max_size: 1GB
+    clean_cmd: "echo cleaned"
+`
+	require.NoError(t, os.WriteFile(cfgPath, []byte(cfgContent), 0o600))
+
+	loader := config.NewLoader()
+	loader.SetConfigPath(cfgPath)
+	loader.SkipDefaults()
+	return loader
+}
+
+func TestClean_InUse_Skip(t *testing.T) {
+	loader := busyConfig(t, config.BusySkip)
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runCleanWithLoader(loader, nil, true, false, true, false, false, os.Stdin)
+	})
+
+	require.NoError(t, err)
+	assert.Contains(t, output, "Cleaning busy-provider... skipped, in use (")
+	assert.Contains(t, output, "Cleaning working-provider... done")
+}
+
+func TestClean_InUse_Abort(t *testing.T) {
+	loader := busyConfig(t, config.BusyAbort)
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runCleanWithLoader(loader, nil, true, false, true, false, false, os.Stdin)
+	})
+
+	require.ErrorContains(t, err, "clean aborted: busy-provider in use")
+	assert.NotContains(t, output, "working-provider")
+}
+
+func TestClean_InUse_DryRunNotes(t *testing.T) {
+	loader := busyConfig(t, config.BusyWait)
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runCleanWithLoader(loader, nil, true, true, false, false, false, os.Stdin)
+	})
+
+	require.NoError(t, err)
+	assert.Contains(t, output, "[dry-run] busy-provider: in use (")
+	assert.Contains(t, output, "a clean would wait")
+}
+
+func TestClean_ProjectTag(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "app")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "build"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "build", "out.o"), []byte("object"), 0o600))
+	project := "providers:\n  build:\n    enabled: true\n    paths: [build]\n    max_size: 1B\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, config.ProjectFile), []byte(project), 0o600))
+
+	loader := config.NewLoader()
+	loader.SetConfigPath(filepath.Join(tmpDir, "config.yaml"))
+	loader.SetProjectDir(root)
+	loader.SkipDefaults()
+
+	stdin := createStdinWithInput(t, "n\n")
+	var err error
+	output := captureStdout(t, func() {
+		err = runCleanWithLoader(loader, []string{"app/build"}, false, false, false, false, false, stdin)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, output, "app/build "+projectTag+"?")
+
+	output = captureStdout(t, func() {
+		err = runCleanWithLoader(loader, []string{"app/build"}, false, true, false, false, false, os.Stdin)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, output, "[dry-run] app/build "+projectTag+":")
+}
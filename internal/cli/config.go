@@ -11,6 +11,9 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// ConfigFlag is the persistent root flag selecting the config file.
+const ConfigFlag = "config"
+
 // ConfigCmd manages configuration.
 var ConfigCmd = &cobra.Command{
 	Use:   "config",
@@ -36,34 +39,93 @@ var configEditCmd = &cobra.Command{
 }
 
 func init() {
+	configShowCmd.Flags().Bool("origin", false, "Annotate each provider field with the file that set it")
 	ConfigCmd.AddCommand(configShowCmd)
 	ConfigCmd.AddCommand(configInitCmd)
 	ConfigCmd.AddCommand(configEditCmd)
 }
 
-func runConfigShow(_ *cobra.Command, _ []string) error {
-	return runConfigShowWithLoader(config.NewLoader())
+// NewLoader returns a config loader honoring the global --config flag.
+func NewLoader(cmd *cobra.Command) *config.Loader {
+	loader := config.NewLoader()
+	if path, _ := cmd.Flags().GetString(ConfigFlag); path != "" {
+		loader.SetConfigPath(path)
+	}
+	return loader
 }
 
-func runConfigShowWithLoader(loader *config.Loader) error {
+func runConfigShow(cmd *cobra.Command, _ []string) error {
+	origin, _ := cmd.Flags().GetBool("origin")
+	return runConfigShowWithLoader(NewLoader(cmd), origin)
+}
+
+func runConfigShowWithLoader(loader *config.Loader, origin bool) error {
 	cfg, err := loader.Load()
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
 
-	out, err := yaml.Marshal(cfg)
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	if origin {
+		annotateOrigins(&doc, loader)
+	}
+
+	out, err := yaml.Marshal(&doc)
 	if err != nil {
 		return fmt.Errorf("marshal config: %w", err)
 	}
 
-	configPath, _ := config.Path()
+	configPath, _ := loader.Path()
 	fmt.Printf("# %s\n", configPath)
+	if origin {
+		for _, f := range loader.Files() {
+			fmt.Printf("# merged: %s\n", f)
+		}
+	}
 	fmt.Print(string(out))
 	return nil
 }
 
-func runConfigInit(_ *cobra.Command, _ []string) error {
-	return runConfigInitWithLoader(config.NewLoader())
+// annotateOrigins adds a line comment naming the source file to every
+// provider field in the encoded config document.
+func annotateOrigins(doc *yaml.Node, loader *config.Loader) {
+	providers := mappingValue(doc, "providers")
+	if providers == nil {
+		return
+	}
+	for i := 0; i+1 < len(providers.Content); i += 2 {
+		name := providers.Content[i].Value
+		fields := providers.Content[i+1]
+		for j := 0; j+1 < len(fields.Content); j += 2 {
+			key := fields.Content[j]
+			if src := loader.Origin("providers." + name + "." + key.Value); src != "" {
+				key.LineComment = src
+			}
+		}
+	}
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func runConfigInit(cmd *cobra.Command, _ []string) error {
+	return runConfigInitWithLoader(NewLoader(cmd))
 }
 
 func runConfigInitWithLoader(loader *config.Loader) error {
@@ -72,7 +134,7 @@ func runConfigInitWithLoader(loader *config.Loader) error {
 		return fmt.Errorf("init config: %w", err)
 	}
 
-	configPath, _ := config.Path()
+	configPath, _ := loader.Path()
 	if created {
 		fmt.Printf("Created %s\n", configPath)
 	} else {
@@ -81,8 +143,8 @@ func runConfigInitWithLoader(loader *config.Loader) error {
 	return nil
 }
 
-func runConfigEdit(_ *cobra.Command, _ []string) error {
-	return runConfigEditWithLoader(config.NewLoader(), os.Getenv("EDITOR"))
+func runConfigEdit(cmd *cobra.Command, _ []string) error {
+	return runConfigEditWithLoader(NewLoader(cmd), os.Getenv("EDITOR"))
 }
 
 func runConfigEditWithLoader(loader *config.Loader, editor string) error {
@@ -95,7 +157,7 @@ func runConfigEditWithLoader(loader *config.Loader, editor string) error {
 		return fmt.Errorf("init config: %w", err)
 	}
 
-	configPath, err := config.Path()
+	configPath, err := loader.Path()
 	if err != nil {
 		return fmt.Errorf("get config path: %w", err)
 	}
@@ -3,9 +3,11 @@ package cli
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/spf13/cobra"
 )
 
 func TestConfigShow(t *testing.T) {
@@ -15,7 +17,7 @@ func TestConfigShow(t *testing.T) {
 	loader := config.NewLoader()
 	loader.SetConfigPath(configPath)
 
-	err := runConfigShowWithLoader(loader)
+	err := runConfigShowWithLoader(loader, false)
 	if err != nil {
 		t.Fatalf("runConfigShowWithLoader failed: %v", err)
 	}
@@ -26,6 +28,43 @@ func TestConfigShow(t *testing.T) {
 	}
 }
 
+func TestConfigShow_Origin(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	dropIn := filepath.Join(tmpDir, "config.d")
+	if err := os.Mkdir(dropIn, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	orgPath := filepath.Join(dropIn, "org.yaml")
+	orgContent := "providers:\n  tool:\n    enabled: true\n    paths: [/opt/tool]\n    max_size: 1G\n"
+	if err := os.WriteFile(orgPath, []byte(orgContent), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, []byte("providers:\n  tool:\n    max_size: 2G\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := config.NewLoader()
+	loader.SetConfigPath(configPath)
+	loader.SkipDefaults()
+
+	out := captureStdout(t, func() {
+		if err := runConfigShowWithLoader(loader, true); err != nil {
+			t.Errorf("runConfigShowWithLoader failed: %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "max_size: 2G # "+configPath) {
+		t.Errorf("max_size should be annotated with main config, got:\n%s", out)
+	}
+	if !strings.Contains(out, "enabled: true # "+orgPath) {
+		t.Errorf("enabled should be annotated with drop-in file, got:\n%s", out)
+	}
+	if !strings.Contains(out, "# merged: "+orgPath) {
+		t.Errorf("merged files header missing, got:\n%s", out)
+	}
+}
+
 func TestConfigInit_New(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.yaml")
@@ -80,3 +119,45 @@ func TestConfigEdit_NoEditor(t *testing.T) {
 		t.Error("config file not created before edit")
 	}
 }
+
+func TestNewLoader_ConfigFlag(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "ci.yaml")
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String(ConfigFlag, "", "")
+	if err := cmd.Flags().Set(ConfigFlag, configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := NewLoader(cmd).Path()
+	if err != nil {
+		t.Fatalf("Path() error = %v", err)
+	}
+	if path != configPath {
+		t.Errorf("Path() = %q, want %q", path, configPath)
+	}
+}
+
+func TestConfigShow_EnvOrigin(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("CACHE_BUSTER_PROVIDERS_NPM_MAX_SIZE", "9G")
+
+	loader := config.NewLoader()
+	loader.SetConfigPath(configPath)
+
+	out := captureStdout(t, func() {
+		if err := runConfigShowWithLoader(loader, true); err != nil {
+			t.Errorf("runConfigShowWithLoader failed: %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "# "+configPath) {
+		t.Errorf("header should name the loader's config path, got:\n%s", out)
+	}
+	if !strings.Contains(out, "max_size: 9G # env CACHE_BUSTER_PROVIDERS_NPM_MAX_SIZE") {
+		t.Errorf("env override should be annotated, got:\n%s", out)
+	}
+	if !strings.Contains(out, "# "+config.OriginDefault) {
+		t.Errorf("default fields should be annotated, got:\n%s", out)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin
+
+package cli
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// flockFile takes an exclusive flock on f, as a tool holding its cache does,
+// and returns a func releasing it.
+func flockFile(t *testing.T, f *os.File) func() {
+	t.Helper()
+	require.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX))
+	return func() { _ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN) }
+}
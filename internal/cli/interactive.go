@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -12,6 +13,7 @@ import (
 	"charm.land/bubbles/v2/spinner"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/Automaat/cache-buster/internal/cache"
 	"github.com/Automaat/cache-buster/internal/config"
 	"github.com/Automaat/cache-buster/internal/provider"
 	"github.com/Automaat/cache-buster/pkg/size"
@@ -33,11 +35,16 @@ type providerItem struct {
 	cleanResult *provider.CleanResult
 	cleanErr    error
 	name        string
+	project     string
 	currentFmt  string
 	maxFmt      string
+	reclaimFmt  string
+	breakdown   string // per-category usage, e.g. "images 2.0 GB (1.5 GB reclaimable)"
+	inUse       string // why the owning tool is using the cache
 	errMsg      string
 	current     int64
 	max         int64
+	reclaimable int64
 	overLimit   bool
 	available   bool
 }
@@ -46,6 +53,7 @@ type model struct {
 	progress   progress.Model
 	ctx        context.Context
 	cfg        *config.Config
+	accessLog  *cache.AccessLog // opens recorded by track; nil without any
 	selected   map[int]struct{}
 	providers  []providerItem
 	spinner    spinner.Model
@@ -87,7 +95,7 @@ func init() {
 func runInteractive(cmd *cobra.Command, _ []string) error {
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	full, _ := cmd.Flags().GetBool("full")
-	return RunInteractiveWithLoader(config.NewLoader(), dryRun, !full)
+	return RunInteractiveWithLoader(NewLoader(cmd), dryRun, !full)
 }
 
 // RunInteractiveWithLoader launches interactive mode with specified loader.
@@ -125,6 +133,9 @@ func newModel(cfg *config.Config, providerNames []string, dryRun, smart bool, ct
 	items := make([]providerItem, len(providerNames))
 	for i, name := range providerNames {
 		items[i] = providerItem{name: name}
+		if provCfg, ok := cfg.GetProvider(name); ok {
+			items[i].project = provCfg.Project
+		}
 	}
 
 	s := spinner.New(
@@ -138,6 +149,10 @@ func newModel(cfg *config.Config, providerNames []string, dryRun, smart bool, ct
 		ctx = context.Background()
 	}
 
+	// An unreadable access log leaves cleans to lru_key alone; the TUI has
+	// nowhere to warn before it starts.
+	accessLog, _ := loadAccessLog()
+
 	return model{
 		state:     stateSelection,
 		providers: items,
@@ -146,6 +161,7 @@ func newModel(cfg *config.Config, providerNames []string, dryRun, smart bool, ct
 		progress:  prog,
 		cfg:       cfg,
 		ctx:       ctx,
+		accessLog: accessLog,
 		dryRun:    dryRun,
 		smartMode: smart,
 		width:     80,
@@ -165,7 +181,7 @@ func (m model) Init() tea.Cmd {
 func (m model) scanProviderCmd(idx int) tea.Cmd {
 	return func() tea.Msg {
 		name := m.providers[idx].name
-		item := providerItem{name: name}
+		item := providerItem{name: name, project: m.providers[idx].project}
 
 		p, err := provider.LoadProvider(name, m.cfg)
 		if err != nil {
@@ -193,6 +209,23 @@ func (m model) scanProviderCmd(idx int) tea.Cmd {
 		item.maxFmt = size.FormatSize(p.MaxSize())
 		item.overLimit = currentSize > p.MaxSize()
 
+		if r, ok := p.(provider.Reclaimer); ok {
+			if reclaimable, err := r.ReclaimableSize(m.ctx); err == nil && reclaimable > 0 {
+				item.reclaimable = reclaimable
+				item.reclaimFmt = size.FormatSize(reclaimable)
+			}
+		}
+
+		if b, ok := p.(provider.Breakdowner); ok {
+			if categories, err := b.Breakdown(m.ctx); err == nil {
+				item.breakdown = formatBreakdown(categories)
+			}
+		}
+
+		if c, ok := p.(provider.InUseChecker); ok {
+			item.inUse = c.InUse()
+		}
+
 		return scanResultMsg{idx: idx, item: item}
 	}
 }
@@ -219,6 +252,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err == nil {
 			m.totalFreed += msg.result.BytesCleaned
 		}
+		var inUse *provider.InUseError
+		if errors.As(msg.err, &inUse) && inUse.Abort {
+			m.state = stateDone
+			return m, nil
+		}
 		return m.cleanNext()
 
 	case spinner.TickMsg:
@@ -287,7 +325,7 @@ func (m model) handleSelectionKey(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 	case "o":
 		m.selected = make(map[int]struct{})
 		for i := range m.providers {
-			if m.providers[i].available && m.providers[i].overLimit {
+			if m.providers[i].available && (m.providers[i].overLimit || m.providers[i].reclaimable > 0) {
 				m.selected[i] = struct{}{}
 			}
 		}
@@ -357,9 +395,16 @@ func (m model) cleanProviderCmd(idx int) tea.Cmd {
 			mode = provider.CleanModeSmart
 		}
 
+		if !m.dryRun {
+			if err := provider.WaitIdle(m.ctx, p, nil); err != nil {
+				return cleanResultMsg{idx: idx, err: err}
+			}
+		}
+
 		result, err := p.Clean(m.ctx, provider.CleanOptions{
-			DryRun: m.dryRun,
-			Mode:   mode,
+			AccessLog: m.accessLog,
+			DryRun:    m.dryRun,
+			Mode:      mode,
 		})
 
 		return cleanResultMsg{idx: idx, result: result, err: err}
@@ -451,8 +496,8 @@ func (m model) viewSelection() string {
 	// Find max provider name length
 	maxNameLen := 0
 	for i := range m.providers {
-		if len(m.providers[i].name) > maxNameLen {
-			maxNameLen = len(m.providers[i].name)
+		if n := len(m.providers[i].label()); n > maxNameLen {
+			maxNameLen = n
 		}
 	}
 	if maxNameLen < 10 {
@@ -485,19 +530,25 @@ func (m model) viewSelection() string {
 		var line string
 		switch {
 		case p.errMsg != "":
-			line = prefix + fmt.Sprintf(nameFmt, p.name) + " " + errorStyle.Render(p.errMsg)
+			line = prefix + fmt.Sprintf(nameFmt, p.label()) + " " + errorStyle.Render(p.errMsg)
 		case p.currentFmt == "":
 			sizeCol := fmt.Sprintf("%10s / %-10s", "-", "-")
-			line = prefix + fmt.Sprintf(nameFmt, p.name) + " " + sizeCol + " " + m.spinner.View()
+			line = prefix + fmt.Sprintf(nameFmt, p.label()) + " " + sizeCol + " " + m.spinner.View()
 		case !p.available:
-			line = prefix + dimStyle.Render(fmt.Sprintf(nameFmt+" (unavailable)", p.name))
+			line = prefix + dimStyle.Render(fmt.Sprintf(nameFmt+" (unavailable)", p.label()))
 		default:
 			status := okStyle.Render("ok")
-			if p.overLimit {
+			switch {
+			case p.inUse != "":
+				status = errorStyle.Render("in use")
+			case p.overLimit:
 				status = overStyle.Render("OVER")
 			}
 			sizeCol := fmt.Sprintf("%10s / %-10s", p.currentFmt, p.maxFmt)
-			line = prefix + fmt.Sprintf(nameFmt, p.name) + " " + sizeCol + " " + status
+			line = prefix + fmt.Sprintf(nameFmt, p.label()) + " " + sizeCol + " " + status
+			if p.reclaimFmt != "" {
+				line += " " + dimStyle.Render(fmt.Sprintf("(%s reclaimable)", p.reclaimFmt))
+			}
 		}
 
 		if i == m.cursor {
@@ -505,13 +556,22 @@ func (m model) viewSelection() string {
 		}
 		b.WriteString(line)
 		b.WriteString("\n")
+
+		if p.breakdown != "" && p.errMsg == "" && p.available {
+			b.WriteString(dimStyle.Render(truncateRight("      "+p.breakdown, contentWidth)))
+			b.WriteString("\n")
+		}
+		if p.inUse != "" && p.errMsg == "" && p.available {
+			b.WriteString(dimStyle.Render(truncateRight("      in use: "+p.inUse, contentWidth)))
+			b.WriteString("\n")
+		}
 	}
 
 	b.WriteString("\n")
 	fmt.Fprintf(&b, "Selected: %d provider(s)", len(m.selected))
 	b.WriteString("\n\n")
 
-	hint := dimStyle.Render("space=toggle  a=all  n=none  o=over-limit  enter=confirm  q=quit")
+	hint := dimStyle.Render("space=toggle  a=all  n=none  o=over-limit/stale  enter=confirm  q=quit")
 	b.WriteString(hint)
 
 	return b.String()
@@ -577,9 +637,12 @@ func (m model) viewCleaning() string {
 				continue
 			}
 			if p.cleanResult != nil {
-				if p.cleanErr != nil {
+				switch {
+				case p.skipReason() != "":
+					fmt.Fprintf(&b, "  %-14s %s\n", p.name, dimStyle.Render("skipped"))
+				case p.cleanErr != nil:
 					fmt.Fprintf(&b, "  %-14s %s\n", p.name, errorStyle.Render("error"))
-				} else {
+				default:
 					freed := size.FormatSize(p.cleanResult.BytesCleaned)
 					fmt.Fprintf(&b, "  %-14s freed %10s\n", p.name, freed)
 				}
@@ -600,28 +663,31 @@ func (m model) viewDone() string {
 	}
 	b.WriteString("\n\n")
 
-	var errors []string
+	var failures []string
 	for i := range m.providers {
 		p := &m.providers[i]
 		if _, ok := m.selected[i]; !ok {
 			continue
 		}
 		if p.cleanResult != nil {
-			if p.cleanErr != nil {
-				errors = append(errors, fmt.Sprintf("%s: %v", p.name, p.cleanErr))
+			switch reason := p.skipReason(); {
+			case reason != "":
+				fmt.Fprintf(&b, "  %-14s %s\n", p.name, dimStyle.Render("skipped ("+reason+")"))
+			case p.cleanErr != nil:
+				failures = append(failures, fmt.Sprintf("%s: %v", p.name, p.cleanErr))
 				fmt.Fprintf(&b, "  %s   %s\n", p.name, errorStyle.Render("✗"))
-			} else {
+			default:
 				freed := size.FormatSize(p.cleanResult.BytesCleaned)
 				fmt.Fprintf(&b, "  %-14s %10s  %s\n", p.name, freed, okStyle.Render("✓"))
 			}
 		}
 	}
 
-	if len(errors) > 0 {
+	if len(failures) > 0 {
 		b.WriteString("\n")
 		b.WriteString(errorStyle.Render("Errors:"))
 		b.WriteString("\n")
-		for _, e := range errors {
+		for _, e := range failures {
 			fmt.Fprintf(&b, "  %s\n", e)
 		}
 	}
@@ -632,6 +698,25 @@ func (m model) viewDone() string {
 	return b.String()
 }
 
+// skipReason returns why the clean of p was skipped, or "" if it ran, failed,
+// or was aborted.
+func (p *providerItem) skipReason() string {
+	var inUse *provider.InUseError
+	if errors.As(p.cleanErr, &inUse) && !inUse.Abort {
+		return "in use: " + inUse.Reason
+	}
+	return ""
+}
+
+// label returns the provider name as shown in the selection list,
+// tagged when the provider comes from a project config.
+func (p *providerItem) label() string {
+	if p.project != "" {
+		return p.name + " " + projectTag
+	}
+	return p.name
+}
+
 func (m model) selectedNames() []string {
 	var names []string
 	for i := range m.providers {
@@ -673,6 +758,15 @@ func truncateLeft(s string, skip int) string {
 	return result.String()
 }
 
+// formatBreakdown joins categories into one line for the selection screen.
+func formatBreakdown(categories []provider.UsageCategory) string {
+	parts := make([]string, 0, len(categories))
+	for _, c := range categories {
+		parts = append(parts, fmt.Sprintf("%s %s (%s reclaimable)", c.Name, size.FormatSize(c.Size), size.FormatSize(c.Reclaimable)))
+	}
+	return strings.Join(parts, " · ")
+}
+
 func truncateRight(s string, keep int) string {
 	var result strings.Builder
 	width := 0
@@ -134,6 +134,19 @@ func TestModelSelectionKeyBindings(t *testing.T) {
 		assert.Contains(t, m.selected, 2)
 	})
 
+	t.Run("select over-limit includes reclaimable", func(t *testing.T) {
+		m := newModel(cfg, []string{"p1", "p2"}, false, false, nil)
+		m.providers[0].available = true
+		m.providers[0].reclaimable = 1024
+		m.providers[1].available = true
+
+		m2, _ := m.Update(keyPress('o'))
+		m = m2.(model)
+
+		assert.Contains(t, m.selected, 0)
+		assert.NotContains(t, m.selected, 1)
+	})
+
 	t.Run("cannot toggle unavailable", func(t *testing.T) {
 		m := newModel(cfg, []string{"p1"}, false, false, nil)
 		m.providers[0].available = false
@@ -491,6 +504,35 @@ func TestModelCleanResultPartialFailures(t *testing.T) {
 	assert.Nil(t, m.providers[2].cleanErr)
 }
 
+func TestModelCleanResultInUse(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]config.Provider{
+			"p1": {Enabled: true, Paths: []string{"/tmp/p1"}, MaxSize: "1G"},
+			"p2": {Enabled: true, Paths: []string{"/tmp/p2"}, MaxSize: "1G"},
+			"p3": {Enabled: true, Paths: []string{"/tmp/p3"}, MaxSize: "1G"},
+		},
+	}
+
+	m := newModel(cfg, []string{"p1", "p2", "p3"}, false, false, nil)
+	m.state = stateCleaning
+	m.selected[0] = struct{}{}
+	m.selected[1] = struct{}{}
+	m.selected[2] = struct{}{}
+	m.cleanIdx = 0
+
+	// A skipped provider moves on to the next one.
+	m2, _ := m.Update(cleanResultMsg{idx: 0, err: &provider.InUseError{Provider: "p1", Reason: "locked"}})
+	m = m2.(model)
+	assert.Equal(t, 1, m.cleanIdx)
+	assert.Equal(t, stateCleaning, m.state)
+
+	// An abort stops the clean.
+	m2, _ = m.Update(cleanResultMsg{idx: 1, err: &provider.InUseError{Provider: "p2", Reason: "locked", Abort: true}})
+	m = m2.(model)
+	assert.Equal(t, stateDone, m.state)
+	assert.Nil(t, m.providers[2].cleanResult)
+}
+
 func TestModelUpdateSpinnerTick(t *testing.T) {
 	cfg := &config.Config{
 		Providers: map[string]config.Provider{
@@ -679,6 +721,20 @@ func TestViewSelection(t *testing.T) {
 		assert.Contains(t, view, "p1")
 	})
 
+	t.Run("shows in use status", func(t *testing.T) {
+		m := newModel(cfg, []string{"p1"}, false, false, nil)
+		m.providers[0].available = true
+		m.providers[0].currentFmt = "2 GiB"
+		m.providers[0].maxFmt = "1 GiB"
+		m.providers[0].overLimit = true
+		m.providers[0].inUse = "cargo (pid 7) is running"
+		view := m.viewSelection()
+
+		assert.Contains(t, view, "in use")
+		assert.NotContains(t, view, "OVER")
+		assert.Contains(t, view, "in use: cargo (pid 7) is running")
+	})
+
 	t.Run("shows over limit status", func(t *testing.T) {
 		m := newModel(cfg, []string{"p1"}, false, false, nil)
 		m.providers[0].available = true
@@ -701,6 +757,21 @@ func TestViewSelection(t *testing.T) {
 		assert.Contains(t, view, "ok")
 	})
 
+	t.Run("shows category breakdown", func(t *testing.T) {
+		m := newModel(cfg, []string{"p1"}, false, false, nil)
+		m.width = 200
+		m.providers[0].available = true
+		m.providers[0].currentFmt = "3 GiB"
+		m.providers[0].maxFmt = "50 GiB"
+		m.providers[0].breakdown = formatBreakdown([]provider.UsageCategory{
+			{Name: "images", Size: 2 << 30, Reclaimable: 1 << 30},
+			{Name: "build-cache", Size: 1 << 30},
+		})
+		view := m.viewSelection()
+
+		assert.Contains(t, view, "images 2.0 GiB (1.0 GiB reclaimable) · build-cache 1.0 GiB (0 B reclaimable)")
+	})
+
 	t.Run("shows selected count", func(t *testing.T) {
 		m := newModel(cfg, []string{"p1", "p2", "p3"}, false, false, nil)
 		m.selected[0] = struct{}{}
@@ -725,6 +796,21 @@ func TestViewSelection(t *testing.T) {
 
 		assert.Contains(t, view, "[x]")
 	})
+
+	t.Run("tags project providers", func(t *testing.T) {
+		projectCfg := &config.Config{
+			Providers: map[string]config.Provider{
+				"app/build": {Enabled: true, Paths: []string{"/tmp/app/build"}, MaxSize: "1G", Project: "/tmp/app"},
+			},
+		}
+		m := newModel(projectCfg, []string{"app/build"}, false, false, nil)
+		m.providers[0].available = true
+		m.providers[0].currentFmt = "1 MiB"
+		m.providers[0].maxFmt = "1 GiB"
+		view := m.viewSelection()
+
+		assert.Contains(t, view, "app/build "+projectTag)
+	})
 }
 
 func TestViewCleaning(t *testing.T) {
@@ -823,6 +909,18 @@ func TestViewDone(t *testing.T) {
 		assert.Contains(t, view, "✓")
 	})
 
+	t.Run("shows skipped provider in use", func(t *testing.T) {
+		m := newModel(cfg, []string{"p1"}, false, false, nil)
+		m.state = stateDone
+		m.selected[0] = struct{}{}
+		m.providers[0].cleanResult = &provider.CleanResult{}
+		m.providers[0].cleanErr = &provider.InUseError{Provider: "p1", Reason: "cargo (pid 7) is running"}
+		view := m.viewDone()
+
+		assert.Contains(t, view, "skipped (in use: cargo (pid 7) is running)")
+		assert.NotContains(t, view, "Errors")
+	})
+
 	t.Run("shows failure mark", func(t *testing.T) {
 		m := newModel(cfg, []string{"p1"}, false, false, nil)
 		m.state = stateDone
@@ -18,17 +18,31 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// CategoryStatus holds the usage of one category within a provider, such as Docker images.
+type CategoryStatus struct {
+	Name           string `json:"name"`
+	SizeFmt        string `json:"size"`
+	ReclaimableFmt string `json:"reclaimable"`
+	Size           int64  `json:"size_bytes"`
+	Reclaimable    int64  `json:"reclaimable_bytes"`
+}
+
 // ProviderStatus holds scan result for a single provider.
 type ProviderStatus struct {
-	Name           string `json:"name"`
-	CurrentFmt     string `json:"current"`
-	MaxFmt         string `json:"max"`
-	Error          string `json:"error,omitempty"`
-	DiskImageFmt   string `json:"disk_image,omitempty"`
-	Current        int64  `json:"current_bytes"`
-	Max            int64  `json:"max_bytes"`
-	DiskImageBytes int64  `json:"disk_image_bytes,omitempty"`
-	OverLimit      bool   `json:"over_limit"`
+	Name           string           `json:"name"`
+	Project        string           `json:"project,omitempty"`
+	CurrentFmt     string           `json:"current"`
+	MaxFmt         string           `json:"max"`
+	Error          string           `json:"error,omitempty"`
+	DiskImageFmt   string           `json:"disk_image,omitempty"`
+	ReclaimableFmt string           `json:"reclaimable,omitempty"`
+	InUse          string           `json:"in_use,omitempty"` // why the owning tool is using the cache
+	Categories     []CategoryStatus `json:"categories,omitempty"`
+	Current        int64            `json:"current_bytes"`
+	Max            int64            `json:"max_bytes"`
+	DiskImageBytes int64            `json:"disk_image_bytes,omitempty"`
+	Reclaimable    int64            `json:"reclaimable_bytes,omitempty"`
+	OverLimit      bool             `json:"over_limit"`
 }
 
 // StatusOutput holds full status output for JSON serialization.
@@ -51,7 +65,7 @@ func init() {
 
 func runStatus(cmd *cobra.Command, _ []string) error {
 	jsonFlag, _ := cmd.Flags().GetBool("json")
-	return runStatusWithLoader(config.NewLoader(), jsonFlag)
+	return runStatusWithLoader(NewLoader(cmd), jsonFlag)
 }
 
 func runStatusWithLoader(loader *config.Loader, jsonOutput bool) error {
@@ -94,6 +108,9 @@ func scanProviders(ctx context.Context, cfg *config.Config, names []string) []Pr
 
 func scanProvider(ctx context.Context, cfg *config.Config, name string) ProviderStatus {
 	status := ProviderStatus{Name: name}
+	if provCfg, ok := cfg.GetProvider(name); ok {
+		status.Project = provCfg.Project
+	}
 
 	p, err := provider.LoadProvider(name, cfg)
 	if err != nil {
@@ -122,9 +139,40 @@ func scanProvider(ctx context.Context, cfg *config.Config, name string) Provider
 		}
 	}
 
+	if r, ok := p.(provider.Reclaimer); ok {
+		if reclaimable, recErr := r.ReclaimableSize(ctx); recErr == nil && reclaimable > 0 {
+			status.Reclaimable = reclaimable
+			status.ReclaimableFmt = size.FormatSize(reclaimable)
+		}
+	}
+
+	if b, ok := p.(provider.Breakdowner); ok {
+		if categories, bdErr := b.Breakdown(ctx); bdErr == nil {
+			status.Categories = categoryStatuses(categories)
+		}
+	}
+
+	if c, ok := p.(provider.InUseChecker); ok {
+		status.InUse = c.InUse()
+	}
+
 	return status
 }
 
+func categoryStatuses(categories []provider.UsageCategory) []CategoryStatus {
+	statuses := make([]CategoryStatus, 0, len(categories))
+	for _, c := range categories {
+		statuses = append(statuses, CategoryStatus{
+			Name:           c.Name,
+			Size:           c.Size,
+			SizeFmt:        size.FormatSize(c.Size),
+			Reclaimable:    c.Reclaimable,
+			ReclaimableFmt: size.FormatSize(c.Reclaimable),
+		})
+	}
+	return statuses
+}
+
 func outputJSON(statuses []ProviderStatus) error {
 	var total int64
 	for _, s := range statuses {
@@ -150,9 +198,12 @@ func outputTable(statuses []ProviderStatus) error {
 		total += s.Current
 
 		statusText := okStyle.Render("ok")
-		if s.Error != "" {
+		switch {
+		case s.Error != "":
 			statusText = errorStyle.Render("error")
-		} else if s.OverLimit {
+		case s.InUse != "":
+			statusText = errorStyle.Render("in use")
+		case s.OverLimit:
 			statusText = overStyle.Render("OVER")
 		}
 
@@ -160,6 +211,9 @@ func outputTable(statuses []ProviderStatus) error {
 		if s.DiskImageFmt != "" {
 			currentFmt = fmt.Sprintf("%s (%s on disk)", s.CurrentFmt, s.DiskImageFmt)
 		}
+		if s.ReclaimableFmt != "" {
+			currentFmt = fmt.Sprintf("%s (%s reclaimable)", currentFmt, s.ReclaimableFmt)
+		}
 		maxFmt := s.MaxFmt
 		if s.Error != "" {
 			if currentFmt == "" {
@@ -169,7 +223,22 @@ func outputTable(statuses []ProviderStatus) error {
 				maxFmt = "-"
 			}
 		}
-		rows = append(rows, []string{s.Name, currentFmt, maxFmt, statusText})
+		name := s.Name
+		if s.Project != "" {
+			name += " " + dimStyle.Render(projectTag)
+		}
+		rows = append(rows, []string{name, currentFmt, maxFmt, statusText})
+		if s.InUse != "" {
+			rows = append(rows, []string{dimStyle.Render("  in use: " + s.InUse), "", "", ""})
+		}
+
+		for _, c := range s.Categories {
+			rows = append(rows, []string{
+				dimStyle.Render("  " + c.Name),
+				dimStyle.Render(fmt.Sprintf("%s (%s reclaimable)", c.SizeFmt, c.ReclaimableFmt)),
+				"", "",
+			})
+		}
 	}
 
 	width, _, _ := term.GetSize(os.Stdout.Fd())
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Automaat/cache-buster/internal/config"
 	"github.com/stretchr/testify/assert"
@@ -224,6 +225,93 @@ func TestOutputTable_DiskImageAnnotation(t *testing.T) {
 	assert.Contains(t, output, "1.0 KiB (5.0 KiB on disk)")
 }
 
+func TestOutputTable_ProjectTag(t *testing.T) {
+	statuses := []ProviderStatus{
+		{Name: "app/gradle", Project: "/src/app", CurrentFmt: "1.0 KiB", MaxFmt: "10 KiB"},
+	}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = outputTable(statuses)
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, output, "app/gradle")
+	assert.Contains(t, output, projectTag)
+}
+
+func TestScanProvider_Project(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Providers: map[string]config.Provider{
+			"app/build": {Enabled: true, Paths: []string{tmpDir}, MaxSize: "1G", Project: "/src/app"},
+		},
+	}
+
+	status := scanProvider(t.Context(), cfg, "app/build")
+	assert.Empty(t, status.Error)
+	assert.Equal(t, "/src/app", status.Project)
+}
+
+func TestScanProvider_Reclaimable(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "app")
+	require.NoError(t, os.MkdirAll(filepath.Join(app, "target"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(app, "Cargo.toml"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(app, "target", "bin"), make([]byte, 1024), 0o600))
+	old := time.Now().Add(-90 * 24 * time.Hour)
+	for _, path := range []string{filepath.Join(app, "Cargo.toml"), filepath.Join(app, "target", "bin")} {
+		require.NoError(t, os.Chtimes(path, old, old))
+	}
+
+	cfg := &config.Config{
+		Providers: map[string]config.Provider{
+			"code": {Enabled: true, Type: config.TypeWorkspace, Paths: []string{root}, MaxSize: "1G", MaxAge: "30d"},
+		},
+	}
+
+	status := scanProvider(t.Context(), cfg, "code")
+	require.Empty(t, status.Error)
+	assert.Equal(t, int64(1024), status.Current)
+	assert.Equal(t, int64(1024), status.Reclaimable)
+
+	output := captureStdout(t, func() {
+		require.NoError(t, outputTable([]ProviderStatus{status}))
+	})
+	assert.Contains(t, output, "reclaimable")
+}
+
+func TestScanProvider_DockerCategories(t *testing.T) {
+	bin := t.TempDir()
+	script := `#!/bin/sh
+echo '{"Type":"Images","Size":"2GB","Reclaimable":"1GB (50%)"}'
+echo '{"Type":"Build Cache","Size":"512MB","Reclaimable":"512MB"}'
+`
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0o755))
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+	// No Engine API here, so the fake CLI answers.
+	t.Setenv("DOCKER_HOST", "unix://"+filepath.Join(bin, "missing.sock"))
+
+	cfg := &config.Config{
+		Providers: map[string]config.Provider{
+			"docker": {Enabled: true, Paths: []string{t.TempDir()}, MaxSize: "50G"},
+		},
+	}
+
+	status := scanProvider(t.Context(), cfg, "docker")
+	require.Empty(t, status.Error)
+	require.Len(t, status.Categories, 2)
+	assert.Equal(t, "images", status.Categories[0].Name)
+	assert.Equal(t, int64(1024*1024*1024), status.Categories[0].Reclaimable)
+	assert.Equal(t, "build-cache", status.Categories[1].Name)
+
+	output := captureStdout(t, func() {
+		require.NoError(t, outputTable([]ProviderStatus{status}))
+	})
+	assert.Contains(t, output, "build-cache")
+	assert.Contains(t, output, "512 MiB reclaimable")
+}
+
 func TestOutputJSON_DiskImageFields(t *testing.T) {
 	statuses := []ProviderStatus{
 		{
@@ -392,3 +480,27 @@ func TestScanProvider_InvalidGlobPattern(t *testing.T) {
 	assert.Contains(t, status.Error, "load provider")
 	assert.Contains(t, status.Error, "expand paths")
 }
+
+func TestScanProvider_InUse(t *testing.T) {
+	dir := t.TempDir()
+	lock := filepath.Join(dir, ".package-cache")
+	require.NoError(t, os.WriteFile(lock, nil, 0o600))
+	f, err := os.Open(lock)
+	require.NoError(t, err)
+	defer f.Close()
+	flockFile(t, f)
+
+	cfg := &config.Config{
+		Providers: map[string]config.Provider{
+			"cargo": {Enabled: true, Paths: []string{dir}, MaxSize: "1G", LockFiles: []string{lock}},
+		},
+	}
+
+	status := scanProvider(t.Context(), cfg, "cargo")
+	assert.Equal(t, lock+" is locked", status.InUse)
+
+	output := captureStdout(t, func() {
+		require.NoError(t, outputTable([]ProviderStatus{status}))
+	})
+	assert.Contains(t, output, "in use")
+}
@@ -2,6 +2,9 @@ package cli
 
 import "charm.land/lipgloss/v2"
 
+// projectTag marks providers that come from a per-project .cache-buster.yaml.
+const projectTag = "[project]"
+
 var (
 	headerStyle       = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("93"))
 	okStyle           = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
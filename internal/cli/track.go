@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// TrackCmd records when cached files are opened, for caches on noatime mounts.
+var TrackCmd = &cobra.Command{
+	Use:   "track [providers...]",
+	Short: "Record when cached files are opened (Linux)",
+	Long: `Watch the paths of the given providers, or all enabled ones, with inotify
+and record when each cached file is last opened. Cleans treat a recorded open
+as a use when it is newer than the file time lru_key reads, so least recently
+used files are found even on noatime mounts.
+
+Runs until interrupted; start it at login, e.g. as a systemd user service.`,
+	RunE: runTrack,
+}
+
+func init() {
+	TrackCmd.Flags().Duration("flush", time.Minute, "How often recorded opens are saved")
+}
+
+func runTrack(cmd *cobra.Command, args []string) error {
+	flush, _ := cmd.Flags().GetDuration("flush")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return runTrackWithLoader(ctx, NewLoader(cmd), args, flush)
+}
+
+func runTrackWithLoader(ctx context.Context, loader *config.Loader, args []string, flush time.Duration) error {
+	if flush <= 0 {
+		return fmt.Errorf("flush must be positive, got %s", flush)
+	}
+
+	cfg, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	providerNames, err := resolveProviders(cfg, args, len(args) == 0)
+	if err != nil {
+		return err
+	}
+	providers, unavailable := loadAndFilterProviders(cfg, providerNames)
+	for _, name := range unavailable {
+		fmt.Fprintf(os.Stderr, "Skipping %s: unavailable\n", name)
+	}
+
+	var roots []string
+	for _, p := range providers {
+		roots = append(roots, p.Paths()...)
+	}
+	slices.Sort(roots)
+	roots = slices.Compact(roots)
+	if len(roots) == 0 {
+		return fmt.Errorf("no provider paths to track")
+	}
+
+	path, err := config.AccessLogPath()
+	if err != nil {
+		return err
+	}
+	accessLog, err := cache.LoadAccessLog(path)
+	if err != nil {
+		return err
+	}
+
+	tracker, err := cache.NewTracker(roots, accessLog)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Tracking %d directories of %d providers, recording to %s\n", tracker.Watches(), len(providers), path)
+
+	if err := tracker.Run(ctx, flush); err != nil {
+		return err
+	}
+	fmt.Printf("Recorded opens of %d files\n", accessLog.Len())
+	return nil
+}
+
+// loadAccessLog reads the file opens recorded by track, if any.
+func loadAccessLog() (*cache.AccessLog, error) {
+	path, err := config.AccessLogPath()
+	if err != nil {
+		return nil, err
+	}
+	return cache.LoadAccessLog(path)
+}
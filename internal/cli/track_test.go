@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrack_RecordsOpens(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("needs inotify")
+	}
+	t.Setenv(config.EnvXDGStateHome, t.TempDir())
+	cacheDir := t.TempDir()
+	file := filepath.Join(cacheDir, "a.whl")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	loader := createTempConfig(t, cacheDir)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	output := captureStdout(t, func() {
+		go func() { done <- runTrackWithLoader(ctx, loader, nil, 10*time.Millisecond) }()
+
+		logPath, err := config.AccessLogPath()
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			f, err := os.Open(file)
+			require.NoError(t, err)
+			_ = f.Close()
+			saved, err := cache.LoadAccessLog(logPath)
+			require.NoError(t, err)
+			_, ok := saved.LastOpened(file)
+			return ok
+		}, 5*time.Second, 20*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	assert.Contains(t, output, "Tracking 1 directories of 1 providers")
+	assert.Contains(t, output, "Recorded opens of 1 files")
+}
+
+func TestTrack_UnknownProvider(t *testing.T) {
+	err := runTrackWithLoader(t.Context(), createTempConfig(t, t.TempDir()), []string{"nope"}, time.Minute)
+	require.ErrorContains(t, err, "unknown providers: nope")
+}
@@ -2,23 +2,111 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
 )
 
 // Config holds cache-buster configuration.
 type Config struct {
 	Providers map[string]Provider `mapstructure:"providers" yaml:"providers"`
 	Version   string              `mapstructure:"version" yaml:"version"`
+	Include   []string            `mapstructure:"include" yaml:"include,omitempty"`
 }
 
+// Provider types selectable with the type field. An empty type picks the
+// implementation from the provider name and clean_cmd.
+const (
+	TypeWorkspace = "workspace"
+	TypeVersioned = "versioned"
+)
+
+// Version orderings selectable with the order field of versioned providers.
+const (
+	OrderSemver = "semver" // 1.2.10 > 1.2.9, pre-releases before releases; the default
+	OrderCalver = "calver" // numeric dot-separated segments, e.g. 2024.1 < 2024.10
+	OrderMtime  = "mtime"  // directory modification time, for opaque versions like commit hashes
+)
+
+// Docker data categories selectable with the categories field.
+const (
+	CategoryImages     = "images"
+	CategoryContainers = "containers"
+	CategoryVolumes    = "volumes"
+	CategoryBuildCache = "build-cache"
+)
+
+// What a clean does with a provider in use, selectable with the on_busy field.
+const (
+	BusySkip  = "skip"  // skip the provider and clean the rest; the default
+	BusyWait  = "wait"  // wait until the provider is no longer in use
+	BusyAbort = "abort" // stop the whole clean
+)
+
+// File times selectable with the lru_key field to tell when a cached file was
+// last used.
+const (
+	LRUMtime = "mtime" // the default; content-addressed caches touch files on use
+	LRUAtime = "atime"
+	LRUCtime = "ctime"
+	LRUMax   = "max" // the latest of the three
+)
+
+// Orders in which trims delete files to get under max_size, selectable with
+// the eviction field.
+const (
+	EvictLRU     = "lru"      // least recently used first; the default
+	EvictLargest = "largest"  // largest first, freeing the space with the fewest deletions
+	EvictAgeSize = "age-size" // highest idle time × size first: big files unused for long
+	EvictLFU     = "lfu"      // fewest opens recorded by track first, then least recently used
+)
+
+// Container engines selectable with the engine field of docker providers.
+const (
+	EngineDocker  = "docker" // the default
+	EnginePodman  = "podman"
+	EngineNerdctl = "nerdctl"
+)
+
 // Provider defines a cache provider's settings.
 type Provider struct {
+	Type     string   `mapstructure:"type" yaml:"type,omitempty"` // provider implementation; see Type* constants
 	MaxSize  string   `mapstructure:"max_size" yaml:"max_size"`
 	MaxAge   string   `mapstructure:"max_age" yaml:"max_age,omitempty"`
 	CleanCmd string   `mapstructure:"clean_cmd" yaml:"clean_cmd,omitempty"`
+	PathCmd  string   `mapstructure:"path_cmd" yaml:"path_cmd,omitempty"` // prints cache paths, one per line; overrides paths when it succeeds
+	Project  string   `mapstructure:"-" yaml:"project,omitempty"`         // project root for providers from a project config
+	Engine   string   `mapstructure:"engine" yaml:"engine,omitempty"`     // container CLI of docker providers; see Engine* constants
+	OnBusy   string   `mapstructure:"on_busy" yaml:"on_busy,omitempty"`   // clean policy while in use; see Busy* constants
+	LRUKey   string   `mapstructure:"lru_key" yaml:"lru_key,omitempty"`   // file time ordering trims; see LRU* constants
+	Eviction string   `mapstructure:"eviction" yaml:"eviction,omitempty"` // order trims delete in; see Evict* constants
+	Headroom string   `mapstructure:"headroom" yaml:"headroom,omitempty"` // share of max_size trims free below it, e.g. "10%"
 	Paths    []string `mapstructure:"paths" yaml:"paths"`
-	Enabled  bool     `mapstructure:"enabled" yaml:"enabled"`
+	Exclude  []string `mapstructure:"exclude" yaml:"exclude,omitempty"` // doublestar patterns skipped by scans and cleans
+	Protect  []string `mapstructure:"protect" yaml:"protect,omitempty"` // doublestar patterns counted but never deleted
+	// The owning tool is using the cache while a process has one of these
+	// names or holds a lock on a file matching one of these doublestar patterns.
+	Processes []string `mapstructure:"processes" yaml:"processes,omitempty"`
+	LockFiles []string `mapstructure:"lock_files" yaml:"lock_files,omitempty"`
+	// Categories limits docker cleans to these Category* kinds of data; empty means all but volumes.
+	Categories []string `mapstructure:"categories" yaml:"categories,omitempty"`
+	// Docker objects never removed: images and volumes by name (doublestar
+	// patterns), and any object by label ("key" or "key=value").
+	ProtectImages  []string `mapstructure:"protect_images" yaml:"protect_images,omitempty"`
+	ProtectVolumes []string `mapstructure:"protect_volumes" yaml:"protect_volumes,omitempty"`
+	ProtectLabels  []string `mapstructure:"protect_labels" yaml:"protect_labels,omitempty"`
+	// Pattern matches version directory names of versioned providers, with "version" and optional "product" groups.
+	Pattern string `mapstructure:"pattern" yaml:"pattern,omitempty"`
+	Order   string `mapstructure:"order" yaml:"order,omitempty"` // version ordering of versioned providers; see Order* constants
+	// Workspaces are roots searched for lock files (go.sum, ...) whose referenced versions are kept.
+	Workspaces []string `mapstructure:"workspaces" yaml:"workspaces,omitempty"`
+	Keep       int      `mapstructure:"keep" yaml:"keep,omitempty"` // newest versions kept per module/product by version-aware providers
+	Enabled    bool     `mapstructure:"enabled" yaml:"enabled"`
+	// SkipOpenFiles keeps files another process has open (Linux only).
+	SkipOpenFiles bool `mapstructure:"skip_open_files" yaml:"skip_open_files,omitempty"`
 }
 
 // Validate checks config for required fields.
@@ -27,12 +115,128 @@ func (c *Config) Validate() error {
 		if strings.Contains(name, ".") {
 			return fmt.Errorf("provider %q: must not contain '.' (reserved as Viper key delimiter)", name)
 		}
-		if len(p.Paths) == 0 {
-			return fmt.Errorf("provider %q: at least one path is required", name)
+		switch p.Type {
+		case "", TypeWorkspace:
+		case TypeVersioned:
+			if err := validateVersioned(p); err != nil {
+				return fmt.Errorf("provider %q: %w", name, err)
+			}
+		default:
+			return fmt.Errorf("provider %q: unknown type %q", name, p.Type)
+		}
+		if len(p.Paths) == 0 && p.PathCmd == "" {
+			return fmt.Errorf("provider %q: at least one path or a path_cmd is required", name)
 		}
 		if p.MaxSize == "" {
 			return fmt.Errorf("provider %q: max_size is required", name)
 		}
+		for _, path := range p.Paths {
+			if _, err := ExpandEnv(path); err != nil {
+				return fmt.Errorf("provider %q: paths: %w", name, err)
+			}
+		}
+		if _, err := ExpandEnv(p.CleanCmd); err != nil {
+			return fmt.Errorf("provider %q: clean_cmd: %w", name, err)
+		}
+		if _, err := ExpandEnv(p.PathCmd); err != nil {
+			return fmt.Errorf("provider %q: path_cmd: %w", name, err)
+		}
+		if p.Keep < 0 {
+			return fmt.Errorf("provider %q: keep must not be negative", name)
+		}
+		for _, path := range p.Workspaces {
+			if _, err := ExpandEnv(path); err != nil {
+				return fmt.Errorf("provider %q: workspaces: %w", name, err)
+			}
+		}
+		switch p.OnBusy {
+		case "", BusySkip, BusyWait, BusyAbort:
+		default:
+			return fmt.Errorf("provider %q: unknown on_busy %q", name, p.OnBusy)
+		}
+		switch p.LRUKey {
+		case "", LRUMtime, LRUAtime, LRUCtime, LRUMax:
+		default:
+			return fmt.Errorf("provider %q: unknown lru_key %q", name, p.LRUKey)
+		}
+		switch p.Eviction {
+		case "", EvictLRU, EvictLargest, EvictAgeSize, EvictLFU:
+		default:
+			return fmt.Errorf("provider %q: unknown eviction %q", name, p.Eviction)
+		}
+		if _, err := ParseHeadroom(p.Headroom); err != nil {
+			return fmt.Errorf("provider %q: headroom: %w", name, err)
+		}
+		if err := validatePatterns(p.LockFiles); err != nil {
+			return fmt.Errorf("provider %q: lock_files: %w", name, err)
+		}
+		switch p.Engine {
+		case "", EngineDocker, EnginePodman, EngineNerdctl:
+		default:
+			return fmt.Errorf("provider %q: unknown engine %q", name, p.Engine)
+		}
+		for _, category := range p.Categories {
+			switch category {
+			case CategoryImages, CategoryContainers, CategoryVolumes, CategoryBuildCache:
+			default:
+				return fmt.Errorf("provider %q: unknown category %q", name, category)
+			}
+		}
+		for _, pattern := range slices.Concat(p.ProtectImages, p.ProtectVolumes) {
+			if !doublestar.ValidatePattern(pattern) {
+				return fmt.Errorf("provider %q: invalid docker name pattern %q", name, pattern)
+			}
+		}
+		if slices.Contains(p.ProtectLabels, "") {
+			return fmt.Errorf("provider %q: protect_labels: empty label", name)
+		}
+		if err := validatePatterns(p.Exclude); err != nil {
+			return fmt.Errorf("provider %q: exclude: %w", name, err)
+		}
+		if err := validatePatterns(p.Protect); err != nil {
+			return fmt.Errorf("provider %q: protect: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func validateVersioned(p Provider) error {
+	if p.Pattern == "" {
+		return fmt.Errorf("pattern is required for type %s", TypeVersioned)
+	}
+	re, err := CompileVersionPattern(p.Pattern)
+	if err != nil {
+		return fmt.Errorf("pattern: %w", err)
+	}
+	if re.SubexpIndex("version") < 0 {
+		return fmt.Errorf("pattern %q has no (?P<version>...) group", p.Pattern)
+	}
+	switch p.Order {
+	case "", OrderSemver, OrderCalver, OrderMtime:
+	default:
+		return fmt.Errorf("unknown order %q", p.Order)
+	}
+	if p.Keep < 1 {
+		return fmt.Errorf("keep must be at least 1 for type %s", TypeVersioned)
+	}
+	return nil
+}
+
+// CompileVersionPattern compiles a versioned provider pattern anchored to
+// match whole directory names.
+func CompileVersionPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+func validatePatterns(patterns []string) error {
+	expanded, err := ExpandPatterns(patterns)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range expanded {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
 	}
 	return nil
 }
@@ -47,7 +251,7 @@ func (c *Config) GetProvider(name string) (Provider, bool) {
 func (c *Config) EnabledProviders() []string {
 	var enabled []string
 	for name, p := range c.Providers {
-		if p.Enabled && PathsExist(p.Paths) {
+		if p.Enabled && providerPathsExist(p) {
 			enabled = append(enabled, name)
 		}
 	}
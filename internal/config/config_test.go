@@ -31,7 +31,195 @@ func TestConfig_Validate(t *testing.T) {
 				},
 			},
 			name:    "provider without paths",
-			errMsg:  "at least one path is required",
+			errMsg:  "at least one path or a path_cmd is required",
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"test": {Enabled: true, PathCmd: "tool cache dir", MaxSize: "1G"},
+				},
+			},
+			name: "path_cmd without paths is valid",
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"code": {Enabled: true, Type: TypeWorkspace, Paths: []string{"~/code"}, MaxSize: "10G"},
+				},
+			},
+			name: "workspace type is valid",
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"code": {Enabled: true, Type: "bogus", Paths: []string{"~/code"}, MaxSize: "10G"},
+				},
+			},
+			name:    "unknown type",
+			errMsg:  `unknown type "bogus"`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"docker": {Enabled: true, Paths: []string{"~/docker"}, MaxSize: "50G", Categories: []string{CategoryBuildCache, CategoryImages}},
+				},
+			},
+			name: "docker categories are valid",
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"docker": {Enabled: true, Paths: []string{"~/docker"}, MaxSize: "50G", Categories: []string{"networks"}},
+				},
+			},
+			name:    "unknown docker category",
+			errMsg:  `unknown category "networks"`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"docker": {Enabled: true, Paths: []string{"~/docker"}, MaxSize: "50G", Engine: "lima"},
+				},
+			},
+			name:    "unknown container engine",
+			errMsg:  `unknown engine "lima"`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"cargo": {Enabled: true, Paths: []string{"~/.cargo"}, MaxSize: "5G", OnBusy: "retry"},
+				},
+			},
+			name:    "unknown on_busy",
+			errMsg:  `unknown on_busy "retry"`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"pip": {Enabled: true, Paths: []string{"~/.cache/pip"}, MaxSize: "5G", LRUKey: "btime"},
+				},
+			},
+			name:    "unknown lru_key",
+			errMsg:  `unknown lru_key "btime"`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"pip": {Enabled: true, Paths: []string{"~/.cache/pip"}, MaxSize: "5G", Eviction: "fifo"},
+				},
+			},
+			name:    "unknown eviction",
+			errMsg:  `unknown eviction "fifo"`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"pip": {Enabled: true, Paths: []string{"~/.cache/pip"}, MaxSize: "5G", Headroom: "150%"},
+				},
+			},
+			name:    "headroom of max_size or more",
+			errMsg:  "headroom must be below 100%",
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"docker": {Enabled: true, Paths: []string{"~/docker"}, MaxSize: "50G", ProtectImages: []string{"postgres", "ghcr.io/acme/**"}, ProtectVolumes: []string{"*-db"}, ProtectLabels: []string{"keep=true"}},
+				},
+			},
+			name: "docker protections are valid",
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"docker": {Enabled: true, Paths: []string{"~/docker"}, MaxSize: "50G", ProtectVolumes: []string{"db["}},
+				},
+			},
+			name:    "invalid docker volume pattern",
+			errMsg:  `invalid docker name pattern "db["`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"playwright": {Enabled: true, Type: TypeVersioned, Paths: []string{"~/.cache/ms-playwright"}, MaxSize: "5G", Pattern: `(?P<product>[a-z]+)-(?P<version>\d+)`, Order: OrderCalver, Keep: 1},
+				},
+			},
+			name: "versioned type is valid",
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"playwright": {Enabled: true, Type: TypeVersioned, Paths: []string{"~/.cache/ms-playwright"}, MaxSize: "5G", Keep: 1},
+				},
+			},
+			name:    "versioned without pattern",
+			errMsg:  `pattern is required`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"playwright": {Enabled: true, Type: TypeVersioned, Paths: []string{"~/.cache/ms-playwright"}, MaxSize: "5G", Pattern: `[a-z]+-\d+`, Keep: 1},
+				},
+			},
+			name:    "versioned pattern without version group",
+			errMsg:  `no (?P<version>...) group`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"playwright": {Enabled: true, Type: TypeVersioned, Paths: []string{"~/.cache/ms-playwright"}, MaxSize: "5G", Pattern: `(?P<version>\d+`, Keep: 1},
+				},
+			},
+			name:    "versioned invalid pattern",
+			errMsg:  `pattern: error parsing regexp`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"playwright": {Enabled: true, Type: TypeVersioned, Paths: []string{"~/.cache/ms-playwright"}, MaxSize: "5G", Pattern: `(?P<version>\d+)`, Order: "lexical", Keep: 1},
+				},
+			},
+			name:    "versioned unknown order",
+			errMsg:  `unknown order "lexical"`,
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"playwright": {Enabled: true, Type: TypeVersioned, Paths: []string{"~/.cache/ms-playwright"}, MaxSize: "5G", Pattern: `(?P<version>\d+)`},
+				},
+			},
+			name:    "versioned without keep",
+			errMsg:  `keep must be at least 1`,
 			wantErr: true,
 		},
 		{
@@ -56,6 +244,39 @@ func TestConfig_Validate(t *testing.T) {
 			errMsg:  "max_size is required",
 			wantErr: true,
 		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"test": {Enabled: true, Paths: []string{"${CB_TEST_UNSET_PATH}/cache"}, MaxSize: "1G"},
+				},
+			},
+			name:    "path with unset env var",
+			errMsg:  "CB_TEST_UNSET_PATH is not set",
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"test": {Enabled: true, Paths: []string{"~/test"}, MaxSize: "1G", CleanCmd: "tool --dir ${CB_TEST_UNSET_CMD}"},
+				},
+			},
+			name:    "clean_cmd with unset env var",
+			errMsg:  "clean_cmd",
+			wantErr: true,
+		},
+		{
+			cfg: &Config{
+				Version: "1",
+				Providers: map[string]Provider{
+					"test": {Enabled: true, Paths: []string{"~/test"}, MaxSize: "1G", Protect: []string{"jars-[0-9"}},
+				},
+			},
+			name:    "invalid protect pattern",
+			errMsg:  "protect: invalid pattern",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -156,7 +377,7 @@ func TestDefaultConfig(t *testing.T) {
 
 	expectedProviders := []string{
 		"go-build", "go-mod", "npm", "yarn", "homebrew",
-		"mise", "uv", "jetbrains", "docker",
+		"mise", "uv", "jetbrains", "docker", "podman",
 	}
 
 	for _, name := range expectedProviders {
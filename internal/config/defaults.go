@@ -23,17 +23,19 @@ func goProviders() map[string]Provider {
 	return map[string]Provider{
 		"go-build": {
 			Enabled:  true,
-			Paths:    []string{"~/Library/Caches/go-build"},
+			Paths:    []string{"${GOCACHE:-~/Library/Caches/go-build}"},
+			PathCmd:  "go env GOCACHE",
 			MaxSize:  "10G",
 			MaxAge:   "30d",
 			CleanCmd: "go clean -cache",
 		},
 		"go-mod": {
-			Enabled:  true,
-			Paths:    []string{"~/go/pkg/mod"},
-			MaxSize:  "5G",
-			MaxAge:   "30d",
-			CleanCmd: "go clean -modcache",
+			Enabled: true,
+			Paths:   []string{"${GOMODCACHE:-~/go/pkg/mod}"},
+			PathCmd: "go env GOMODCACHE",
+			MaxSize: "5G",
+			MaxAge:  "30d",
+			Keep:    1,
 		},
 	}
 }
@@ -41,15 +43,16 @@ func goProviders() map[string]Provider {
 func jsProviders() map[string]Provider {
 	return map[string]Provider{
 		"npm": {
-			Enabled:  true,
-			Paths:    []string{"~/.npm"},
-			MaxSize:  "3G",
-			MaxAge:   "30d",
-			CleanCmd: "npm cache clean --force",
+			Enabled: true,
+			Paths:   []string{"~/.npm"},
+			PathCmd: "npm config get cache",
+			MaxSize: "3G",
+			MaxAge:  "30d",
 		},
 		"yarn": {
 			Enabled:  true,
 			Paths:    []string{"~/Library/Caches/Yarn"},
+			PathCmd:  "yarn cache dir",
 			MaxSize:  "2G",
 			MaxAge:   "30d",
 			CleanCmd: "yarn cache clean",
@@ -57,6 +60,7 @@ func jsProviders() map[string]Provider {
 		"pnpm": {
 			Enabled:  true,
 			Paths:    []string{"~/.local/share/pnpm/store", "~/Library/pnpm/store"},
+			PathCmd:  "pnpm store path",
 			MaxSize:  "5G",
 			MaxAge:   "30d",
 			CleanCmd: "pnpm store prune",
@@ -69,6 +73,7 @@ func systemProviders() map[string]Provider {
 		"homebrew": {
 			Enabled:  true,
 			Paths:    []string{"~/Library/Caches/Homebrew"},
+			PathCmd:  "brew --cache",
 			MaxSize:  "5G",
 			MaxAge:   "30d",
 			CleanCmd: "brew cleanup",
@@ -81,11 +86,17 @@ func systemProviders() map[string]Provider {
 			CleanCmd: "mise prune",
 		},
 		"docker": {
-			Enabled:  true,
-			Paths:    []string{"~/Library/Containers/com.docker.docker"},
-			MaxSize:  "50G",
-			MaxAge:   "30d",
-			CleanCmd: "docker system prune -af --volumes",
+			Enabled: true,
+			Paths:   []string{"~/Library/Containers/com.docker.docker"},
+			MaxSize: "50G",
+			MaxAge:  "30d",
+		},
+		"podman": {
+			Enabled: true,
+			Paths:   []string{"~/.local/share/containers/storage", "~/.local/share/containers/podman/machine"},
+			Engine:  EnginePodman,
+			MaxSize: "50G",
+			MaxAge:  "30d",
 		},
 	}
 }
@@ -121,35 +132,61 @@ func otherProviders() map[string]Provider {
 	return map[string]Provider{
 		"uv": {
 			Enabled:  true,
-			Paths:    []string{"~/.cache/uv"},
+			Paths:    []string{"${UV_CACHE_DIR:-~/.cache/uv}"},
+			PathCmd:  "uv cache dir",
 			MaxSize:  "4G",
 			MaxAge:   "30d",
 			CleanCmd: "",
 		},
 		"jetbrains": {
-			Enabled:  true,
-			Paths:    []string{"~/Library/Caches/JetBrains"},
-			MaxSize:  "3G",
-			MaxAge:   "30d",
-			CleanCmd: "",
+			Enabled: true,
+			Paths: []string{
+				"~/Library/Caches/JetBrains",
+				"~/Library/Logs/JetBrains",
+				"~/.cache/JetBrains",
+				"~/.local/share/JetBrains",
+			},
+			MaxSize: "3G",
+			MaxAge:  "30d",
+			Keep:    1,
 		},
 		"cargo": {
-			Enabled:  true,
-			Paths:    []string{"~/.cargo/registry", "~/.cargo/git"},
-			MaxSize:  "5G",
-			MaxAge:   "30d",
-			CleanCmd: "",
+			Enabled:   true,
+			Paths:     []string{"${CARGO_HOME:-~/.cargo}/registry", "${CARGO_HOME:-~/.cargo}/git"},
+			MaxSize:   "5G",
+			MaxAge:    "30d",
+			CleanCmd:  "",
+			Processes: []string{"cargo"},
+			LockFiles: []string{"${CARGO_HOME:-~/.cargo}/.package-cache"},
+			Keep:      1,
 		},
 		"gradle": {
 			Enabled:  true,
-			Paths:    []string{"~/.gradle/caches"},
+			Paths:    []string{"${GRADLE_USER_HOME:-~/.gradle}/caches", "${GRADLE_USER_HOME:-~/.gradle}/wrapper/dists"},
 			MaxSize:  "10G",
 			MaxAge:   "30d",
 			CleanCmd: "",
+			// gradle and gradlew exec java, and daemons idle between builds,
+			// so only the cache locks a build holds show it is running.
+			LockFiles: []string{
+				"${GRADLE_USER_HOME:-~/.gradle}/caches/*/*.lock",
+				"${GRADLE_USER_HOME:-~/.gradle}/caches/*/*/*.lock",
+			},
+			Keep: 1,
+		},
+		"maven": {
+			Enabled: true,
+			Paths:   []string{"~/.m2/repository"},
+			MaxSize: "5G",
+			MaxAge:  "60d",
+			// Maven only reads what it resolved, so mtimes say when it was downloaded.
+			LRUKey: LRUAtime,
+			Keep:   1,
 		},
 		"pip": {
 			Enabled:  true,
 			Paths:    []string{"~/.cache/pip", "~/Library/Caches/pip"},
+			PathCmd:  "pip cache dir",
 			MaxSize:  "3G",
 			MaxAge:   "30d",
 			CleanCmd: "pip cache purge",
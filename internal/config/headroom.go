@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var headroomRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(%?)$`)
+
+// Headroom shares of max_size trims free below it. A zero headroom stands
+// for DefaultHeadroom (10%), so none at all is NoHeadroom.
+const (
+	DefaultHeadroom = 0.1
+	NoHeadroom      = -1.0
+)
+
+// ParseHeadroom parses headroom strings like "10%", or fractions like "0.1",
+// into a share of max_size below 1, or NoHeadroom for zero. If empty string,
+// returns DefaultHeadroom.
+func ParseHeadroom(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DefaultHeadroom, nil
+	}
+
+	matches := headroomRegex.FindStringSubmatch(s)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid headroom format: %q", s)
+	}
+
+	value, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse headroom value: %w", err)
+	}
+	if matches[2] == "%" {
+		value /= 100
+	}
+	if value >= 1 {
+		return 0, fmt.Errorf("headroom must be below 100%%: %q", s)
+	}
+	if value == 0 {
+		return NoHeadroom, nil
+	}
+
+	return value, nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeadroom(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		wantErr  bool
+	}{
+		{"10%", 0.1, false},
+		{"25 %", 0.25, false},
+		{"2.5%", 0.025, false},
+		{"0%", NoHeadroom, false},
+		{"0.2", 0.2, false},
+
+		// Empty string returns default
+		{"", DefaultHeadroom, false},
+
+		// Errors
+		{"100%", 0, true},
+		{"1", 0, true},
+		{"-5%", 0, true},
+		{"ten", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseHeadroom(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.expected, got, 1e-9)
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -11,8 +13,11 @@ import (
 // Loader handles config file operations.
 type Loader struct {
 	v            *viper.Viper
-	configPath   string // override for testing, empty uses Path()
-	skipDefaults bool   // skip merging with defaults (for test isolation)
+	origins      map[string]string // field key -> file (or OriginDefault) that set it
+	configPath   string            // --config or test override, empty uses Path()
+	projectDir   string            // start of project config discovery, empty uses the working directory
+	files        []string          // config files merged by the last Load
+	skipDefaults bool              // skip merging with defaults (for test isolation)
 }
 
 // NewLoader creates a new config loader.
@@ -20,24 +25,78 @@ func NewLoader() *Loader {
 	return &Loader{v: viper.New()}
 }
 
-// SetConfigPath overrides config path (for testing).
+// SetConfigPath overrides the config path (the --config flag, or tests).
 func (l *Loader) SetConfigPath(path string) {
 	l.configPath = path
 }
 
+// SetProjectDir sets where discovery of a per-project config starts.
+func (l *Loader) SetProjectDir(dir string) {
+	l.projectDir = dir
+}
+
 // SkipDefaults disables merging with defaults (for test isolation).
 func (l *Loader) SkipDefaults() {
 	l.skipDefaults = true
 }
 
-func (l *Loader) path() (string, error) {
+// Path returns the config file this loader reads and writes.
+func (l *Loader) Path() (string, error) {
 	if l.configPath != "" {
-		return l.configPath, nil
+		return ExpandTilde(l.configPath)
 	}
 	return Path()
 }
 
+// OriginDefault marks config fields that come from the builtin defaults.
+const OriginDefault = "default"
+
+// envPrefix namespaces environment variable overrides.
+const envPrefix = "CACHE_BUSTER"
+
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_", "/", "_")
+
+// providerFields lists the per-provider keys a config layer may override,
+// each with a setter that copies the field from the layer onto the merged provider.
+var providerFields = []struct {
+	apply func(dst *Provider, src *Provider)
+	key   string
+}{
+	{key: "type", apply: func(dst, src *Provider) { dst.Type = src.Type }},
+	{key: "max_size", apply: func(dst, src *Provider) { dst.MaxSize = src.MaxSize }},
+	{key: "max_age", apply: func(dst, src *Provider) { dst.MaxAge = src.MaxAge }},
+	{key: "clean_cmd", apply: func(dst, src *Provider) { dst.CleanCmd = src.CleanCmd }},
+	{key: "path_cmd", apply: func(dst, src *Provider) { dst.PathCmd = src.PathCmd }},
+	{key: "paths", apply: func(dst, src *Provider) { dst.Paths = src.Paths }},
+	{key: "exclude", apply: func(dst, src *Provider) { dst.Exclude = src.Exclude }},
+	{key: "protect", apply: func(dst, src *Provider) { dst.Protect = src.Protect }},
+	{key: "processes", apply: func(dst, src *Provider) { dst.Processes = src.Processes }},
+	{key: "lock_files", apply: func(dst, src *Provider) { dst.LockFiles = src.LockFiles }},
+	{key: "skip_open_files", apply: func(dst, src *Provider) { dst.SkipOpenFiles = src.SkipOpenFiles }},
+	{key: "lru_key", apply: func(dst, src *Provider) { dst.LRUKey = src.LRUKey }},
+	{key: "eviction", apply: func(dst, src *Provider) { dst.Eviction = src.Eviction }},
+	{key: "headroom", apply: func(dst, src *Provider) { dst.Headroom = src.Headroom }},
+	{key: "on_busy", apply: func(dst, src *Provider) { dst.OnBusy = src.OnBusy }},
+	{key: "engine", apply: func(dst, src *Provider) { dst.Engine = src.Engine }},
+	{key: "categories", apply: func(dst, src *Provider) { dst.Categories = src.Categories }},
+	{key: "protect_images", apply: func(dst, src *Provider) { dst.ProtectImages = src.ProtectImages }},
+	{key: "protect_volumes", apply: func(dst, src *Provider) { dst.ProtectVolumes = src.ProtectVolumes }},
+	{key: "protect_labels", apply: func(dst, src *Provider) { dst.ProtectLabels = src.ProtectLabels }},
+	{key: "workspaces", apply: func(dst, src *Provider) { dst.Workspaces = src.Workspaces }},
+	{key: "pattern", apply: func(dst, src *Provider) { dst.Pattern = src.Pattern }},
+	{key: "order", apply: func(dst, src *Provider) { dst.Order = src.Order }},
+	{key: "keep", apply: func(dst, src *Provider) { dst.Keep = src.Keep }},
+	{key: "enabled", apply: func(dst, src *Provider) { dst.Enabled = src.Enabled }},
+}
+
 // Load reads config from disk and merges with defaults.
+//
+// Layers are applied in order, each overriding only the fields it sets:
+// builtin defaults, config.d/*.yaml next to the config file (lexical order),
+// files named by the main config's include list, then the main config itself.
+// Providers from the nearest .cache-buster.yaml up from the working directory
+// are added last under project-prefixed names. The merged config is then
+// validated, so e.g. a path naming an unset ${VAR} fails the load.
 func (l *Loader) Load() (*Config, error) {
 	var cfg *Config
 	if l.skipDefaults {
@@ -46,7 +105,15 @@ func (l *Loader) Load() (*Config, error) {
 		cfg = DefaultConfig()
 	}
 
-	configPath, err := l.path()
+	l.origins = make(map[string]string)
+	l.files = nil
+	for name := range cfg.Providers {
+		for _, f := range providerFields {
+			l.origins[providerKey(name, f.key)] = OriginDefault
+		}
+	}
+
+	configPath, err := l.Path()
 	if err != nil {
 		return nil, err
 	}
@@ -54,46 +121,201 @@ func (l *Loader) Load() (*Config, error) {
 	l.v.SetConfigFile(configPath)
 	l.v.SetConfigType("yaml")
 
+	mainExists := true
 	if err := l.v.ReadInConfig(); err != nil {
-		if os.IsNotExist(err) {
-			return cfg, nil
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read config: %w", err)
 		}
-		return nil, fmt.Errorf("read config: %w", err)
+		mainExists = false
 	}
 
-	var userCfg Config
-	if err := l.v.Unmarshal(&userCfg); err != nil {
-		return nil, fmt.Errorf("unmarshal config: %w", err)
+	var mainCfg Config
+	if mainExists {
+		if err := l.v.Unmarshal(&mainCfg); err != nil {
+			return nil, fmt.Errorf("unmarshal config: %w", err)
+		}
 	}
 
-	// Merge user overrides on top of defaults, field by field.
-	for name, userP := range userCfg.Providers {
-		defaultP, hasDefault := cfg.Providers[name]
-		if !hasDefault {
-			// New provider not in defaults: use as-is; do not auto-enable when `enabled` is omitted.
-			cfg.Providers[name] = userP
-			continue
+	layers, err := dropInFiles(filepath.Dir(configPath))
+	if err != nil {
+		return nil, err
+	}
+	includes, err := resolveIncludes(filepath.Dir(configPath), mainCfg.Include)
+	if err != nil {
+		return nil, err
+	}
+	layers = append(layers, includes...)
+
+	for _, layerPath := range layers {
+		v := viper.New()
+		v.SetConfigFile(layerPath)
+		v.SetConfigType("yaml")
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("read config %s: %w", layerPath, err)
 		}
-		merged := defaultP
-		if l.v.IsSet("providers." + name + ".max_size") {
-			merged.MaxSize = userP.MaxSize
+		var layerCfg Config
+		if err := v.Unmarshal(&layerCfg); err != nil {
+			return nil, fmt.Errorf("unmarshal config %s: %w", layerPath, err)
 		}
-		if l.v.IsSet("providers." + name + ".max_age") {
-			merged.MaxAge = userP.MaxAge
+		l.files = append(l.files, layerPath)
+		l.mergeLayer(cfg, v, &layerCfg, layerPath)
+	}
+
+	if mainExists {
+		l.files = append(l.files, configPath)
+		l.mergeLayer(cfg, l.v, &mainCfg, configPath)
+		cfg.Include = mainCfg.Include
+	}
+
+	projectPath, err := l.findProject()
+	if err != nil {
+		return nil, err
+	}
+	if projectPath != "" {
+		if err := l.mergeProject(cfg, projectPath); err != nil {
+			return nil, err
 		}
-		if l.v.IsSet("providers." + name + ".clean_cmd") {
-			merged.CleanCmd = userP.CleanCmd
+	}
+
+	if err := l.mergeEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validate config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// mergeEnv applies CACHE_BUSTER_PROVIDERS_<NAME>_<FIELD> overrides for every
+// known provider. Dashes and slashes in names map to underscores, so go-build's
+// max_size is CACHE_BUSTER_PROVIDERS_GO_BUILD_MAX_SIZE. Lists are comma-separated.
+func (l *Loader) mergeEnv(cfg *Config) error {
+	v := viper.New()
+	envNames := make(map[string]string)
+	for name := range cfg.Providers {
+		for _, f := range providerFields {
+			key := providerKey(name, f.key)
+			envName := envPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+			if err := v.BindEnv(key, envName); err != nil {
+				return fmt.Errorf("bind env %s: %w", envName, err)
+			}
+			envNames[key] = envName
 		}
-		if l.v.IsSet("providers." + name + ".paths") {
-			merged.Paths = userP.Paths
+	}
+
+	var envCfg Config
+	if err := v.Unmarshal(&envCfg); err != nil {
+		return fmt.Errorf("unmarshal env overrides: %w", err)
+	}
+
+	for name, envP := range envCfg.Providers {
+		merged, ok := cfg.Providers[name]
+		if !ok {
+			continue
 		}
-		if l.v.IsSet("providers." + name + ".enabled") {
-			merged.Enabled = userP.Enabled
+		for _, f := range providerFields {
+			key := providerKey(name, f.key)
+			if v.IsSet(key) {
+				f.apply(&merged, &envP)
+				l.origins[key] = "env " + envNames[key]
+			}
 		}
 		cfg.Providers[name] = merged
 	}
 
-	return cfg, nil
+	return nil
+}
+
+func (l *Loader) findProject() (string, error) {
+	dir := l.projectDir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("get working dir: %w", err)
+		}
+		dir = wd
+	}
+	return FindProjectConfig(dir)
+}
+
+// mergeLayer applies the fields set in one config file on top of cfg,
+// recording the file as the origin of each field it overrides.
+func (l *Loader) mergeLayer(cfg *Config, v *viper.Viper, layer *Config, origin string) {
+	if v.IsSet("version") {
+		cfg.Version = layer.Version
+	}
+
+	for name, userP := range layer.Providers {
+		// A provider not seen in earlier layers starts from zero values, so
+		// `enabled` must be set explicitly for it to be auto-enabled.
+		merged := cfg.Providers[name]
+		for _, f := range providerFields {
+			key := providerKey(name, f.key)
+			if v.IsSet(key) {
+				f.apply(&merged, &userP)
+				l.origins[key] = origin
+			}
+		}
+		cfg.Providers[name] = merged
+	}
+}
+
+// Origin returns where the field at key (e.g. "providers.npm.max_size") was
+// set by the last Load: a file path, "env <VAR>", OriginDefault, or "" if never set.
+func (l *Loader) Origin(key string) string {
+	return l.origins[key]
+}
+
+// Files returns the config files merged by the last Load, in merge order.
+func (l *Loader) Files() []string {
+	return l.files
+}
+
+func providerKey(name, field string) string {
+	return "providers." + name + "." + field
+}
+
+// dropInFiles returns config.d/*.yaml under dir in lexical order.
+func dropInFiles(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, dropInDir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("glob %s: %w", dropInDir, err)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// resolveIncludes expands include entries relative to dir. Plain paths must
+// exist; glob patterns may match nothing. Glob matches are merged in lexical order.
+func resolveIncludes(dir string, includes []string) ([]string, error) {
+	var result []string
+	for _, inc := range includes {
+		expanded, err := ExpandTilde(inc)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(expanded) {
+			expanded = filepath.Join(dir, expanded)
+		}
+
+		if strings.ContainsAny(expanded, "*?[") {
+			matches, err := filepath.Glob(expanded)
+			if err != nil {
+				return nil, fmt.Errorf("include %q: %w", inc, err)
+			}
+			sort.Strings(matches)
+			result = append(result, matches...)
+			continue
+		}
+
+		if _, err := os.Stat(expanded); err != nil {
+			return nil, fmt.Errorf("include %q: %w", inc, err)
+		}
+		result = append(result, expanded)
+	}
+	return result, nil
 }
 
 // LoadOrCreate loads config (always merges with defaults). Returns (config, created, error).
@@ -110,7 +332,7 @@ func (l *Loader) Save(cfg *Config) error {
 		return fmt.Errorf("validate config: %w", err)
 	}
 
-	configPath, err := l.path()
+	configPath, err := l.Path()
 	if err != nil {
 		return err
 	}
@@ -150,7 +372,7 @@ func (l *Loader) InitDefault() (bool, error) {
 
 // Exists checks if config file exists.
 func (l *Loader) Exists() (bool, error) {
-	configPath, err := l.path()
+	configPath, err := l.Path()
 	if err != nil {
 		return false, err
 	}
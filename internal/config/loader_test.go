@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -278,6 +279,30 @@ func TestLoader_Save_ValidatesConfig(t *testing.T) {
 	}
 }
 
+func TestLoader_Load_ValidatesConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	content := `providers:
+  tool:
+    enabled: true
+    paths:
+      - ${CACHE_BUSTER_TEST_UNSET_DIR}/cache
+    max_size: 1G
+    clean_cmd: tool clean
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader()
+	loader.SetConfigPath(configPath)
+
+	_, err := loader.Load()
+	if err == nil || !strings.Contains(err.Error(), "CACHE_BUSTER_TEST_UNSET_DIR is not set") {
+		t.Fatalf("Load() error = %v, want unset variable error", err)
+	}
+}
+
 func TestNewLoader(t *testing.T) {
 	loader := NewLoader()
 	if loader == nil {
@@ -287,3 +312,231 @@ func TestNewLoader(t *testing.T) {
 		t.Fatal("NewLoader() viper instance is nil")
 	}
 }
+
+func TestLoader_DropInDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	dropIn := filepath.Join(tmpDir, "config.d")
+	if err := os.Mkdir(dropIn, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"10-org.yaml": `providers:
+  internal-tool:
+    enabled: true
+    paths: [/opt/tool/cache]
+    max_size: 2G
+    clean_cmd: tool clean
+  go-build:
+    max_size: 15G
+`,
+		"20-team.yaml": `providers:
+  go-build:
+    max_size: 12G
+    max_age: 7d
+`,
+		"README.md": "not yaml config",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dropIn, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(configPath, []byte("providers:\n  go-build:\n    max_age: 14d\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader()
+	loader.SetConfigPath(configPath)
+
+	cfg, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tool, ok := cfg.Providers["internal-tool"]
+	if !ok {
+		t.Fatal("drop-in provider internal-tool missing")
+	}
+	if !tool.Enabled || tool.CleanCmd != "tool clean" {
+		t.Errorf("internal-tool = %+v, want enabled with clean_cmd", tool)
+	}
+
+	goBuild := cfg.Providers["go-build"]
+	if goBuild.MaxSize != "12G" {
+		t.Errorf("go-build MaxSize = %q, want 12G (later drop-in wins)", goBuild.MaxSize)
+	}
+	if goBuild.MaxAge != "14d" {
+		t.Errorf("go-build MaxAge = %q, want 14d (main config wins)", goBuild.MaxAge)
+	}
+	if !goBuild.Enabled {
+		t.Error("go-build should keep default enabled")
+	}
+
+	if got := loader.Origin("providers.go-build.max_size"); got != filepath.Join(dropIn, "20-team.yaml") {
+		t.Errorf("max_size origin = %q", got)
+	}
+	if got := loader.Origin("providers.go-build.max_age"); got != configPath {
+		t.Errorf("max_age origin = %q, want %q", got, configPath)
+	}
+	if got := loader.Origin("providers.go-build.paths"); got != OriginDefault {
+		t.Errorf("paths origin = %q, want %q", got, OriginDefault)
+	}
+
+	wantFiles := []string{
+		filepath.Join(dropIn, "10-org.yaml"),
+		filepath.Join(dropIn, "20-team.yaml"),
+		configPath,
+	}
+	got := loader.Files()
+	if len(got) != len(wantFiles) {
+		t.Fatalf("Files() = %v, want %v", got, wantFiles)
+	}
+	for i := range wantFiles {
+		if got[i] != wantFiles[i] {
+			t.Errorf("Files()[%d] = %q, want %q", i, got[i], wantFiles[i])
+		}
+	}
+}
+
+func TestLoader_DropInWithoutMainConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	dropIn := filepath.Join(tmpDir, "config.d")
+	if err := os.Mkdir(dropIn, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	content := "providers:\n  npm:\n    enabled: false\n"
+	if err := os.WriteFile(filepath.Join(dropIn, "org.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader()
+	loader.SetConfigPath(filepath.Join(tmpDir, "config.yaml"))
+
+	cfg, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Providers["npm"].Enabled {
+		t.Error("drop-in should disable npm even without config.yaml")
+	}
+}
+
+func TestLoader_Include(t *testing.T) {
+	t.Run("merges included files before main config", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		sharedDir := filepath.Join(tmpDir, "shared")
+		if err := os.Mkdir(sharedDir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+
+		for name, maxSize := range map[string]string{"a.yaml": "1G", "b.yaml": "2G"} {
+			content := "providers:\n  pip:\n    max_size: " + maxSize + "\n"
+			if err := os.WriteFile(filepath.Join(sharedDir, name), []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		content := "include:\n  - shared/*.yaml\nproviders:\n  pip:\n    max_age: 3d\n"
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		loader := NewLoader()
+		loader.SetConfigPath(configPath)
+
+		cfg, err := loader.Load()
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		pip := cfg.Providers["pip"]
+		if pip.MaxSize != "2G" {
+			t.Errorf("pip MaxSize = %q, want 2G (lexically last include wins)", pip.MaxSize)
+		}
+		if pip.MaxAge != "3d" {
+			t.Errorf("pip MaxAge = %q, want 3d", pip.MaxAge)
+		}
+		if got := loader.Origin("providers.pip.max_size"); got != filepath.Join(sharedDir, "b.yaml") {
+			t.Errorf("max_size origin = %q", got)
+		}
+	})
+
+	t.Run("missing include is an error", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte("include:\n  - missing.yaml\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		loader := NewLoader()
+		loader.SetConfigPath(configPath)
+
+		_, err := loader.Load()
+		if err == nil {
+			t.Fatal("Load() expected error for missing include")
+		}
+		if !containsString(err.Error(), "missing.yaml") {
+			t.Errorf("error = %v, want to mention missing.yaml", err)
+		}
+	})
+}
+
+func TestLoader_EnvOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("providers:\n  go-build:\n    max_size: 20G\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CACHE_BUSTER_PROVIDERS_GO_BUILD_MAX_SIZE", "30G")
+	t.Setenv("CACHE_BUSTER_PROVIDERS_NPM_ENABLED", "false")
+	t.Setenv("CACHE_BUSTER_PROVIDERS_PIP_PATHS", "/a,/b")
+
+	loader := NewLoader()
+	loader.SetConfigPath(configPath)
+
+	cfg, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.Providers["go-build"].MaxSize; got != "30G" {
+		t.Errorf("go-build MaxSize = %q, want 30G (env beats file)", got)
+	}
+	if cfg.Providers["npm"].Enabled {
+		t.Error("npm should be disabled by env override")
+	}
+	if got := cfg.Providers["pip"].Paths; len(got) != 2 || got[0] != "/a" || got[1] != "/b" {
+		t.Errorf("pip Paths = %v, want [/a /b]", got)
+	}
+	if got := cfg.Providers["npm"].MaxSize; got != DefaultProviders()["npm"].MaxSize {
+		t.Errorf("npm MaxSize = %q, unset env var should not override", got)
+	}
+
+	if got := loader.Origin("providers.go-build.max_size"); got != "env CACHE_BUSTER_PROVIDERS_GO_BUILD_MAX_SIZE" {
+		t.Errorf("origin = %q", got)
+	}
+}
+
+func TestLoader_Path(t *testing.T) {
+	t.Setenv(EnvConfig, "/from/env.yaml")
+
+	loader := NewLoader()
+	path, err := loader.Path()
+	if err != nil {
+		t.Fatalf("Path() error = %v", err)
+	}
+	if path != "/from/env.yaml" {
+		t.Errorf("Path() = %q, want env path", path)
+	}
+
+	loader.SetConfigPath("/from/flag.yaml")
+	path, err = loader.Path()
+	if err != nil {
+		t.Fatalf("Path() error = %v", err)
+	}
+	if path != "/from/flag.yaml" {
+		t.Errorf("Path() = %q, --config should win over env", path)
+	}
+}
@@ -4,12 +4,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+const (
+	configDir     = ".config/cache-buster"
+	stateDir      = ".local/state/cache-buster"
+	appDir        = "cache-buster"
+	configFile    = "config.yaml"
+	dropInDir     = "config.d"
+	accessLogFile = "access.db"
 )
 
+// Environment variables controlling where config is read from.
 const (
-	configDir  = ".config/cache-buster"
-	configFile = "config.yaml"
+	EnvConfig        = "CACHE_BUSTER_CONFIG" // explicit config file path
+	EnvXDGConfigHome = "XDG_CONFIG_HOME"     // base dir for the default config location
+	EnvXDGStateHome  = "XDG_STATE_HOME"      // base dir for the access log
 )
 
 // ExpandTilde replaces ~ prefix with home directory.
@@ -34,18 +48,66 @@ func ExpandTilde(path string) (string, error) {
 	return path, nil
 }
 
-// ExpandPaths expands ~ and globs in path patterns.
+// envVarPattern matches ${VAR} and ${VAR:-default}.
+var envVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv replaces ${VAR} and ${VAR:-default} with environment values.
+// As in the shell, the default applies when VAR is unset or empty.
+// A reference to an unset variable without a default is an error.
+func ExpandEnv(s string) (string, error) {
+	var missing []string
+	expanded := envVarPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envVarPattern.FindStringSubmatch(ref)
+		if val := os.Getenv(m[1]); val != "" {
+			return val
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		missing = append(missing, m[1])
+		return ref
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%q: environment variable %s is not set (use ${%s:-default} to provide a fallback)",
+			s, missing[0], missing[0])
+	}
+	return expanded, nil
+}
+
+// ExpandPatterns expands ${VAR} and ~ in match patterns, leaving glob syntax intact.
+func ExpandPatterns(patterns []string) ([]string, error) {
+	result := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		withEnv, err := ExpandEnv(pattern)
+		if err != nil {
+			return nil, err
+		}
+		expanded, err := ExpandTilde(withEnv)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded)
+	}
+	return result, nil
+}
+
+// ExpandPaths expands ${VAR}, ~ and globs (including ** for any depth) in path patterns.
 func ExpandPaths(patterns []string) ([]string, error) {
 	var result []string
 
 	for _, pattern := range patterns {
-		expanded, err := ExpandTilde(pattern)
+		withEnv, err := ExpandEnv(pattern)
+		if err != nil {
+			return nil, err
+		}
+		expanded, err := ExpandTilde(withEnv)
 		if err != nil {
 			return nil, err
 		}
 
 		if strings.ContainsAny(expanded, "*?[") {
-			matches, err := filepath.Glob(expanded)
+			matches, err := doublestar.FilepathGlob(expanded)
 			if err != nil {
 				return nil, fmt.Errorf("glob %q: %w", pattern, err)
 			}
@@ -58,8 +120,20 @@ func ExpandPaths(patterns []string) ([]string, error) {
 	return result, nil
 }
 
-// DirPath returns ~/.config/cache-buster.
+// DirPath returns the config directory: the parent of $CACHE_BUSTER_CONFIG when
+// set, else $XDG_CONFIG_HOME/cache-buster, else ~/.config/cache-buster.
 func DirPath() (string, error) {
+	if p := os.Getenv(EnvConfig); p != "" {
+		expanded, err := ExpandTilde(p)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Dir(expanded), nil
+	}
+	if xdg := os.Getenv(EnvXDGConfigHome); xdg != "" {
+		return filepath.Join(xdg, appDir), nil
+	}
+
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("get home dir: %w", err)
@@ -67,8 +141,13 @@ func DirPath() (string, error) {
 	return filepath.Join(home, configDir), nil
 }
 
-// Path returns ~/.config/cache-buster/config.yaml.
+// Path returns the config file path: $CACHE_BUSTER_CONFIG when set,
+// else config.yaml in DirPath.
 func Path() (string, error) {
+	if p := os.Getenv(EnvConfig); p != "" {
+		return ExpandTilde(p)
+	}
+
 	dir, err := DirPath()
 	if err != nil {
 		return "", err
@@ -76,6 +155,20 @@ func Path() (string, error) {
 	return filepath.Join(dir, configFile), nil
 }
 
+// AccessLogPath returns where the track command records file opens:
+// $XDG_STATE_HOME/cache-buster/access.db, else ~/.local/state/cache-buster/access.db.
+func AccessLogPath() (string, error) {
+	if xdg := os.Getenv(EnvXDGStateHome); xdg != "" {
+		return filepath.Join(xdg, appDir, accessLogFile), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+	return filepath.Join(home, stateDir, accessLogFile), nil
+}
+
 // EnsureDir creates config directory if missing.
 func EnsureDir() error {
 	dir, err := DirPath()
@@ -99,3 +192,18 @@ func PathsExist(patterns []string) bool {
 	}
 	return false
 }
+
+// providerPathsExist checks if any of p's resolved paths (path_cmd output or paths) exist on disk.
+func providerPathsExist(p Provider) bool {
+	paths, err := ResolvePaths(p)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: path expansion failed: %v\n", err)
+		return false
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
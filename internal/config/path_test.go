@@ -40,6 +40,105 @@ func TestExpandTilde(t *testing.T) {
 	}
 }
 
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("CB_TEST_SET", "/custom")
+	t.Setenv("CB_TEST_EMPTY", "")
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"no references", "~/.cache", "~/.cache", false},
+		{"set variable", "${CB_TEST_SET}/mod", "/custom/mod", false},
+		{"default unused", "${CB_TEST_SET:-~/go}/mod", "/custom/mod", false},
+		{"default for unset", "${CB_TEST_UNSET:-~/.cache}/uv", "~/.cache/uv", false},
+		{"default for empty", "${CB_TEST_EMPTY:-/fallback}", "/fallback", false},
+		{"empty default", "${CB_TEST_UNSET:-}/x", "/x", false},
+		{"bare dollar untouched", "$CB_TEST_SET/x", "$CB_TEST_SET/x", false},
+		{"unset without default", "${CB_TEST_UNSET}/x", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandEnv(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !containsString(err.Error(), "CB_TEST_UNSET is not set") {
+					t.Errorf("ExpandEnv() error = %v, want to name the variable", err)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ExpandEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandPaths_Env(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("get home dir: %v", err)
+	}
+	t.Setenv("CB_TEST_HOME", "/opt/cargo")
+
+	got, err := ExpandPaths([]string{"${CB_TEST_HOME}/registry", "${CB_TEST_UNSET:-~/.cargo}/git"})
+	if err != nil {
+		t.Fatalf("ExpandPaths() error = %v", err)
+	}
+	want := []string{"/opt/cargo/registry", filepath.Join(home, ".cargo/git")}
+	if !slices.Equal(got, want) {
+		t.Errorf("ExpandPaths() = %v, want %v", got, want)
+	}
+
+	if _, err := ExpandPaths([]string{"${CB_TEST_UNSET}/x"}); err == nil {
+		t.Error("ExpandPaths() expected error for unset variable")
+	}
+}
+
+func TestExpandPaths_DoubleStar(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a/node_modules/.cache", "b/c/node_modules/.cache", "d/other"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0o750); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ExpandPaths([]string{filepath.Join(tmpDir, "**/node_modules/.cache")})
+	if err != nil {
+		t.Fatalf("ExpandPaths() error = %v", err)
+	}
+	slices.Sort(got)
+	want := []string{
+		filepath.Join(tmpDir, "a/node_modules/.cache"),
+		filepath.Join(tmpDir, "b/c/node_modules/.cache"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ExpandPaths() = %v, want %v", got, want)
+	}
+}
+
+func TestExpandPatterns(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("get home dir: %v", err)
+	}
+	t.Setenv("CB_TEST_GRADLE", "/opt/gradle")
+
+	got, err := ExpandPatterns([]string{"~/.gradle/caches/jars-*", "${CB_TEST_GRADLE}/**/*.lock", "**/keep"})
+	if err != nil {
+		t.Fatalf("ExpandPatterns() error = %v", err)
+	}
+	want := []string{filepath.Join(home, ".gradle/caches/jars-*"), "/opt/gradle/**/*.lock", "**/keep"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ExpandPatterns() = %v, want %v", got, want)
+	}
+}
+
 func TestExpandPaths(t *testing.T) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -85,6 +184,8 @@ func TestExpandPaths(t *testing.T) {
 }
 
 func TestPath(t *testing.T) {
+	t.Setenv(EnvConfig, "")
+	t.Setenv(EnvXDGConfigHome, "")
 	home, err := os.UserHomeDir()
 	if err != nil {
 		t.Fatalf("get home dir: %v", err)
@@ -102,6 +203,8 @@ func TestPath(t *testing.T) {
 }
 
 func TestDirPath(t *testing.T) {
+	t.Setenv(EnvConfig, "")
+	t.Setenv(EnvXDGConfigHome, "")
 	home, err := os.UserHomeDir()
 	if err != nil {
 		t.Fatalf("get home dir: %v", err)
@@ -117,3 +220,64 @@ func TestDirPath(t *testing.T) {
 		t.Errorf("DirPath() = %v, want %v", path, want)
 	}
 }
+
+func TestPath_EnvOverrides(t *testing.T) {
+	t.Run("CACHE_BUSTER_CONFIG wins", func(t *testing.T) {
+		t.Setenv(EnvXDGConfigHome, "/xdg")
+		t.Setenv(EnvConfig, "/ci/cache-buster.yaml")
+
+		path, err := Path()
+		if err != nil {
+			t.Fatalf("Path() error = %v", err)
+		}
+		if path != "/ci/cache-buster.yaml" {
+			t.Errorf("Path() = %v, want /ci/cache-buster.yaml", path)
+		}
+
+		dir, err := DirPath()
+		if err != nil {
+			t.Fatalf("DirPath() error = %v", err)
+		}
+		if dir != "/ci" {
+			t.Errorf("DirPath() = %v, want /ci", dir)
+		}
+	})
+
+	t.Run("XDG_CONFIG_HOME", func(t *testing.T) {
+		t.Setenv(EnvConfig, "")
+		t.Setenv(EnvXDGConfigHome, "/xdg")
+
+		path, err := Path()
+		if err != nil {
+			t.Fatalf("Path() error = %v", err)
+		}
+		if path != "/xdg/cache-buster/config.yaml" {
+			t.Errorf("Path() = %v, want /xdg/cache-buster/config.yaml", path)
+		}
+	})
+}
+
+func TestAccessLogPath(t *testing.T) {
+	t.Setenv(EnvXDGStateHome, "")
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("get home dir: %v", err)
+	}
+
+	path, err := AccessLogPath()
+	if err != nil {
+		t.Fatalf("AccessLogPath() error = %v", err)
+	}
+	if want := filepath.Join(home, ".local/state/cache-buster/access.db"); path != want {
+		t.Errorf("AccessLogPath() = %v, want %v", path, want)
+	}
+
+	t.Setenv(EnvXDGStateHome, "/xdg-state")
+	path, err = AccessLogPath()
+	if err != nil {
+		t.Fatalf("AccessLogPath() error = %v", err)
+	}
+	if path != "/xdg-state/cache-buster/access.db" {
+		t.Errorf("AccessLogPath() = %v, want /xdg-state/cache-buster/access.db", path)
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// pathCmdTimeout bounds how long a path_cmd may run before falling back to paths.
+var pathCmdTimeout = 5 * time.Second
+
+// pathCmdCache memoizes path_cmd output for the lifetime of the process, so
+// status, clean and the TUI agree on paths and each tool is queried once per run.
+// mu guards only the map; each command runs under its own entry's once, so
+// providers do not queue behind each other's commands.
+var pathCmdCache = struct {
+	results map[string]*pathCmdResult
+	mu      sync.Mutex
+}{results: make(map[string]*pathCmdResult)}
+
+// pathCmdResult is the memoized output of one path_cmd.
+type pathCmdResult struct {
+	paths []string
+	once  sync.Once
+}
+
+// SplitCommand splits cmd into arguments and expands ${VAR} references in
+// each one, so values containing spaces stay single arguments.
+func SplitCommand(cmd string) ([]string, error) {
+	args, err := shellquote.Split(cmd)
+	if err != nil {
+		return nil, err
+	}
+	for i, arg := range args {
+		expanded, err := ExpandEnv(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = expanded
+	}
+	return args, nil
+}
+
+// ResolvePaths returns the expanded paths for p. When path_cmd is set and
+// prints at least one path, its output lines are used; otherwise (command
+// missing, failing, timing out or printing nothing) the configured paths are.
+func ResolvePaths(p Provider) ([]string, error) {
+	if p.PathCmd != "" {
+		if paths := runPathCmd(p.PathCmd); len(paths) > 0 {
+			return paths, nil
+		}
+	}
+	return ExpandPaths(p.Paths)
+}
+
+// runPathCmd runs cmd and returns its non-empty output lines as paths.
+// Failures yield nil so callers fall back to configured paths.
+func runPathCmd(cmd string) []string {
+	pathCmdCache.mu.Lock()
+	result, ok := pathCmdCache.results[cmd]
+	if !ok {
+		result = &pathCmdResult{}
+		pathCmdCache.results[cmd] = result
+	}
+	pathCmdCache.mu.Unlock()
+
+	result.once.Do(func() { result.paths = execPathCmd(cmd) })
+	return result.paths
+}
+
+// execPathCmd runs cmd from the home directory: tools like yarn and pnpm
+// report the cache of the project they run in, which must not replace the
+// user-wide cache paths.
+func execPathCmd(cmd string) []string {
+	args, err := SplitCommand(cmd)
+	if err != nil || len(args) == 0 {
+		return nil
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pathCmdTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	c := exec.CommandContext(ctx, args[0], args[1:]...)
+	c.Stdout = &stdout
+	if home, err := os.UserHomeDir(); err == nil {
+		c.Dir = home
+	}
+	if err := c.Run(); err != nil {
+		return nil
+	}
+
+	var paths []string
+	for line := range strings.SplitSeq(stdout.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		expanded, err := ExpandTilde(line)
+		if err != nil {
+			continue
+		}
+		paths = append(paths, filepath.Clean(expanded))
+	}
+	return paths
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func resetPathCmdCache(t *testing.T) {
+	t.Helper()
+	pathCmdCache.mu.Lock()
+	pathCmdCache.results = make(map[string]*pathCmdResult)
+	pathCmdCache.mu.Unlock()
+}
+
+func TestResolvePaths_PathCmd(t *testing.T) {
+	resetPathCmdCache(t)
+	dir := t.TempDir()
+	script := writeScript(t, "echo "+dir+"\necho\necho '  "+dir+"/extra/ '\n")
+
+	got, err := ResolvePaths(Provider{Paths: []string{"/fallback"}, PathCmd: "sh " + script})
+	if err != nil {
+		t.Fatalf("ResolvePaths() error = %v", err)
+	}
+	want := []string{dir, filepath.Join(dir, "extra")}
+	if !slices.Equal(got, want) {
+		t.Errorf("ResolvePaths() = %v, want %v", got, want)
+	}
+}
+
+func TestResolvePaths_FallsBackToPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		pathCmd string
+	}{
+		{"missing binary", "cache-buster-no-such-tool cache dir"},
+		{"command fails", "sh -c 'exit 1'"},
+		{"empty output", "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetPathCmdCache(t)
+			got, err := ResolvePaths(Provider{Paths: []string{"/fallback"}, PathCmd: tt.pathCmd})
+			if err != nil {
+				t.Fatalf("ResolvePaths() error = %v", err)
+			}
+			if !slices.Equal(got, []string{"/fallback"}) {
+				t.Errorf("ResolvePaths() = %v, want [/fallback]", got)
+			}
+		})
+	}
+}
+
+func TestResolvePaths_Timeout(t *testing.T) {
+	resetPathCmdCache(t)
+	old := pathCmdTimeout
+	pathCmdTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { pathCmdTimeout = old })
+
+	start := time.Now()
+	got, err := ResolvePaths(Provider{Paths: []string{"/fallback"}, PathCmd: "sleep 5"})
+	if err != nil {
+		t.Fatalf("ResolvePaths() error = %v", err)
+	}
+	if !slices.Equal(got, []string{"/fallback"}) {
+		t.Errorf("ResolvePaths() = %v, want [/fallback]", got)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("ResolvePaths() took %v, timeout not applied", elapsed)
+	}
+}
+
+func TestResolvePaths_CachedPerRun(t *testing.T) {
+	resetPathCmdCache(t)
+	counter := filepath.Join(t.TempDir(), "count")
+	cmd := "sh " + writeScript(t, "echo x >> "+counter+"\necho /cached\n")
+
+	for range 3 {
+		got, err := ResolvePaths(Provider{PathCmd: cmd})
+		if err != nil {
+			t.Fatalf("ResolvePaths() error = %v", err)
+		}
+		if !slices.Equal(got, []string{"/cached"}) {
+			t.Fatalf("ResolvePaths() = %v, want [/cached]", got)
+		}
+	}
+
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "x\n" {
+		t.Errorf("path_cmd ran %d times, want 1", len(data)/2)
+	}
+}
+
+func TestResolvePaths_RunsFromHome(t *testing.T) {
+	resetPathCmdCache(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(t.TempDir())
+
+	got, err := ResolvePaths(Provider{PathCmd: "pwd"})
+	if err != nil {
+		t.Fatalf("ResolvePaths() error = %v", err)
+	}
+	want, err := filepath.EvalSymlinks(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("ResolvePaths() = %v, want [%s]", got, want)
+	}
+	if dir, _ := filepath.EvalSymlinks(got[0]); dir != want {
+		t.Errorf("path_cmd ran in %s, want %s", got[0], want)
+	}
+}
+
+func TestResolvePaths_Concurrent(t *testing.T) {
+	resetPathCmdCache(t)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for _, dir := range []string{"/a", "/b", "/c"} {
+		wg.Go(func() {
+			got, err := ResolvePaths(Provider{PathCmd: "sh -c 'sleep 0.5; echo " + dir + "'"})
+			if err != nil || !slices.Equal(got, []string{dir}) {
+				t.Errorf("ResolvePaths() = %v, %v, want [%s]", got, err, dir)
+			}
+		})
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed > 1200*time.Millisecond {
+		t.Errorf("path_cmds took %v, want them to run in parallel", elapsed)
+	}
+}
+
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "paths.sh")
+	if err := os.WriteFile(script, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
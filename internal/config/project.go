@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/spf13/viper"
+)
+
+// ProjectFile is the per-project config file discovered by walking up from the working directory.
+const ProjectFile = ".cache-buster.yaml"
+
+// FindProjectConfig walks up from dir looking for ProjectFile.
+// Returns "" if no project config exists in dir or any parent.
+func FindProjectConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve project dir: %w", err)
+	}
+
+	for {
+		candidate := filepath.Join(dir, ProjectFile)
+		info, err := os.Stat(candidate)
+		if err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("stat %s: %w", candidate, err)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// ProjectProviderName returns the session-wide name of a provider declared in
+// a project config, prefixed with the project so it cannot shadow global providers.
+func ProjectProviderName(root, name string) string {
+	project := strings.ReplaceAll(filepath.Base(root), ".", "-")
+	return project + "/" + name
+}
+
+// mergeProject adds the providers declared in a project config to cfg.
+// Relative paths are resolved against the directory containing the file.
+//
+// A project config comes with whatever repository was cloned, so it is not
+// trusted like the user's own config: it may not run commands or reach the
+// container engine, and its paths must stay inside the project.
+func (l *Loader) mergeProject(cfg *Config, projectPath string) error {
+	v := viper.New()
+	v.SetConfigFile(projectPath)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("read project config: %w", err)
+	}
+
+	var projectCfg Config
+	if err := v.Unmarshal(&projectCfg); err != nil {
+		return fmt.Errorf("unmarshal project config: %w", err)
+	}
+
+	root := filepath.Dir(projectPath)
+	l.files = append(l.files, projectPath)
+
+	for name, p := range projectCfg.Providers {
+		if err := checkProjectProvider(p); err != nil {
+			return fmt.Errorf("project config %s: provider %q: %w", projectPath, name, err)
+		}
+		paths, err := resolveProjectPaths(root, p.Paths)
+		if err != nil {
+			return fmt.Errorf("project config %s: provider %q: %w", projectPath, name, err)
+		}
+
+		fullName := ProjectProviderName(root, name)
+		p.Project = root
+		p.Paths = paths
+		for _, f := range providerFields {
+			if v.IsSet(providerKey(name, f.key)) {
+				l.origins[providerKey(fullName, f.key)] = projectPath
+			}
+		}
+		cfg.Providers[fullName] = p
+	}
+
+	return nil
+}
+
+// checkProjectProvider rejects the fields a project config may not set.
+func checkProjectProvider(p Provider) error {
+	switch {
+	case p.PathCmd != "":
+		return errors.New("path_cmd is not allowed in project configs")
+	case p.CleanCmd != "":
+		return errors.New("clean_cmd is not allowed in project configs")
+	case p.Engine != "":
+		return errors.New("engine is not allowed in project configs")
+	}
+	return nil
+}
+
+// resolveProjectPaths expands paths and makes relative ones absolute under
+// root. A path outside root, lexically or through a symlink, is an error.
+func resolveProjectPaths(root string, paths []string) ([]string, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve project root: %w", err)
+	}
+
+	resolved := make([]string, len(paths))
+	for i, p := range paths {
+		expanded, err := ExpandPatterns([]string{p})
+		if err != nil {
+			return nil, fmt.Errorf("paths: %w", err)
+		}
+		path := expanded[0]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		path = filepath.Clean(path)
+
+		if !withinDir(root, path) {
+			return nil, fmt.Errorf("path %q is outside the project", p)
+		}
+		target, err := evalExisting(globBase(path))
+		if err != nil {
+			return nil, fmt.Errorf("resolve %q: %w", p, err)
+		}
+		if !withinDir(realRoot, target) {
+			return nil, fmt.Errorf("path %q leads outside the project through a symlink", p)
+		}
+		resolved[i] = path
+	}
+	return resolved, nil
+}
+
+// withinDir reports whether path is dir or below it.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// globBase returns the directory part of a path pattern before its first
+// glob segment, or the path itself when it has none.
+func globBase(pattern string) string {
+	if !strings.ContainsAny(pattern, "*?[{") {
+		return pattern
+	}
+	base, _ := doublestar.SplitPattern(filepath.ToSlash(pattern))
+	return filepath.FromSlash(base)
+}
+
+// evalExisting resolves symlinks in the longest existing prefix of path and
+// appends the rest unchanged.
+func evalExisting(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	realParent, err := evalExisting(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(realParent, filepath.Base(path)), nil
+}
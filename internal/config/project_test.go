@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "src", "pkg")
+	if err := os.MkdirAll(nested, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("none found", func(t *testing.T) {
+		got, err := FindProjectConfig(nested)
+		if err != nil {
+			t.Fatalf("FindProjectConfig() error = %v", err)
+		}
+		// A stray config above TempDir is possible on dev machines; only check the tree we built.
+		if strings.HasPrefix(got, root) {
+			t.Errorf("FindProjectConfig() = %q, want none under %s", got, root)
+		}
+	})
+
+	projectPath := filepath.Join(root, ProjectFile)
+	if err := os.WriteFile(projectPath, []byte("providers: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("walks up from nested dir", func(t *testing.T) {
+		got, err := FindProjectConfig(nested)
+		if err != nil {
+			t.Fatalf("FindProjectConfig() error = %v", err)
+		}
+		if got != projectPath {
+			t.Errorf("FindProjectConfig() = %q, want %q", got, projectPath)
+		}
+	})
+
+	t.Run("finds in start dir", func(t *testing.T) {
+		got, err := FindProjectConfig(root)
+		if err != nil {
+			t.Fatalf("FindProjectConfig() error = %v", err)
+		}
+		if got != projectPath {
+			t.Errorf("FindProjectConfig() = %q, want %q", got, projectPath)
+		}
+	})
+}
+
+func TestLoader_ProjectConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "my.app")
+	workDir := filepath.Join(root, "services", "api")
+	if err := os.MkdirAll(workDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	content := `providers:
+  gradle:
+    enabled: true
+    paths:
+      - .gradle
+      - ${PROJECT_BUILD_DIR:-build}/**/tmp
+    max_size: 2G
+`
+	projectPath := filepath.Join(root, ProjectFile)
+	if err := os.WriteFile(projectPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader()
+	loader.SetConfigPath(filepath.Join(tmpDir, "config.yaml"))
+	loader.SetProjectDir(workDir)
+
+	cfg, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if _, ok := cfg.Providers["gradle"]; !ok {
+		t.Error("global gradle provider should not be shadowed")
+	}
+
+	p, ok := cfg.Providers["my-app/gradle"]
+	if !ok {
+		t.Fatalf("project provider missing, have %v", cfg.AllEnabledProviders())
+	}
+	if p.Project != root {
+		t.Errorf("Project = %q, want %q", p.Project, root)
+	}
+	wantPaths := []string{filepath.Join(root, ".gradle"), filepath.Join(root, "build/**/tmp")}
+	if len(p.Paths) != 2 || p.Paths[0] != wantPaths[0] || p.Paths[1] != wantPaths[1] {
+		t.Errorf("Paths = %v, want %v", p.Paths, wantPaths)
+	}
+	if got := loader.Origin("providers.my-app/gradle.max_size"); got != projectPath {
+		t.Errorf("origin = %q, want %q", got, projectPath)
+	}
+}
+
+func TestLoader_ProjectConfigRejectsEscapes(t *testing.T) {
+	outside := t.TempDir()
+	tests := []struct {
+		name    string
+		field   string
+		wantErr string
+	}{
+		{"path_cmd", "path_cmd: go env GOCACHE", "path_cmd is not allowed"},
+		{"clean_cmd", "clean_cmd: rm -rf ~", "clean_cmd is not allowed"},
+		{"engine", "engine: podman", "engine is not allowed"},
+		{"absolute path", "paths: [" + outside + "]", "is outside the project"},
+		{"home path", "paths: [~/.cache]", "is outside the project"},
+		{"parent path", "paths: [../other]", "is outside the project"},
+		{"symlink", "paths: [link/cache]", "through a symlink"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			root := filepath.Join(tmpDir, "app")
+			if err := os.MkdirAll(root, 0o750); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+				t.Fatal(err)
+			}
+			content := "providers:\n  evil:\n    max_size: 1G\n    " + tt.field + "\n"
+			if err := os.WriteFile(filepath.Join(root, ProjectFile), []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			loader := NewLoader()
+			loader.SetConfigPath(filepath.Join(tmpDir, "config.yaml"))
+			loader.SetProjectDir(root)
+
+			_, err := loader.Load()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Automaat/cache-buster/internal/cache"
@@ -12,15 +13,24 @@ import (
 
 // BaseProvider implements common functionality for providers.
 type BaseProvider struct {
-	name    string
-	paths   []string
-	maxSize int64
-	maxAge  time.Duration
+	name      string
+	onBusy    string
+	lruKey    cache.LRUKey
+	eviction  cache.EvictionStrategy
+	paths     []string
+	processes []string
+	lockFiles []string // doublestar patterns
+	filter    cache.Filter
+	maxSize   int64
+	maxAge    time.Duration
+	headroom  float64 // share of maxSize cleans free below it, as cache.TrimOptions.Headroom
+	// skipOpenFiles keeps files another process has open.
+	skipOpenFiles bool
 }
 
 // NewBaseProvider creates a BaseProvider from config.
 func NewBaseProvider(name string, cfg config.Provider) (*BaseProvider, error) {
-	paths, err := config.ExpandPaths(cfg.Paths)
+	paths, err := config.ResolvePaths(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("expand paths: %w", err)
 	}
@@ -35,11 +45,44 @@ func NewBaseProvider(name string, cfg config.Provider) (*BaseProvider, error) {
 		return nil, fmt.Errorf("parse max_age: %w", err)
 	}
 
+	exclude, err := config.ExpandPatterns(cfg.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("expand exclude: %w", err)
+	}
+
+	protect, err := config.ExpandPatterns(cfg.Protect)
+	if err != nil {
+		return nil, fmt.Errorf("expand protect: %w", err)
+	}
+
+	lockFiles, err := config.ExpandPatterns(cfg.LockFiles)
+	if err != nil {
+		return nil, fmt.Errorf("expand lock_files: %w", err)
+	}
+
+	eviction, err := cache.NewEvictionStrategy(cfg.Eviction)
+	if err != nil {
+		return nil, fmt.Errorf("eviction: %w", err)
+	}
+
+	headroom, err := config.ParseHeadroom(cfg.Headroom)
+	if err != nil {
+		return nil, fmt.Errorf("parse headroom: %w", err)
+	}
+
 	return &BaseProvider{
-		name:    name,
-		paths:   paths,
-		maxSize: maxBytes,
-		maxAge:  maxAge,
+		name:          name,
+		onBusy:        cfg.OnBusy,
+		paths:         paths,
+		processes:     cfg.Processes,
+		lockFiles:     lockFiles,
+		filter:        cache.Filter{Exclude: exclude, Protect: protect},
+		maxSize:       maxBytes,
+		maxAge:        maxAge,
+		headroom:      headroom,
+		eviction:      eviction,
+		lruKey:        cache.LRUKey(cfg.LRUKey),
+		skipOpenFiles: cfg.SkipOpenFiles,
 	}, nil
 }
 
@@ -55,7 +98,7 @@ func (b *BaseProvider) Paths() []string {
 
 // CurrentSize implements Provider.
 func (b *BaseProvider) CurrentSize(ctx context.Context) (int64, error) {
-	result, err := cache.CalculateSizeContext(ctx, b.paths)
+	result, err := cache.CalculateSizeFiltered(ctx, b.paths, b.filter)
 	return result.Size, err
 }
 
@@ -73,3 +116,62 @@ func (b *BaseProvider) MaxAge() time.Duration {
 func (b *BaseProvider) Available() bool {
 	return true
 }
+
+// sizeTarget is the size smart cleans get under: max_size less headroom.
+func (b *BaseProvider) sizeTarget() int64 {
+	return cache.TrimTarget(b.maxSize, b.headroom)
+}
+
+// evictionOrder sorts units a clean removes whole, like module versions or
+// projects, into the order the eviction strategy deletes them in. unit
+// describes one as a file of its size, last used when it was; the Path it
+// gives must be unique.
+func evictionOrder[T any](strategy cache.EvictionStrategy, units []T, unit func(T) cache.FileInfo) {
+	files := make([]cache.FileInfo, len(units))
+	byPath := make(map[string]T, len(units))
+	for i, u := range units {
+		files[i] = unit(u)
+		byPath[files[i].Path] = u
+	}
+	strategy.Order(files, time.Now())
+	for i := range files {
+		units[i] = byPath[files[i].Path]
+	}
+}
+
+// lruWarning warns about provider paths on mounts that do not keep the
+// access times lru_key reads current: noatime never updates them, and
+// relatime about once a day, too coarse for a max_age of a day or less.
+func (b *BaseProvider) lruWarning() string {
+	if !b.lruKey.ReadsAtime() {
+		return ""
+	}
+
+	var warnings []string
+	seen := make(map[string]bool)
+	for _, path := range b.paths {
+		mount, policy, err := cache.AtimePolicy(path)
+		if err != nil || seen[mount] {
+			continue
+		}
+		seen[mount] = true
+		switch {
+		case policy == cache.AtimeNever:
+			warnings = append(warnings, fmt.Sprintf("warning: %s is mounted noatime, lru_key %s cannot see reads there", mount, b.lruKey))
+		case policy == cache.AtimeRelative && b.maxAge > 0 && b.maxAge <= 24*time.Hour:
+			warnings = append(warnings, fmt.Sprintf("warning: %s is mounted relatime, access times lag up to a day behind reads", mount))
+		}
+	}
+	return strings.Join(warnings, "\n")
+}
+
+// withWarning puts warning, if any, on the lines before output.
+func withWarning(warning, output string) string {
+	if warning == "" {
+		return output
+	}
+	if output == "" {
+		return warning
+	}
+	return warning + "\n" + output
+}
@@ -0,0 +1,355 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/Automaat/cache-buster/pkg/size"
+	"golang.org/x/mod/semver"
+)
+
+// crateFilePattern splits "<name>-<version>.crate"; names may contain dashes,
+// so the version starts at the first dash followed by major.minor.
+var crateFilePattern = regexp.MustCompile(`^(.+?)-(\d+\.\d+\.\d+.*)\.crate$`)
+
+// cargoTier orders what the cargo provider removes: cheapest to rebuild first.
+type cargoTier int
+
+const (
+	cargoTierSource   cargoTier = iota // registry/src/<registry>/<crate>-<version>, re-extracted from the .crate
+	cargoTierCheckout                  // git/checkouts/<repo>/<rev>, re-checked out from git/db
+	cargoTierCrate                     // registry/cache/<registry>/<crate>-<version>.crate beyond the newest keep
+)
+
+func (t cargoTier) String() string {
+	switch t {
+	case cargoTierSource:
+		return "source"
+	case cargoTierCheckout:
+		return "checkout"
+	case cargoTierCrate:
+		return "crate"
+	}
+	return "unknown"
+}
+
+// cargoItem is one removable unit: an extracted source tree, a checkout, or a .crate file.
+type cargoItem struct {
+	lastUsed time.Time
+	root     string // provider path the item was found under
+	path     string
+	size     int64
+	tier     cargoTier
+}
+
+// CargoProvider cleans ~/.cargo in tiers: extracted registry sources and git
+// checkouts first, then .crate archives beyond the newest keep per crate.
+// The registry index and git databases are never touched.
+type CargoProvider struct {
+	*BaseProvider
+	keep int
+}
+
+// NewCargoProvider creates a registry-aware cargo provider.
+func NewCargoProvider(name string, cfg config.Provider) (*CargoProvider, error) {
+	base, err := NewBaseProvider(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CargoProvider{
+		BaseProvider: base,
+		keep:         cfg.Keep,
+	}, nil
+}
+
+// Clean implements Provider. Full mode removes every source, checkout, and
+// surplus .crate. Smart mode removes items unused for max_age, then continues
+// tier by tier, in eviction order within a tier, while the cache exceeds
+// max_size less headroom.
+func (p *CargoProvider) Clean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
+	items, err := p.findItems(ctx)
+	if err != nil {
+		return CleanResult{}, err
+	}
+	open, err := p.openFiles()
+	if err != nil {
+		return CleanResult{}, err
+	}
+	items, skipped := skipOpen(open, items,
+		func(item cargoItem) []string { return []string{item.path} },
+		func(item cargoItem) string { return item.tier.String() + " " + filepath.Base(item.path) })
+
+	if opts.Mode == CleanModeSmart {
+		current, err := p.CurrentSize(ctx)
+		if err != nil {
+			return CleanResult{}, err
+		}
+		items = p.selectSmart(items, current)
+	}
+
+	result, err := p.removeItems(ctx, items, opts.DryRun)
+	result.Output = withWarning(p.lruWarning(), withWarning(skipped, result.Output))
+	return result, err
+}
+
+func (p *CargoProvider) selectSmart(items []cargoItem, current int64) []cargoItem {
+	cutoff := time.Now().Add(-p.maxAge)
+	var selected, rest []cargoItem
+	for _, item := range items {
+		if p.maxAge > 0 && item.lastUsed.Before(cutoff) {
+			selected = append(selected, item)
+			current -= item.size
+		} else {
+			rest = append(rest, item)
+		}
+	}
+
+	// Cheaper tiers still go first; the eviction strategy orders each tier.
+	evictionOrder(p.eviction, rest, func(item cargoItem) cache.FileInfo {
+		return cache.FileInfo{Path: item.path, Size: item.size, LastUsed: item.lastUsed}
+	})
+	sort.SliceStable(rest, func(i, j int) bool {
+		return rest[i].tier < rest[j].tier
+	})
+
+	target := p.sizeTarget()
+	for _, item := range rest {
+		if current <= target {
+			break
+		}
+		selected = append(selected, item)
+		current -= item.size
+	}
+	return selected
+}
+
+func (p *CargoProvider) removeItems(ctx context.Context, items []cargoItem, dryRun bool) (CleanResult, error) {
+	if len(items) == 0 {
+		return CleanResult{Output: "nothing to clean"}, nil
+	}
+
+	var (
+		result  CleanResult
+		output  strings.Builder
+		remover = cache.Remover{Roots: p.paths}
+		counts  = make(map[cargoTier]int)
+	)
+
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			result.Output = "interrupted"
+			return result, err
+		}
+
+		if dryRun {
+			fmt.Fprintf(&output, "would remove %s: %s (%s)\n", item.tier, filepath.Base(item.path), size.FormatSize(item.size))
+			result.BytesCleaned += item.size
+			continue
+		}
+
+		var err error
+		if item.tier == cargoTierCrate {
+			err = remover.Remove(item.path)
+		} else {
+			err = os.RemoveAll(item.path)
+		}
+		if err != nil {
+			fmt.Fprintf(&output, "error removing %s: %v\n", item.path, err)
+			continue
+		}
+		result.BytesCleaned += item.size
+		result.FilesDeleted++
+		counts[item.tier]++
+	}
+
+	if dryRun {
+		result.Output = strings.TrimSpace(output.String())
+		return result, nil
+	}
+
+	for _, e := range remover.Restore() {
+		fmt.Fprintf(&output, "warning: %v\n", e)
+	}
+	result.Fixups = remover.Fixups()
+	result.Output = fmt.Sprintf("removed %d sources, %d checkouts, %d crates",
+		counts[cargoTierSource], counts[cargoTierCheckout], counts[cargoTierCrate])
+	if output.Len() > 0 {
+		result.Output += "\n" + strings.TrimSpace(output.String())
+	}
+	return result, nil
+}
+
+// findItems lists removable items ordered by tier, oldest first within a tier.
+// Each provider path may be CARGO_HOME itself or its registry or git directory.
+func (p *CargoProvider) findItems(ctx context.Context) ([]cargoItem, error) {
+	var items []cargoItem
+
+	for _, root := range p.paths {
+		registry, git := filepath.Join(root, "registry"), filepath.Join(root, "git")
+		switch filepath.Base(root) {
+		case "registry":
+			registry, git = root, ""
+		case "git":
+			registry, git = "", root
+		}
+
+		if registry != "" {
+			found, err := p.listDirs(ctx, root, filepath.Join(registry, "src"), cargoTierSource)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, found...)
+
+			crates, err := p.listSurplusCrates(ctx, root, filepath.Join(registry, "cache"))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, crates...)
+		}
+		if git != "" {
+			found, err := p.listDirs(ctx, root, filepath.Join(git, "checkouts"), cargoTierCheckout)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, found...)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].tier != items[j].tier {
+			return items[i].tier < items[j].tier
+		}
+		return items[i].lastUsed.Before(items[j].lastUsed)
+	})
+	return items, nil
+}
+
+// listDirs returns the second-level directories under base (<registry>/<crate>
+// or <repo>/<rev>), skipping guarded ones.
+func (p *CargoProvider) listDirs(ctx context.Context, root, base string, tier cargoTier) ([]cargoItem, error) {
+	groups, err := os.ReadDir(base)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var items []cargoItem
+	for _, group := range groups {
+		if !group.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(base, group.Name()))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			path := filepath.Join(base, group.Name(), e.Name())
+			guarded, err := p.filter.Guards(root, path)
+			if err != nil {
+				return nil, err
+			}
+			if guarded {
+				continue
+			}
+			dirSize, err := cache.CalculateSizeContext(ctx, []string{path})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, cargoItem{
+				root:     root,
+				path:     path,
+				size:     dirSize.Size,
+				tier:     tier,
+				lastUsed: extractedAt(path, p.lruKey),
+			})
+		}
+	}
+	return items, nil
+}
+
+// extractedAt returns when key says a source or checkout was last used. File
+// times come from the crate tarball, so those of the .cargo-ok marker cargo
+// writes on extraction (or of the directory itself) are read instead.
+func extractedAt(dir string, key cache.LRUKey) time.Time {
+	for _, path := range []string{filepath.Join(dir, ".cargo-ok"), dir} {
+		if info, err := os.Stat(path); err == nil {
+			return key.Time(info)
+		}
+	}
+	return time.Time{}
+}
+
+// crateFile is a .crate archive with the version parsed from its name.
+type crateFile struct {
+	version string
+	item    cargoItem
+}
+
+// listSurplusCrates returns .crate files beyond the newest keep versions of each crate.
+func (p *CargoProvider) listSurplusCrates(ctx context.Context, root, base string) ([]cargoItem, error) {
+	groups, err := os.ReadDir(base)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var items []cargoItem
+	for _, group := range groups {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !group.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(base, group.Name()))
+		if err != nil {
+			continue
+		}
+
+		byCrate := make(map[string][]crateFile)
+		for _, e := range entries {
+			m := crateFilePattern.FindStringSubmatch(e.Name())
+			if m == nil || e.IsDir() {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(base, group.Name(), e.Name())
+			byCrate[m[1]] = append(byCrate[m[1]], crateFile{
+				version: m[2],
+				item:    cargoItem{root: root, path: path, size: info.Size(), tier: cargoTierCrate, lastUsed: p.lruKey.Time(info)},
+			})
+		}
+
+		for _, versions := range byCrate {
+			sort.Slice(versions, func(i, j int) bool {
+				return semver.Compare("v"+versions[i].version, "v"+versions[j].version) > 0
+			})
+			for i, v := range versions {
+				if i < p.keep || p.filter.Excluded(root, v.item.path) || p.filter.Protected(root, v.item.path) {
+					continue
+				}
+				items = append(items, v.item)
+			}
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCargoRegistry = "index.crates.io-6f17d22bba15001f"
+
+func writeAged(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0o644))
+	when := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(path, when, when))
+}
+
+// setupCargoHome builds a CARGO_HOME with an old extracted source, a recent
+// checkout, and two versions of each crate.
+func setupCargoHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	old := 60 * 24 * time.Hour
+
+	writeAged(t, filepath.Join(home, "registry", "index", testCargoRegistry, "config.json"), 10, old)
+
+	cacheDir := filepath.Join(home, "registry", "cache", testCargoRegistry)
+	writeAged(t, filepath.Join(cacheDir, "serde-1.0.100.crate"), 100, time.Hour)
+	writeAged(t, filepath.Join(cacheDir, "serde-1.0.200.crate"), 100, time.Hour)
+	writeAged(t, filepath.Join(cacheDir, "wasm-bindgen-0.2.89.crate"), 100, time.Hour)
+	writeAged(t, filepath.Join(cacheDir, "wasm-bindgen-0.2.90.crate"), 100, time.Hour)
+
+	src := filepath.Join(home, "registry", "src", testCargoRegistry, "serde-1.0.200")
+	writeAged(t, filepath.Join(src, "src", "lib.rs"), 300, 5*365*24*time.Hour) // tarball mtime
+	writeAged(t, filepath.Join(src, ".cargo-ok"), 0, old)
+
+	writeAged(t, filepath.Join(home, "git", "db", "repo-1234", "HEAD"), 10, old)
+	writeAged(t, filepath.Join(home, "git", "checkouts", "repo-1234", "abc1234", "lib.rs"), 200, time.Hour)
+	writeAged(t, filepath.Join(home, "git", "checkouts", "repo-1234", "abc1234", ".cargo-ok"), 0, time.Hour)
+
+	return home
+}
+
+func newTestCargoProvider(t *testing.T, paths []string, maxSize string) *CargoProvider {
+	t.Helper()
+	p, err := NewCargoProvider("cargo", config.Provider{Paths: paths, MaxSize: maxSize, MaxAge: "30d", Keep: 1})
+	require.NoError(t, err)
+	return p
+}
+
+func cargoPaths(home string) []string {
+	return []string{filepath.Join(home, "registry"), filepath.Join(home, "git")}
+}
+
+func TestCrateFilePattern(t *testing.T) {
+	tests := []struct {
+		file    string
+		name    string
+		version string
+	}{
+		{file: "serde-1.0.200.crate", name: "serde", version: "1.0.200"},
+		{file: "wasm-bindgen-0.2.89.crate", name: "wasm-bindgen", version: "0.2.89"},
+		{file: "foo-2d-1.0.0-beta.1.crate", name: "foo-2d", version: "1.0.0-beta.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			m := crateFilePattern.FindStringSubmatch(tt.file)
+			require.NotNil(t, m)
+			assert.Equal(t, tt.name, m[1])
+			assert.Equal(t, tt.version, m[2])
+		})
+	}
+	assert.Nil(t, crateFilePattern.FindStringSubmatch("README.md"))
+}
+
+func TestCargoProvider_FullClean(t *testing.T) {
+	home := setupCargoHome(t)
+	p := newTestCargoProvider(t, cargoPaths(home), "1G")
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "removed 1 sources, 1 checkouts, 2 crates")
+	assert.Equal(t, int64(700), result.BytesCleaned)
+
+	cacheDir := filepath.Join(home, "registry", "cache", testCargoRegistry)
+	assert.NoFileExists(t, filepath.Join(cacheDir, "serde-1.0.100.crate"))
+	assert.NoFileExists(t, filepath.Join(cacheDir, "wasm-bindgen-0.2.89.crate"))
+	assert.FileExists(t, filepath.Join(cacheDir, "serde-1.0.200.crate"))
+	assert.FileExists(t, filepath.Join(cacheDir, "wasm-bindgen-0.2.90.crate"))
+	assert.NoDirExists(t, filepath.Join(home, "registry", "src", testCargoRegistry, "serde-1.0.200"))
+	assert.NoDirExists(t, filepath.Join(home, "git", "checkouts", "repo-1234", "abc1234"))
+
+	// The index and git databases are never touched.
+	assert.FileExists(t, filepath.Join(home, "registry", "index", testCargoRegistry, "config.json"))
+	assert.FileExists(t, filepath.Join(home, "git", "db", "repo-1234", "HEAD"))
+}
+
+func TestCargoProvider_SmartCleanByAge(t *testing.T) {
+	home := setupCargoHome(t)
+	p := newTestCargoProvider(t, cargoPaths(home), "1G")
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+
+	// Only the source extracted before max_age goes; its tarball mtimes are ignored.
+	assert.Equal(t, int64(300), result.BytesCleaned)
+	assert.NoDirExists(t, filepath.Join(home, "registry", "src", testCargoRegistry, "serde-1.0.200"))
+	assert.DirExists(t, filepath.Join(home, "git", "checkouts", "repo-1234", "abc1234"))
+}
+
+func TestCargoProvider_SmartCleanTierOrder(t *testing.T) {
+	home := setupCargoHome(t)
+	p := newTestCargoProvider(t, cargoPaths(home), "1G")
+	p.maxAge = 0
+
+	current, err := p.CurrentSize(t.Context())
+	require.NoError(t, err)
+	// Over by more than a source and a checkout: one surplus crate must go too.
+	p.maxSize = current - 550
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), result.BytesCleaned)
+	assert.Contains(t, result.Output, "removed 1 sources, 1 checkouts, 1 crates")
+}
+
+func TestCargoProvider_CargoHomePath(t *testing.T) {
+	home := setupCargoHome(t)
+	p := newTestCargoProvider(t, []string{home}, "1G")
+
+	items, err := p.findItems(t.Context())
+	require.NoError(t, err)
+	require.Len(t, items, 4)
+	assert.Equal(t, cargoTierSource, items[0].tier)
+	assert.Equal(t, cargoTierCheckout, items[1].tier)
+	assert.Equal(t, cargoTierCrate, items[2].tier)
+}
+
+func TestCargoProvider_DryRun(t *testing.T) {
+	home := setupCargoHome(t)
+	p := newTestCargoProvider(t, cargoPaths(home), "1G")
+
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true, Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Equal(t, int64(700), result.BytesCleaned)
+	assert.Contains(t, result.Output, "would remove source: serde-1.0.200")
+	assert.Contains(t, result.Output, "would remove crate: serde-1.0.100.crate")
+	assert.DirExists(t, filepath.Join(home, "registry", "src", testCargoRegistry, "serde-1.0.200"))
+}
+
+func TestNewProvider_Cargo(t *testing.T) {
+	p, err := NewProvider("cargo", config.Provider{Paths: []string{t.TempDir()}, MaxSize: "1G"})
+	require.NoError(t, err)
+	assert.IsType(t, &CargoProvider{}, p)
+}
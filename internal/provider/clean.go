@@ -7,11 +7,13 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/Automaat/cache-buster/internal/config"
 )
 
 // runMeasuredClean runs args as a command, measuring cache size before and
 // after via sizeFn to report freed bytes. name is used for warnings. It is the
-// shared scaffold behind the command-based fullClean and Docker smartClean.
+// shared scaffold behind the command-based and Docker full cleans.
 func runMeasuredClean(
 	ctx context.Context,
 	name string,
@@ -21,29 +23,75 @@ func runMeasuredClean(
 	if len(args) == 0 {
 		return CleanResult{}, nil
 	}
+	return runMeasuredCommands(ctx, name, [][]string{args}, sizeFn)
+}
+
+// runCleanCmd runs a configured clean_cmd as a measured full clean, or only
+// names it in a dry run. Providers that clean natively still defer to a
+// clean_cmd the user configured.
+func runCleanCmd(
+	ctx context.Context,
+	name, cleanCmd string,
+	dryRun bool,
+	sizeFn func(context.Context) (int64, error),
+) (CleanResult, error) {
+	if dryRun {
+		return CleanResult{Output: "would run: " + cleanCmd}, nil
+	}
 
+	args, err := config.SplitCommand(cleanCmd)
+	if err != nil {
+		return CleanResult{}, fmt.Errorf("invalid command: %w", err)
+	}
+	return runMeasuredClean(ctx, name, args, sizeFn)
+}
+
+// runMeasuredCommands is runMeasuredClean for a sequence of commands, measured
+// as one clean. It stops at the first command that fails.
+func runMeasuredCommands(
+	ctx context.Context,
+	name string,
+	cmds [][]string,
+	sizeFn func(context.Context) (int64, error),
+) (CleanResult, error) {
 	sizeBefore, _ := sizeFn(ctx)
 
+	var outputs []string
+	for _, args := range cmds {
+		output, err := runCommand(ctx, args)
+		if output != "" {
+			outputs = append(outputs, output)
+		}
+		if err != nil {
+			return CleanResult{Output: strings.Join(outputs, "\n")}, err
+		}
+	}
+
+	sizeAfter, _ := sizeFn(ctx)
+
+	return CleanResult{
+		BytesCleaned: freedBytes(name, sizeBefore, sizeAfter),
+		Output:       strings.Join(outputs, "\n"),
+	}, nil
+}
+
+// runCommand runs args and returns its trimmed stdout and stderr.
+func runCommand(ctx context.Context, args []string) (string, error) {
 	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
-	output := strings.TrimSpace(stdout.String() + stderr.String())
-	if err != nil {
-		return CleanResult{Output: output}, err
-	}
+	return strings.TrimSpace(stdout.String() + stderr.String()), err
+}
 
-	sizeAfter, _ := sizeFn(ctx)
-	bytesCleaned := sizeBefore - sizeAfter
-	if bytesCleaned < 0 {
+// freedBytes reports the bytes a clean freed from sizes measured around it.
+// A size that grew meanwhile (the tool kept writing) counts as nothing freed.
+func freedBytes(name string, sizeBefore, sizeAfter int64) int64 {
+	if sizeAfter > sizeBefore {
 		fmt.Fprintf(os.Stderr, "warning: %s cache size increased during clean\n", name)
-		bytesCleaned = 0
+		return 0
 	}
-
-	return CleanResult{
-		BytesCleaned: bytesCleaned,
-		Output:       output,
-	}, nil
+	return sizeBefore - sizeAfter
 }
@@ -7,7 +7,6 @@ import (
 
 	"github.com/Automaat/cache-buster/internal/cache"
 	"github.com/Automaat/cache-buster/internal/config"
-	"github.com/kballard/go-shellquote"
 )
 
 // CommandProvider cleans caches by running an external command.
@@ -24,7 +23,7 @@ func NewCommandProvider(name string, cfg config.Provider) (*CommandProvider, err
 		return nil, err
 	}
 
-	args, err := shellquote.Split(cfg.CleanCmd)
+	args, err := config.SplitCommand(cfg.CleanCmd)
 	if err != nil {
 		return nil, fmt.Errorf("invalid clean_cmd: %w", err)
 	}
@@ -57,19 +56,33 @@ func (p *CommandProvider) Clean(ctx context.Context, opts CleanOptions) (CleanRe
 
 func (p *CommandProvider) smartClean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
 	trimResult, err := cache.Trim(ctx, p.paths, cache.TrimOptions{
-		MaxSize: p.maxSize,
-		MaxAge:  p.maxAge,
-		DryRun:  opts.DryRun,
+		MaxSize:       p.maxSize,
+		MaxAge:        p.maxAge,
+		LRUKey:        p.lruKey,
+		Strategy:      p.eviction,
+		Headroom:      p.headroom,
+		AccessLog:     opts.AccessLog,
+		Filter:        p.filter,
+		DryRun:        opts.DryRun,
+		SkipOpenFiles: p.skipOpenFiles,
 	})
 	if err != nil {
 		return CleanResult{}, err
 	}
 
-	return CleanResult{
+	result := CleanResult{
 		BytesCleaned: trimResult.FreedBytes,
 		FilesDeleted: trimResult.DeletedCount,
+		Fixups:       trimResult.Fixups,
 		Output:       trimResult.Output,
-	}, nil
+	}
+
+	if len(trimResult.Errors) > 0 {
+		result.Output = formatResultWithErrors(trimResult.Output, trimResult.DeletedCount, trimResult.Errors)
+	}
+	result.Output = withWarning(p.lruWarning(), result.Output)
+
+	return result, nil
 }
 
 func (p *CommandProvider) fullClean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/Automaat/cache-buster/internal/config"
+)
+
+// containerEngine is a docker-compatible CLI a DockerProvider drives. Each
+// accepts the same container, image, volume, and system df subcommands.
+type containerEngine struct {
+	bin         string
+	hostEnv     string // variable naming the daemon address, if the CLI reads one
+	defaultHost string // daemon address when neither hostEnv nor a context sets one
+	buildCache  bool   // has a BuildKit cache emptied with builder prune -af
+	keepStorage bool   // builder prune also takes --filter until and --keep-storage
+	contexts    bool   // the CLI resolves docker contexts
+	api         bool   // the daemon serves the Docker Engine API, used over a unix socket
+}
+
+// containerEngines are the engine config values. Podman has no separate
+// build cache; nerdctl's BuildKit cache can only be emptied.
+var containerEngines = map[string]containerEngine{
+	config.EngineDocker: {
+		bin:         "docker",
+		hostEnv:     "DOCKER_HOST",
+		defaultHost: "unix:///var/run/docker.sock",
+		buildCache:  true,
+		keepStorage: true,
+		contexts:    true,
+		api:         true,
+	},
+	config.EnginePodman:  {bin: "podman", hostEnv: "CONTAINER_HOST"},
+	config.EngineNerdctl: {bin: "nerdctl", buildCache: true},
+}
+
+// host returns the daemon address the CLI connects to: the host variable if
+// set, else the endpoint of the current docker context, else the default.
+// Empty means the CLI needs no daemon address, e.g. rootless podman.
+func (e *containerEngine) host() string {
+	if e.hostEnv != "" {
+		if h := os.Getenv(e.hostEnv); h != "" {
+			return h
+		}
+	}
+	if e.contexts {
+		if h := dockerContextHost(); h != "" {
+			return h
+		}
+	}
+	return e.defaultHost
+}
+
+// dockerContextHost returns the docker endpoint of the context named by
+// DOCKER_CONTEXT or the currentContext of the docker CLI config, or "" for
+// the default context.
+func dockerContextHost() string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	name := os.Getenv("DOCKER_CONTEXT")
+	if name == "" {
+		var cliConfig struct {
+			CurrentContext string `json:"currentContext"`
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, "config.json")); err == nil {
+			_ = json.Unmarshal(data, &cliConfig)
+		}
+		name = cliConfig.CurrentContext
+	}
+	if name == "" || name == "default" {
+		return ""
+	}
+
+	// Context metadata lives under the SHA-256 of the context name.
+	sum := sha256.Sum256([]byte(name))
+	data, err := os.ReadFile(filepath.Join(dir, "contexts", "meta", hex.EncodeToString(sum[:]), "meta.json"))
+	if err != nil {
+		return ""
+	}
+	var meta struct {
+		Endpoints map[string]struct {
+			Host string `json:"Host"`
+		} `json:"Endpoints"`
+	}
+	if json.Unmarshal(data, &meta) != nil {
+		return ""
+	}
+	return meta.Endpoints["docker"].Host
+}
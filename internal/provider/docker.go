@@ -4,21 +4,55 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Automaat/cache-buster/internal/config"
 	"github.com/Automaat/cache-buster/pkg/size"
-	"github.com/kballard/go-shellquote"
 )
 
-// DockerProvider cleans Docker caches when daemon is available.
+// dockerCategory ties a categories config value to its docker system df row
+// and the docker object kind removed for it.
+type dockerCategory struct {
+	name   string // config.Category* value
+	dfType string // Type column of docker system df
+	kind   string // docker <kind> rm; empty for build cache, which is pruned
+}
+
+// dockerCategories in clean order: containers go first so the images and
+// volumes only they used become unused, and images last so smart clean
+// only removes them if the rest left docker over max_size.
+var dockerCategories = []dockerCategory{
+	{name: config.CategoryContainers, dfType: "Containers", kind: "container"},
+	{name: config.CategoryVolumes, dfType: "Local Volumes", kind: "volume"},
+	{name: config.CategoryBuildCache, dfType: "Build Cache"},
+	{name: config.CategoryImages, dfType: "Images", kind: "image"},
+}
+
+// dockerDefaultCategories are cleaned when no categories are configured.
+// Volumes hold data rather than cache, so they must be asked for.
+var dockerDefaultCategories = []string{config.CategoryContainers, config.CategoryBuildCache, config.CategoryImages}
+
+// DockerProvider cleans Docker caches when daemon is available. The engine
+// field swaps the docker CLI for podman or nerdctl.
 type DockerProvider struct {
 	*BaseProvider
-	cleanCmd string
+	protect    dockerProtection
+	engine     containerEngine
+	cleanCmd   string
+	categories []string
+	warning    string // set when the legacy clean_cmd is ignored
 }
 
+// legacyDockerCleanCmd is the clean_cmd older config init versions wrote.
+// It also deletes every volume, so it is ignored in favor of object clean.
+var legacyDockerCleanCmd = []string{"docker", "system", "prune", "-af", "--volumes"}
+
 // NewDockerProvider creates a Docker provider with availability checking.
 func NewDockerProvider(name string, cfg config.Provider) (*DockerProvider, error) {
 	base, err := NewBaseProvider(name, cfg)
@@ -26,15 +60,37 @@ func NewDockerProvider(name string, cfg config.Provider) (*DockerProvider, error
 		return nil, err
 	}
 
+	cleanCmd, warning := cfg.CleanCmd, ""
+	if slices.Equal(strings.Fields(cleanCmd), legacyDockerCleanCmd) {
+		cleanCmd = ""
+		warning = fmt.Sprintf("warning: ignoring clean_cmd %q, the old default that also deletes volumes; remove it from the config", cfg.CleanCmd)
+	}
+
+	protect := dockerProtection{images: cfg.ProtectImages, volumes: cfg.ProtectVolumes, labels: cfg.ProtectLabels}
+	if cleanCmd != "" && len(protect.images)+len(protect.volumes)+len(protect.labels) > 0 {
+		return nil, fmt.Errorf("clean_cmd would bypass protect_images, protect_volumes, and protect_labels; remove it")
+	}
+
+	engine := cfg.Engine
+	if engine == "" {
+		engine = config.EngineDocker
+	}
+
 	return &DockerProvider{
 		BaseProvider: base,
-		cleanCmd:     cfg.CleanCmd,
+		protect:      protect,
+		engine:       containerEngines[engine],
+		cleanCmd:     cleanCmd,
+		categories:   cfg.Categories,
+		warning:      warning,
 	}, nil
 }
 
 // dockerDFRow is one line of docker system df --format '{{json .}}' output.
 type dockerDFRow struct {
-	Size string `json:"Size"`
+	Type        string `json:"Type"`
+	Size        string `json:"Size"`
+	Reclaimable string `json:"Reclaimable"` // e.g. "1.2GB (45%)"
 }
 
 // CurrentSize returns actual Docker data usage from docker system df.
@@ -52,22 +108,48 @@ func (p *DockerProvider) DiskImageSize(ctx context.Context) (int64, error) {
 }
 
 func (p *DockerProvider) dockerDataSize(ctx context.Context) (int64, error) {
+	categories, err := p.dockerSystemDF(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, c := range categories {
+		total += c.Size
+	}
+	return total, nil
+}
+
+// Breakdown implements Breakdowner with the rows of docker system df.
+func (p *DockerProvider) Breakdown(ctx context.Context) ([]UsageCategory, error) {
+	return p.dockerSystemDF(ctx)
+}
+
+// dockerSystemDF returns the rows of docker system df, named by their
+// config.Category* value where known. The Engine API gives exact sizes; the
+// CLI, used when the API can't be reached, rounds them.
+func (p *DockerProvider) dockerSystemDF(ctx context.Context) ([]UsageCategory, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "docker", "system", "df", "--format", "{{json .}}")
+	if api := p.api(); api != nil {
+		if categories, err := api.systemDF(ctx); err == nil {
+			return categories, nil
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, p.engine.bin, "system", "df", "--format", "{{json .}}")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		msg := strings.TrimSpace(string(out))
 		if msg != "" {
-			return 0, fmt.Errorf("docker system df: %w: %s", err, msg)
+			return nil, fmt.Errorf("%s system df: %w: %s", p.engine.bin, err, msg)
 		}
-		return 0, fmt.Errorf("docker system df: %w", err)
+		return nil, fmt.Errorf("%s system df: %w", p.engine.bin, err)
 	}
 
-	var total int64
+	var categories []UsageCategory
 	var firstErr error
-	var rowsParsed int
 
 	for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
 		if line == "" {
@@ -87,70 +169,444 @@ func (p *DockerProvider) dockerDataSize(ctx context.Context) (int64, error) {
 			}
 			continue
 		}
-		total += b
-		rowsParsed++
+		// Reclaimable carries a percentage suffix; an unparsable value only loses the hint.
+		reclaimableField, _, _ := strings.Cut(row.Reclaimable, " ")
+		reclaimable, _ := size.ParseSize(reclaimableField)
+		categories = append(categories, UsageCategory{
+			Name:        dockerCategoryName(row.Type),
+			Size:        b,
+			Reclaimable: reclaimable,
+		})
 	}
 
-	if rowsParsed == 0 {
+	if len(categories) == 0 {
 		if firstErr != nil {
-			return 0, firstErr
+			return nil, firstErr
 		}
-		return 0, fmt.Errorf("docker system df: no parsable output")
+		return nil, fmt.Errorf("%s system df: no parsable output", p.engine.bin)
 	}
 
 	// Some rows parsed successfully; treat partial parse errors as non-fatal.
-	return total, nil
+	return categories, nil
 }
 
-// Available implements Provider.
+// dockerCategoryName maps a docker system df Type to its categories config value.
+func dockerCategoryName(dfType string) string {
+	for _, c := range dockerCategories {
+		if c.dfType == dfType {
+			return c.name
+		}
+	}
+	return strings.ToLower(dfType)
+}
+
+// Available implements Provider. It checks the daemon the CLI would talk to,
+// per DOCKER_HOST (CONTAINER_HOST for podman) or the current docker context.
 func (p *DockerProvider) Available() bool {
-	if _, err := exec.LookPath("docker"); err != nil {
+	if _, err := exec.LookPath(p.engine.bin); err != nil {
 		return false
 	}
 
-	cmd := exec.Command("docker", "ps", "--quiet")
+	// A missing socket means the daemon is down; don't wait on the CLI to say so.
+	if path, ok := strings.CutPrefix(p.engine.host(), "unix://"); ok {
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if api := p.api(); api != nil && api.ping(ctx) == nil {
+		return true
+	}
+	cmd := exec.CommandContext(ctx, p.engine.bin, "ps", "--quiet")
 	return cmd.Run() == nil
 }
 
-// Clean implements Provider.
+// api returns an Engine API client for the daemon, or nil if the engine or
+// its host has none; callers fall back to the CLI.
+func (p *DockerProvider) api() *dockerAPI {
+	if !p.engine.api {
+		return nil
+	}
+	return newDockerAPI(p.engine.host())
+}
+
+// Clean implements Provider. A configured clean_cmd replaces full clean;
+// otherwise containers, volumes, and images are removed one by one so
+// protections hold and a dry run lists exactly what would go.
 func (p *DockerProvider) Clean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
 	if !p.Available() {
 		return CleanResult{
-			Output: "docker not available",
+			Output: p.engine.bin + " not available",
 		}, nil
 	}
 
-	if opts.Mode == CleanModeSmart {
-		return p.smartClean(ctx, opts)
+	if opts.Mode == CleanModeFull && p.cleanCmd != "" && len(p.categories) == 0 {
+		return p.fullClean(ctx, opts)
 	}
-	return p.fullClean(ctx, opts)
+	result, err := p.objectClean(ctx, opts)
+	result.Output = withWarning(p.warning, result.Output)
+	return result, err
 }
 
-func (p *DockerProvider) smartClean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
-	hours := max(int64(p.maxAge.Hours()), 1)
-	filterArg := fmt.Sprintf("until=%dh", hours)
-	args := []string{"docker", "system", "prune", "-af", "--volumes", "--filter", filterArg}
+// dockerObject is a container, volume, or image a clean removes.
+type dockerObject struct {
+	label  string
+	detail string   // shown in dry runs, e.g. when an image was last used
+	refs   []string // arguments to docker <kind> rm
+	size   int64    // known for images only
+}
+
+// objectClean cleans the configured categories. Full mode removes every
+// stopped container, unused volume and image, and all build cache. Smart
+// mode removes containers stopped more than max_age ago, build cache older
+// than max_age and then down to max_size, and images by last use (see
+// removableImages). Protected objects are never removed.
+func (p *DockerProvider) objectClean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
+	categories := p.categories
+	if len(categories) == 0 {
+		categories = dockerDefaultCategories
+		if !p.engine.buildCache {
+			categories = slices.DeleteFunc(slices.Clone(categories), func(c string) bool {
+				return c == config.CategoryBuildCache
+			})
+		}
+	}
+
+	inv, err := loadInventory(ctx, &p.engine, slices.Contains(categories, config.CategoryVolumes))
+	if err != nil {
+		return CleanResult{}, err
+	}
+
+	var sizeBefore int64
+	if !opts.DryRun {
+		sizeBefore, _ = p.CurrentSize(ctx)
+	}
+
+	var removedContainers []dockerObject
+	remaining := inv.containers
+	if slices.Contains(categories, config.CategoryContainers) {
+		removedContainers, remaining = p.removableContainers(inv.containers, opts.Mode)
+	}
+
+	var (
+		output    []string
+		estimated int64
+	)
+	for _, c := range dockerCategories {
+		if !slices.Contains(categories, c.name) {
+			continue
+		}
+
+		var objects []dockerObject
+		switch c.name {
+		case config.CategoryContainers:
+			objects = removedContainers
+		case config.CategoryVolumes:
+			objects = p.removableVolumes(inv.volumes, remaining)
+		case config.CategoryImages:
+			if lines, ok, err := p.pruneImages(ctx, opts); ok {
+				output = append(output, lines...)
+				if err != nil {
+					return CleanResult{Output: strings.Join(output, "\n")}, err
+				}
+				continue
+			}
+			objects, err = p.removableImages(ctx, inv.images, inv.containers, remaining, opts.Mode)
+			if err != nil {
+				return CleanResult{Output: strings.Join(output, "\n")}, err
+			}
+		case config.CategoryBuildCache:
+			lines, err := p.pruneBuildCache(ctx, opts)
+			output = append(output, lines...)
+			if err != nil {
+				return CleanResult{Output: strings.Join(output, "\n")}, err
+			}
+			continue
+		}
+
+		output = append(output, removeDockerObjects(ctx, &p.engine, c.kind, objects, opts.DryRun)...)
+		for _, o := range objects {
+			estimated += o.size
+		}
+	}
 
+	if len(output) == 0 {
+		output = append(output, "nothing to clean")
+	}
+	result := CleanResult{Output: strings.Join(output, "\n")}
 	if opts.DryRun {
-		return CleanResult{
-			Output: "would run: " + strings.Join(args, " "),
-		}, nil
+		result.BytesCleaned = estimated
+		return result, nil
 	}
 
-	return runMeasuredClean(ctx, p.name, args, p.CurrentSize)
+	sizeAfter, _ := p.CurrentSize(ctx)
+	result.BytesCleaned = freedBytes(p.name, sizeBefore, sizeAfter)
+	return result, nil
 }
 
-func (p *DockerProvider) fullClean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
-	if opts.DryRun {
-		return CleanResult{
-			Output: "would run: " + p.cleanCmd,
-		}, nil
+// removeDockerObjects removes objects with <engine> <kind> rm, or lists them
+// in a dry run, and returns the output lines.
+func removeDockerObjects(ctx context.Context, engine *containerEngine, kind string, objects []dockerObject, dryRun bool) []string {
+	var lines []string
+	removed := 0
+	for _, o := range objects {
+		if dryRun {
+			line := fmt.Sprintf("would remove %s: %s", kind, o.label)
+			if o.detail != "" {
+				line += " (" + o.detail + ")"
+			}
+			lines = append(lines, line)
+			continue
+		}
+
+		out, err := runCommand(ctx, append([]string{engine.bin, kind, "rm"}, o.refs...))
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("error removing %s %s: %v: %s", kind, o.label, err, out))
+			continue
+		}
+		removed++
 	}
 
-	parts, err := shellquote.Split(p.cleanCmd)
+	if removed > 0 {
+		lines = append(lines, fmt.Sprintf("removed %d %ss", removed, kind))
+	}
+	return lines
+}
+
+// removableContainers splits containers into those a clean removes and those
+// it keeps: running or protected ones, and in smart mode those stopped within max_age.
+func (p *DockerProvider) removableContainers(containers []dockerContainer, mode CleanMode) (remove []dockerObject, keep []dockerContainer) {
+	var cutoff time.Time
+	if mode == CleanModeSmart && p.maxAge > 0 {
+		cutoff = time.Now().Add(-p.maxAge)
+	}
+
+	for i := range containers {
+		c := &containers[i]
+		if !c.stopped() || p.protect.labelled(c.Config.Labels) || (!cutoff.IsZero() && !c.stoppedAt().Before(cutoff)) {
+			keep = append(keep, *c)
+			continue
+		}
+		remove = append(remove, dockerObject{
+			label:  c.label(),
+			detail: "stopped " + c.stoppedAt().Format(time.DateOnly),
+			refs:   []string{c.ID},
+		})
+	}
+	return remove, keep
+}
+
+// removableVolumes returns the unprotected volumes no remaining container mounts.
+func (p *DockerProvider) removableVolumes(volumes []dockerVolume, remaining []dockerContainer) []dockerObject {
+	mounted := make(map[string]bool)
+	for i := range remaining {
+		for _, m := range remaining[i].Mounts {
+			if m.Type == "volume" {
+				mounted[m.Name] = true
+			}
+		}
+	}
+
+	var remove []dockerObject
+	for i := range volumes {
+		v := &volumes[i]
+		if mounted[v.Name] || p.protect.volume(v) {
+			continue
+		}
+		remove = append(remove, dockerObject{label: v.Name, refs: []string{v.Name}})
+	}
+	return remove
+}
+
+// removableImages returns the unprotected images no remaining container was
+// created from. Full mode takes them all; smart mode goes least recently used
+// first, taking all unused for max_age, then more while docker usage is over
+// max_size less headroom. An image was last used when it was built, pulled, or
+// tagged, or when a container created from it last stopped.
+func (p *DockerProvider) removableImages(
+	ctx context.Context,
+	inspected []dockerImageInspect,
+	containers, remaining []dockerContainer,
+	mode CleanMode,
+) ([]dockerObject, error) {
+	used := make(map[string]bool)
+	for i := range remaining {
+		used[remaining[i].Image] = true
+	}
+	lastRun := make(map[string]time.Time)
+	for i := range containers {
+		if t := containers[i].stoppedAt(); t.After(lastRun[containers[i].Image]) {
+			lastRun[containers[i].Image] = t
+		}
+	}
+
+	var images []dockerImage
+	for i := range inspected {
+		info := &inspected[i]
+		if used[info.ID] || p.protect.image(info.RepoTags, info.Config.Labels) {
+			continue
+		}
+		lastUsed := info.Created
+		if info.Metadata.LastTagTime.After(lastUsed) {
+			lastUsed = info.Metadata.LastTagTime
+		}
+		if lastRun[info.ID].After(lastUsed) {
+			lastUsed = lastRun[info.ID]
+		}
+		images = append(images, dockerImage{lastUsed: lastUsed, id: info.ID, tags: info.RepoTags, size: info.Size})
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].lastUsed.Before(images[j].lastUsed)
+	})
+
+	var (
+		total  int64
+		cutoff time.Time
+	)
+	if mode == CleanModeSmart {
+		var err error
+		if total, err = p.CurrentSize(ctx); err != nil {
+			return nil, err
+		}
+		if p.maxAge > 0 {
+			cutoff = time.Now().Add(-p.maxAge)
+		}
+	}
+
+	var remove []dockerObject
+	target := p.sizeTarget()
+	for i := range images {
+		img := &images[i]
+		if mode == CleanModeSmart && !img.lastUsed.Before(cutoff) && total <= target {
+			break
+		}
+		remove = append(remove, dockerObject{
+			label:  img.label(),
+			detail: fmt.Sprintf("%s, last used %s", size.FormatSize(img.size), img.lastUsed.Format(time.DateOnly)),
+			refs:   img.refs(),
+			size:   img.size,
+		})
+		total -= img.size
+	}
+	return remove, nil
+}
+
+// pruneBuildCache empties the build cache in full mode. Smart mode prunes
+// cache older than max_age, then the rest down to max_size less headroom,
+// least recently used first; engines whose prune can't select by age and size are skipped.
+func (p *DockerProvider) pruneBuildCache(ctx context.Context, opts CleanOptions) ([]string, error) {
+	switch {
+	case !p.engine.buildCache:
+		return []string{fmt.Sprintf("skipping build cache: %s has none", p.engine.bin)}, nil
+	case opts.Mode == CleanModeSmart && !p.engine.keepStorage:
+		return []string{fmt.Sprintf("skipping build cache: %s can only empty it, use full clean", p.engine.bin)}, nil
+	}
+
+	if !opts.DryRun {
+		if lines, ok, err := p.apiPruneBuildCache(ctx, opts.Mode); ok {
+			return lines, err
+		}
+	}
+
+	prune := []string{p.engine.bin, "builder", "prune", "-af"}
+	cmds := [][]string{prune}
+	if opts.Mode == CleanModeSmart {
+		cmds = [][]string{
+			append(slices.Clone(prune), "--filter", p.untilFilter()),
+			append(slices.Clone(prune), "--keep-storage", strconv.FormatInt(p.sizeTarget(), 10)),
+		}
+	}
+
+	var lines []string
+	for _, args := range cmds {
+		if opts.DryRun {
+			lines = append(lines, "would run: "+strings.Join(args, " "))
+			continue
+		}
+		out, err := runCommand(ctx, args)
+		if out != "" {
+			lines = append(lines, out)
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+	return lines, nil
+}
+
+// apiPruneBuildCache prunes build cache as pruneBuildCache does, through the
+// Engine API. It reports false if the API can't be reached.
+func (p *DockerProvider) apiPruneBuildCache(ctx context.Context, mode CleanMode) (lines []string, ok bool, err error) {
+	api := p.api()
+	if api == nil || api.ping(ctx) != nil {
+		return nil, false, nil
+	}
+
+	var reports []dockerPruneReport
+	if mode == CleanModeSmart {
+		var byAge, bySize dockerPruneReport
+		if byAge, err = api.pruneBuildCache(ctx, p.pruneAge(), 0); err == nil {
+			reports = append(reports, byAge)
+			if bySize, err = api.pruneBuildCache(ctx, "", p.sizeTarget()); err == nil {
+				reports = append(reports, bySize)
+			}
+		}
+	} else {
+		var all dockerPruneReport
+		if all, err = api.pruneBuildCache(ctx, "", 0); err == nil {
+			reports = append(reports, all)
+		}
+	}
+
+	var total dockerPruneReport
+	for _, r := range reports {
+		total.deleted += r.deleted
+		total.spaceReclaimed += r.spaceReclaimed
+	}
+	if total.deleted > 0 {
+		lines = append(lines, fmt.Sprintf("pruned %d build cache records, reclaimed %s",
+			total.deleted, size.FormatSize(total.spaceReclaimed)))
+	}
+	return lines, true, err
+}
+
+// pruneImages removes every unused image with one Engine API prune, which
+// reports exactly what it freed. That matches full clean only without image
+// or label protections; otherwise, in smart mode, dry runs, or without the
+// API it reports false and images are removed one by one.
+func (p *DockerProvider) pruneImages(ctx context.Context, opts CleanOptions) (lines []string, ok bool, err error) {
+	if opts.DryRun || opts.Mode != CleanModeFull || len(p.protect.images)+len(p.protect.labels) > 0 {
+		return nil, false, nil
+	}
+	api := p.api()
+	if api == nil || api.ping(ctx) != nil {
+		return nil, false, nil
+	}
+
+	report, err := api.pruneImages(ctx)
 	if err != nil {
-		return CleanResult{}, fmt.Errorf("invalid command: %w", err)
+		return nil, true, err
+	}
+	if report.deleted > 0 {
+		lines = append(lines, fmt.Sprintf("removed %d images, reclaimed %s", report.deleted, size.FormatSize(report.spaceReclaimed)))
 	}
+	return lines, true, nil
+}
 
-	return runMeasuredClean(ctx, p.name, parts, p.CurrentSize)
+// pruneAge is the prune until value selecting data older than max_age.
+func (p *DockerProvider) pruneAge() string {
+	hours := max(int64(p.maxAge.Hours()), 1)
+	return fmt.Sprintf("%dh", hours)
+}
+
+// untilFilter is the prune filter value selecting data older than max_age.
+func (p *DockerProvider) untilFilter() string {
+	return "until=" + p.pruneAge()
+}
+
+func (p *DockerProvider) fullClean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
+	return runCleanCmd(ctx, p.name, p.cleanCmd, opts.DryRun, p.CurrentSize)
 }
@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Automaat/cache-buster/internal/config"
+)
+
+// dockerAPI is a client of the Docker Engine API on a unix socket. It reports
+// exact byte counts where the CLI prints rounded sizes.
+type dockerAPI struct {
+	client *http.Client
+}
+
+// newDockerAPI returns a client for host, or nil unless host is a unix socket.
+func newDockerAPI(host string) *dockerAPI {
+	socket, ok := strings.CutPrefix(host, "unix://")
+	if !ok || socket == "" {
+		return nil
+	}
+	var dialer net.Dialer
+	return &dockerAPI{client: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+		DisableKeepAlives: true,
+	}}}
+}
+
+// do sends a request and decodes a JSON response into out, if non-nil.
+func (a *dockerAPI) do(ctx context.Context, method, path string, query url.Values, out any) error {
+	u := url.URL{Scheme: "http", Host: "docker", Path: path, RawQuery: query.Encode()}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("docker api %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(body, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(body))
+		}
+		return fmt.Errorf("docker api %s: %s: %s", path, resp.Status, apiErr.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode docker api %s: %w", path, err)
+	}
+	return nil
+}
+
+func (a *dockerAPI) ping(ctx context.Context) error {
+	return a.do(ctx, http.MethodGet, "/_ping", nil, nil)
+}
+
+// dockerAPIDF is the part of the /system/df response read here.
+type dockerAPIDF struct {
+	Images []struct {
+		Size       int64 `json:"Size"`
+		SharedSize int64 `json:"SharedSize"`
+		Containers int64 `json:"Containers"`
+	} `json:"Images"`
+	Containers []struct {
+		State  string `json:"State"`
+		SizeRw int64  `json:"SizeRw"`
+	} `json:"Containers"`
+	Volumes []struct {
+		UsageData struct {
+			Size     int64 `json:"Size"`
+			RefCount int64 `json:"RefCount"`
+		} `json:"UsageData"`
+	} `json:"Volumes"`
+	BuildCache []struct {
+		Size   int64 `json:"Size"`
+		InUse  bool  `json:"InUse"`
+		Shared bool  `json:"Shared"`
+	} `json:"BuildCache"`
+	LayersSize int64 `json:"LayersSize"`
+}
+
+// systemDF returns usage by category as docker system df computes it:
+// images share layers, so their total is the layer store and only layers of
+// images no container uses are reclaimable.
+func (a *dockerAPI) systemDF(ctx context.Context) ([]UsageCategory, error) {
+	var df dockerAPIDF
+	if err := a.do(ctx, http.MethodGet, "/system/df", nil, &df); err != nil {
+		return nil, err
+	}
+
+	images := UsageCategory{Name: config.CategoryImages, Size: df.LayersSize, Reclaimable: df.LayersSize}
+	for _, img := range df.Images {
+		if img.Containers > 0 && img.SharedSize >= 0 {
+			images.Reclaimable -= img.Size - img.SharedSize
+		}
+	}
+	images.Reclaimable = max(images.Reclaimable, 0)
+
+	containers := UsageCategory{Name: config.CategoryContainers}
+	for _, c := range df.Containers {
+		containers.Size += c.SizeRw
+		if c.State != "running" {
+			containers.Reclaimable += c.SizeRw
+		}
+	}
+
+	volumes := UsageCategory{Name: config.CategoryVolumes}
+	for _, v := range df.Volumes {
+		if v.UsageData.Size < 0 {
+			continue // size not computed for this volume driver
+		}
+		volumes.Size += v.UsageData.Size
+		if v.UsageData.RefCount == 0 {
+			volumes.Reclaimable += v.UsageData.Size
+		}
+	}
+
+	buildCache := UsageCategory{Name: config.CategoryBuildCache}
+	for _, r := range df.BuildCache {
+		if r.Shared {
+			continue // counted under images
+		}
+		buildCache.Size += r.Size
+		if !r.InUse {
+			buildCache.Reclaimable += r.Size
+		}
+	}
+
+	return []UsageCategory{images, containers, volumes, buildCache}, nil
+}
+
+// dockerPruneReport is what a prune endpoint removed.
+type dockerPruneReport struct {
+	deleted        int
+	spaceReclaimed int64
+}
+
+// pruneImages removes every image no container uses, like docker image prune -a.
+func (a *dockerAPI) pruneImages(ctx context.Context) (dockerPruneReport, error) {
+	var resp struct {
+		ImagesDeleted []struct {
+			Deleted string `json:"Deleted"`
+		} `json:"ImagesDeleted"`
+		SpaceReclaimed int64 `json:"SpaceReclaimed"`
+	}
+	query := url.Values{"filters": {`{"dangling":["false"]}`}}
+	if err := a.do(ctx, http.MethodPost, "/images/prune", query, &resp); err != nil {
+		return dockerPruneReport{}, err
+	}
+
+	// Untagging entries are listed alongside deletions; count images only.
+	report := dockerPruneReport{spaceReclaimed: resp.SpaceReclaimed}
+	for _, d := range resp.ImagesDeleted {
+		if d.Deleted != "" {
+			report.deleted++
+		}
+	}
+	return report, nil
+}
+
+// pruneBuildCache removes all unused build cache, or with until set only
+// records older than it (e.g. "720h"), or with keepStorage set only down to
+// that many bytes.
+func (a *dockerAPI) pruneBuildCache(ctx context.Context, until string, keepStorage int64) (dockerPruneReport, error) {
+	query := url.Values{"all": {"1"}}
+	if until != "" {
+		filters, err := json.Marshal(map[string][]string{"until": {until}})
+		if err != nil {
+			return dockerPruneReport{}, err
+		}
+		query.Set("filters", string(filters))
+	}
+	if keepStorage > 0 {
+		query.Set("keep-storage", strconv.FormatInt(keepStorage, 10))
+	}
+
+	var resp struct {
+		CachesDeleted  []string `json:"CachesDeleted"`
+		SpaceReclaimed int64    `json:"SpaceReclaimed"`
+	}
+	if err := a.do(ctx, http.MethodPost, "/build/prune", query, &resp); err != nil {
+		return dockerPruneReport{}, err
+	}
+	return dockerPruneReport{deleted: len(resp.CachesDeleted), spaceReclaimed: resp.SpaceReclaimed}, nil
+}
@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubDockerAPI serves handlers on a unix socket, points DOCKER_HOST at it,
+// and returns a func listing the requests received as "METHOD /path?query".
+func stubDockerAPI(t *testing.T, handlers map[string]string) func() []string {
+	t.Helper()
+	// Unix socket paths are limited to ~100 bytes, too short for t.TempDir.
+	dir, err := os.MkdirTemp("", "dockerapi")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "docker.sock")
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, strings.TrimSuffix(r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery, "?"))
+		mu.Unlock()
+
+		body, ok := handlers[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"page not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	srv.Listener = listener
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	t.Setenv("DOCKER_HOST", "unix://"+socket)
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+const stubSystemDF = `{
+	"LayersSize": 3000000123,
+	"Images": [
+		{"Size": 2000000000, "SharedSize": 500000000, "Containers": 1},
+		{"Size": 1000000123, "SharedSize": 500000000, "Containers": 0}
+	],
+	"Containers": [
+		{"State": "running", "SizeRw": 100},
+		{"State": "exited", "SizeRw": 23}
+	],
+	"Volumes": [
+		{"UsageData": {"Size": 4096, "RefCount": 1}},
+		{"UsageData": {"Size": 1024, "RefCount": 0}},
+		{"UsageData": {"Size": -1, "RefCount": -1}}
+	],
+	"BuildCache": [
+		{"Size": 700, "InUse": false, "Shared": false},
+		{"Size": 300, "InUse": true, "Shared": false},
+		{"Size": 999, "InUse": false, "Shared": true}
+	]
+}`
+
+func TestDockerAPI_Breakdown(t *testing.T) {
+	// The CLI would fail; sizes must come from the API.
+	fakeDockerBin(t, `exit 1`)
+	stubDockerAPI(t, map[string]string{"/system/df": stubSystemDF})
+
+	p := newTestDockerProvider(t, []string{t.TempDir()})
+	categories, err := p.Breakdown(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []UsageCategory{
+		{Name: config.CategoryImages, Size: 3000000123, Reclaimable: 1500000123},
+		{Name: config.CategoryContainers, Size: 123, Reclaimable: 23},
+		{Name: config.CategoryVolumes, Size: 5120, Reclaimable: 1024},
+		{Name: config.CategoryBuildCache, Size: 1000, Reclaimable: 700},
+	}, categories)
+
+	total, err := p.CurrentSize(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3000000123+123+5120+1000), total)
+}
+
+func TestDockerAPI_FallsBackToCLI(t *testing.T) {
+	fakeDockerBin(t, `echo '{"Type":"Images","Size":"1.5GB"}'`)
+	t.Setenv("DOCKER_HOST", "unix://"+filepath.Join(t.TempDir(), "missing.sock"))
+
+	p := newTestDockerProvider(t, []string{t.TempDir()})
+	size, err := p.CurrentSize(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1.5*1024*1024*1024), size)
+}
+
+func TestDockerAPI_FullCleanPrunes(t *testing.T) {
+	log := fakeDockerEngine(t, standardDockerFixture(), "")
+	requests := stubDockerAPI(t, map[string]string{
+		"/_ping":        "OK",
+		"/system/df":    stubSystemDF,
+		"/images/prune": `{"ImagesDeleted":[{"Untagged":"job:1"},{"Deleted":"sha256:job"},{"Deleted":"sha256:layer"}],"SpaceReclaimed":1073741824}`,
+		"/build/prune":  `{"CachesDeleted":["a","b"],"SpaceReclaimed":2048}`,
+	})
+	p, err := NewDockerProvider("docker", config.Provider{
+		Paths:      []string{t.TempDir()},
+		MaxSize:    "50G",
+		Categories: []string{config.CategoryBuildCache, config.CategoryImages},
+	})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Equal(t, "pruned 2 build cache records, reclaimed 2.0 KiB\nremoved 2 images, reclaimed 1.0 GiB", result.Output)
+	assert.Contains(t, requests(), "POST /build/prune?all=1")
+	assert.Contains(t, requests(), `POST /images/prune?filters=%7B%22dangling%22%3A%5B%22false%22%5D%7D`)
+	assert.Empty(t, dockerCalls(t, log, "builder prune", "image rm"))
+}
+
+func TestDockerAPI_SmartBuildCachePrune(t *testing.T) {
+	fakeDockerEngine(t, standardDockerFixture(), "")
+	requests := stubDockerAPI(t, map[string]string{
+		"/_ping":       "OK",
+		"/system/df":   stubSystemDF,
+		"/build/prune": `{"CachesDeleted":["a"],"SpaceReclaimed":1024}`,
+	})
+	p, err := NewDockerProvider("docker", config.Provider{
+		Paths:      []string{t.TempDir()},
+		MaxSize:    "1G",
+		MaxAge:     "7d",
+		Categories: []string{config.CategoryBuildCache},
+	})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.Equal(t, "pruned 2 build cache records, reclaimed 2.0 KiB", result.Output)
+	assert.Equal(t, []string{
+		"POST /build/prune?all=1&filters=%7B%22until%22%3A%5B%22168h%22%5D%7D",
+		"POST /build/prune?all=1&keep-storage=966367641", // 90% of 1G
+	}, filterRequests(requests(), "/build/prune"))
+}
+
+func TestDockerAPI_PruneError(t *testing.T) {
+	fakeDockerEngine(t, standardDockerFixture(), "")
+	stubDockerAPI(t, map[string]string{"/_ping": "OK", "/system/df": stubSystemDF})
+
+	p, err := NewDockerProvider("docker", config.Provider{
+		Paths:      []string{t.TempDir()},
+		MaxSize:    "50G",
+		Categories: []string{config.CategoryBuildCache},
+	})
+	require.NoError(t, err)
+
+	_, err = p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.ErrorContains(t, err, "404 Not Found: page not found")
+}
+
+// filterRequests keeps the requests for path.
+func filterRequests(requests []string, path string) []string {
+	var kept []string
+	for _, r := range requests {
+		_, target, _ := strings.Cut(r, " ")
+		if p, _, _ := strings.Cut(target, "?"); p == path {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,237 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// dockerContainer is the part of docker container inspect output read here.
+type dockerContainer struct {
+	Created time.Time `json:"Created"`
+	State   struct {
+		FinishedAt time.Time `json:"FinishedAt"`
+		Status     string    `json:"Status"`
+	} `json:"State"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Image  string `json:"Image"` // image ID
+	Mounts []struct {
+		Type string `json:"Type"`
+		Name string `json:"Name"`
+	} `json:"Mounts"`
+}
+
+// stopped reports whether the container is not running and can be removed.
+func (c *dockerContainer) stopped() bool {
+	switch c.State.Status {
+	case "exited", "created", "dead":
+		return true
+	default:
+		return false
+	}
+}
+
+// stoppedAt is when the container last stopped, or its creation time if it never ran.
+func (c *dockerContainer) stoppedAt() time.Time {
+	if c.State.FinishedAt.After(c.Created) {
+		return c.State.FinishedAt
+	}
+	return c.Created
+}
+
+func (c *dockerContainer) label() string {
+	return strings.TrimPrefix(c.Name, "/")
+}
+
+// dockerImageInspect is the part of docker image inspect output read here.
+type dockerImageInspect struct {
+	Created  time.Time `json:"Created"`
+	Metadata struct {
+		LastTagTime time.Time `json:"LastTagTime"`
+	} `json:"Metadata"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	ID       string   `json:"Id"`
+	RepoTags []string `json:"RepoTags"`
+	Size     int64    `json:"Size"`
+}
+
+// dockerImage is a top-level image no remaining container uses.
+type dockerImage struct {
+	lastUsed time.Time // newest of creation and last tag, the best docker records
+	id       string
+	tags     []string
+	size     int64
+}
+
+// label names the image by its first tag, or a short ID when untagged.
+func (img *dockerImage) label() string {
+	if len(img.tags) > 0 {
+		return img.tags[0]
+	}
+	id := strings.TrimPrefix(img.id, "sha256:")
+	return id[:min(12, len(id))]
+}
+
+// refs are the arguments removing the image: every tag, since removing one
+// of several tags only untags, or the ID when untagged.
+func (img *dockerImage) refs() []string {
+	if len(img.tags) > 0 {
+		return img.tags
+	}
+	return []string{img.id}
+}
+
+// dockerVolume is the part of docker volume inspect output read here.
+type dockerVolume struct {
+	Labels map[string]string `json:"Labels"`
+	Name   string            `json:"Name"`
+}
+
+// dockerProtection holds the objects a docker clean must never remove.
+type dockerProtection struct {
+	images  []string // doublestar patterns matched against repo:tag and repo
+	volumes []string // doublestar patterns matched against volume names
+	labels  []string // "key" or "key=value"
+}
+
+// labelled reports whether labels carry a protected label.
+func (dp *dockerProtection) labelled(labels map[string]string) bool {
+	for _, protected := range dp.labels {
+		key, value, hasValue := strings.Cut(protected, "=")
+		if got, ok := labels[key]; ok && (!hasValue || got == value) {
+			return true
+		}
+	}
+	return false
+}
+
+// image reports whether an image with these tags and labels is protected.
+func (dp *dockerProtection) image(tags []string, labels map[string]string) bool {
+	if dp.labelled(labels) {
+		return true
+	}
+	for _, tag := range tags {
+		repo := tag
+		if i := strings.LastIndex(tag, ":"); i > strings.LastIndex(tag, "/") {
+			repo = tag[:i]
+		}
+		for _, pattern := range dp.images {
+			if matchName(pattern, tag) || matchName(pattern, repo) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// volume reports whether a volume is protected.
+func (dp *dockerProtection) volume(v *dockerVolume) bool {
+	if dp.labelled(v.Labels) {
+		return true
+	}
+	return slices.ContainsFunc(dp.volumes, func(pattern string) bool {
+		return matchName(pattern, v.Name)
+	})
+}
+
+func matchName(pattern, name string) bool {
+	ok, err := doublestar.Match(pattern, name)
+	return err == nil && ok
+}
+
+// dockerInventory is the docker state a clean plans from.
+type dockerInventory struct {
+	containers []dockerContainer
+	images     []dockerImageInspect
+	volumes    []dockerVolume
+}
+
+// loadInventory lists every container and top-level image of engine, and
+// every volume if withVolumes is set.
+func loadInventory(ctx context.Context, engine *containerEngine, withVolumes bool) (*dockerInventory, error) {
+	var inv dockerInventory
+
+	ids, err := dockerLines(ctx, engine, "container", "ls", "--all", "--quiet", "--no-trunc")
+	if err != nil {
+		return nil, err
+	}
+	if err := dockerInspect(ctx, engine, "container", ids, &inv.containers); err != nil {
+		return nil, err
+	}
+
+	ids, err = dockerLines(ctx, engine, "image", "ls", "--quiet", "--no-trunc")
+	if err != nil {
+		return nil, err
+	}
+	// An image is listed once per tag.
+	slices.Sort(ids)
+	if err := dockerInspect(ctx, engine, "image", slices.Compact(ids), &inv.images); err != nil {
+		return nil, err
+	}
+
+	if withVolumes {
+		names, err := dockerLines(ctx, engine, "volume", "ls", "--quiet")
+		if err != nil {
+			return nil, err
+		}
+		if err := dockerInspect(ctx, engine, "volume", names, &inv.volumes); err != nil {
+			return nil, err
+		}
+	}
+
+	return &inv, nil
+}
+
+// dockerInspect decodes <engine> <kind> inspect output for ids into out, one
+// JSON object per line.
+func dockerInspect[T any](ctx context.Context, engine *containerEngine, kind string, ids []string, out *[]T) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	lines, err := dockerLines(ctx, engine, append([]string{kind, "inspect", "--format", "{{json .}}"}, ids...)...)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		var v T
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			return fmt.Errorf("unmarshal %s %s inspect: %w", engine.bin, kind, err)
+		}
+		*out = append(*out, v)
+	}
+	return nil
+}
+
+// dockerLines runs engine with args and returns the non-empty lines of its stdout.
+func dockerLines(ctx context.Context, engine *containerEngine, args ...string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, engine.bin, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s %s: %w: %s", engine.bin, strings.Join(args[:2], " "), err, msg)
+		}
+		return nil, fmt.Errorf("%s %s: %w", engine.bin, strings.Join(args[:2], " "), err)
+	}
+
+	var lines []string
+	for line := range strings.SplitSeq(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
@@ -1,9 +1,14 @@
 package provider
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Automaat/cache-buster/internal/config"
 	"github.com/stretchr/testify/assert"
@@ -22,11 +27,22 @@ func newTestDockerProvider(t *testing.T, paths []string) *DockerProvider {
 }
 
 func fakeDockerBin(t *testing.T, script string) string {
+	t.Helper()
+	return fakeEngineBin(t, "docker", script)
+}
+
+// fakeEngineBin installs a fake container CLI named bin running script, and
+// points DOCKER_HOST at a socket path that exists so Available asks the CLI.
+func fakeEngineBin(t *testing.T, bin, script string) string {
 	t.Helper()
 	dir := t.TempDir()
-	bin := filepath.Join(dir, "docker")
-	require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\n"+script), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, bin), []byte("#!/bin/sh\n"+script), 0o755))
 	t.Setenv("PATH", dir+":"+os.Getenv("PATH"))
+
+	socket := filepath.Join(dir, "docker.sock")
+	require.NoError(t, os.WriteFile(socket, nil, 0o600))
+	t.Setenv("DOCKER_HOST", "unix://"+socket)
+	t.Setenv("CONTAINER_HOST", "")
 	return dir
 }
 
@@ -106,18 +122,6 @@ func TestDockerCurrentSize_FallsBackToPathBased(t *testing.T) {
 	assert.Equal(t, int64(5), size)
 }
 
-func TestDockerSmartCleanDryRun_IncludesVolumes(t *testing.T) {
-	fakeDockerBin(t, `exit 0`)
-
-	p := newTestDockerProvider(t, []string{t.TempDir()})
-	result, err := p.Clean(t.Context(), CleanOptions{
-		Mode:   CleanModeSmart,
-		DryRun: true,
-	})
-	require.NoError(t, err)
-	assert.Equal(t, "would run: docker system prune -af --volumes --filter until=720h", result.Output)
-}
-
 func TestDockerSmartClean_DaemonUnavailable(t *testing.T) {
 	// docker ps fails => daemon down => Clean returns without pruning.
 	fakeDockerBin(t, `case "$1 $2" in
@@ -136,8 +140,9 @@ func TestDockerSmartClean_NothingToPrune(t *testing.T) {
 	fakeDockerBin(t, `case "$1 $2" in
 "ps --quiet") exit 0 ;;
 "system df") echo '{"Size":"2GB"}'; exit 0 ;;
-"system prune") echo "Total reclaimed space: 0B"; exit 0 ;;
-esac`)
+"image ls"|"container ls") exit 0 ;;
+esac
+echo "Total reclaimed space: 0B"`)
 
 	p := newTestDockerProvider(t, []string{t.TempDir()})
 	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
@@ -147,15 +152,15 @@ esac`)
 }
 
 func TestDockerSmartClean_FreesSpace(t *testing.T) {
-	// docker system df reports 5GB before the prune and 1GB after it,
-	// keyed off a marker file the fake prune drops.
+	// docker system df reports 5GB before the build cache prune and 1GB
+	// after it, keyed off a marker file the fake prune drops.
 	marker := filepath.Join(t.TempDir(), "pruned")
 	fakeDockerBin(t, `case "$1 $2" in
 "ps --quiet") exit 0 ;;
 "system df")
   if [ -f "`+marker+`" ]; then echo '{"Size":"1GB"}'; else echo '{"Size":"5GB"}'; fi
   exit 0 ;;
-"system prune")
+"builder prune")
   touch "`+marker+`"
   echo "Total reclaimed space: 4GB"
   exit 0 ;;
@@ -172,7 +177,7 @@ func TestDockerSmartClean_PruneFails(t *testing.T) {
 	fakeDockerBin(t, `case "$1 $2" in
 "ps --quiet") exit 0 ;;
 "system df") echo '{"Size":"2GB"}'; exit 0 ;;
-"system prune") echo "Error response from daemon: prune failed" >&2; exit 1 ;;
+"builder prune") echo "Error response from daemon: prune failed" >&2; exit 1 ;;
 esac`)
 
 	p := newTestDockerProvider(t, []string{t.TempDir()})
@@ -180,3 +185,372 @@ esac`)
 	require.Error(t, err)
 	assert.Contains(t, result.Output, "prune failed")
 }
+
+func TestDockerBreakdown_Categories(t *testing.T) {
+	fakeDockerBin(t, `echo '{"Type":"Images","TotalCount":"5","Size":"2GB","Reclaimable":"1.5GB (75%)"}'
+echo '{"Type":"Containers","TotalCount":"2","Size":"10MB","Reclaimable":"0B (0%)"}'
+echo '{"Type":"Local Volumes","TotalCount":"1","Size":"1GB","Reclaimable":"1GB (100%)"}'
+echo '{"Type":"Build Cache","TotalCount":"8","Size":"750MB","Reclaimable":"750MB"}'
+`)
+
+	p := newTestDockerProvider(t, []string{t.TempDir()})
+	categories, err := p.Breakdown(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []UsageCategory{
+		{Name: config.CategoryImages, Size: 2 * 1024 * 1024 * 1024, Reclaimable: 1.5 * 1024 * 1024 * 1024},
+		{Name: config.CategoryContainers, Size: 10 * 1024 * 1024},
+		{Name: config.CategoryVolumes, Size: 1024 * 1024 * 1024, Reclaimable: 1024 * 1024 * 1024},
+		{Name: config.CategoryBuildCache, Size: 750 * 1024 * 1024, Reclaimable: 750 * 1024 * 1024},
+	}, categories)
+}
+
+// dockerFixture is the state a fake docker engine reports: one JSON object
+// per line for each kind's inspect output.
+type dockerFixture struct {
+	df         string
+	containers []string
+	images     []string
+	volumes    []string
+}
+
+// fakeDockerEngine installs a fake docker serving fixture and logging every
+// call to the returned file. Removals and prunes succeed unless failRm names
+// a reference whose removal should fail.
+func fakeDockerEngine(t *testing.T, fixture dockerFixture, failRm string) string {
+	t.Helper()
+	return fakeEngine(t, "docker", fixture, failRm)
+}
+
+// fakeEngine is fakeDockerEngine for the container CLI named bin.
+func fakeEngine(t *testing.T, bin string, fixture dockerFixture, failRm string) string {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	write := func(name string, lines []string) {
+		content := strings.Join(lines, "\n")
+		if content != "" {
+			content += "\n"
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	ids := func(lines []string) []string {
+		return slices.Repeat([]string{"id"}, len(lines))
+	}
+	write("df", []string{fixture.df})
+	write("container-ls", ids(fixture.containers))
+	write("container-inspect", fixture.containers)
+	write("image-ls", ids(fixture.images))
+	write("image-inspect", fixture.images)
+	write("volume-ls", ids(fixture.volumes))
+	write("volume-inspect", fixture.volumes)
+
+	fakeEngineBin(t, bin, `echo "$@" >> "`+log+`"
+case "$1 $2" in
+"ps --quiet") exit 0 ;;
+"system df") cat "`+dir+`/df" ;;
+"container ls"|"container inspect"|"image ls"|"image inspect"|"volume ls"|"volume inspect") cat "`+dir+`/$1-$2" ;;
+*" rm")
+  for ref in "$@"; do
+    if [ "$ref" = "`+failRm+`" ]; then echo "Error: conflict: $ref is in use" >&2; exit 1; fi
+  done ;;
+esac
+exit 0`)
+	return log
+}
+
+// dockerCalls returns the logged calls starting with one of prefixes.
+func dockerCalls(t *testing.T, log string, prefixes ...string) []string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	var calls []string
+	for line := range strings.SplitSeq(strings.TrimSpace(string(data)), "\n") {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(line, prefix) {
+				calls = append(calls, line)
+			}
+		}
+	}
+	return calls
+}
+
+func daysAgo(days int) time.Time {
+	return time.Now().Add(-time.Duration(days) * 24 * time.Hour).UTC()
+}
+
+// standardDockerFixture holds a running web container, two stopped jobs, and
+// a protected database container, with their images and volumes, plus
+// unused images and volumes, some of them protected.
+func standardDockerFixture() dockerFixture {
+	ts := func(days int) string { return daysAgo(days).Format(time.RFC3339Nano) }
+	return dockerFixture{
+		df: `{"Type":"Images","Size":"5GB"}`,
+		containers: []string{
+			`{"Id":"c-web","Name":"/web","Image":"sha256:web","Created":"` + ts(10) + `","State":{"Status":"running"},"Mounts":[{"Type":"volume","Name":"webdata"}]}`,
+			`{"Id":"c-old","Name":"/old-job","Image":"sha256:job","Created":"` + ts(61) + `","State":{"Status":"exited","FinishedAt":"` + ts(60) + `"},"Mounts":[{"Type":"volume","Name":"jobcache"}]}`,
+			`{"Id":"c-new","Name":"/new-job","Image":"sha256:tool","Created":"` + ts(2) + `","State":{"Status":"exited","FinishedAt":"` + ts(1) + `"}}`,
+			`{"Id":"c-pg","Name":"/pg","Image":"sha256:pg","Created":"` + ts(100) + `","State":{"Status":"exited","FinishedAt":"` + ts(90) + `"},"Config":{"Labels":{"keep":"true"}},"Mounts":[{"Type":"volume","Name":"pgdata"}]}`,
+		},
+		images: []string{
+			`{"Id":"sha256:web","RepoTags":["web:1"],"Size":1073741824,"Created":"` + ts(10) + `"}`,
+			`{"Id":"sha256:job","RepoTags":["job:1"],"Size":1073741824,"Created":"` + ts(90) + `"}`,
+			`{"Id":"sha256:tool","RepoTags":["tool:2"],"Size":1073741824,"Created":"` + ts(5) + `"}`,
+			`{"Id":"sha256:pg","RepoTags":["postgres:16"],"Size":1073741824,"Created":"` + ts(100) + `"}`,
+			`{"Id":"sha256:0123456789abcdef","RepoTags":[],"Size":1073741824,"Created":"` + ts(200) + `"}`,
+			`{"Id":"sha256:redis","RepoTags":["redis:7"],"Size":1073741824,"Created":"` + ts(100) + `"}`,
+			`{"Id":"sha256:base","RepoTags":["base:1"],"Size":1073741824,"Created":"` + ts(100) + `","Config":{"Labels":{"keep":"true"}}}`,
+		},
+		volumes: []string{
+			`{"Name":"webdata"}`,
+			`{"Name":"jobcache"}`,
+			`{"Name":"pgdata"}`,
+			`{"Name":"scratch"}`,
+			`{"Name":"dbvol"}`,
+			`{"Name":"notes","Labels":{"keep":"true"}}`,
+		},
+	}
+}
+
+func newProtectedDockerProvider(t *testing.T, categories []string) *DockerProvider {
+	t.Helper()
+	p, err := NewDockerProvider("docker", config.Provider{
+		Paths:          []string{t.TempDir()},
+		MaxSize:        "50G",
+		MaxAge:         "30d",
+		Categories:     categories,
+		ProtectImages:  []string{"redis"},
+		ProtectVolumes: []string{"db*"},
+		ProtectLabels:  []string{"keep=true"},
+	})
+	require.NoError(t, err)
+	return p
+}
+
+var allDockerCategories = []string{config.CategoryContainers, config.CategoryVolumes, config.CategoryBuildCache, config.CategoryImages}
+
+func TestDockerFullCleanDryRun_ListsObjects(t *testing.T) {
+	fakeDockerEngine(t, standardDockerFixture(), "")
+	p := newProtectedDockerProvider(t, allDockerCategories)
+
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true})
+	require.NoError(t, err)
+
+	date := func(days int) string { return daysAgo(days).Format(time.DateOnly) }
+	assert.Equal(t, strings.Join([]string{
+		"would remove container: old-job (stopped " + date(60) + ")",
+		"would remove container: new-job (stopped " + date(1) + ")",
+		"would remove volume: jobcache",
+		"would remove volume: scratch",
+		"would run: docker builder prune -af",
+		"would remove image: 0123456789ab (1.0 GiB, last used " + date(200) + ")",
+		"would remove image: job:1 (1.0 GiB, last used " + date(60) + ")",
+		"would remove image: tool:2 (1.0 GiB, last used " + date(1) + ")",
+	}, "\n"), result.Output)
+	assert.Equal(t, int64(3*1024*1024*1024), result.BytesCleaned)
+}
+
+func TestDockerFullClean_RemovesObjects(t *testing.T) {
+	log := fakeDockerEngine(t, standardDockerFixture(), "")
+	p := newProtectedDockerProvider(t, allDockerCategories)
+
+	result, err := p.Clean(t.Context(), CleanOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "removed 2 containers\nremoved 2 volumes\nremoved 3 images", result.Output)
+	assert.Equal(t, []string{
+		"container rm c-old",
+		"container rm c-new",
+		"volume rm jobcache",
+		"volume rm scratch",
+		"builder prune -af",
+		"image rm sha256:0123456789abcdef",
+		"image rm job:1",
+		"image rm tool:2",
+	}, dockerCalls(t, log, "container rm", "volume rm", "builder prune", "image rm"))
+}
+
+func TestDockerFullClean_ReportsFailedRemoval(t *testing.T) {
+	fakeDockerEngine(t, standardDockerFixture(), "job:1")
+	p := newProtectedDockerProvider(t, allDockerCategories)
+
+	result, err := p.Clean(t.Context(), CleanOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "error removing image job:1: exit status 1: Error: conflict: job:1 is in use")
+	assert.Contains(t, result.Output, "removed 2 images")
+}
+
+func TestDockerSmartCleanDryRun_SkipsVolumes(t *testing.T) {
+	log := fakeDockerEngine(t, standardDockerFixture(), "")
+	p := newProtectedDockerProvider(t, nil)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart, DryRun: true})
+	require.NoError(t, err)
+
+	// new-job stopped within max_age, so it stays and keeps tool:2 in use;
+	// job:1 was last used when old-job stopped. Build cache is kept to 90% of 50G.
+	date := func(days int) string { return daysAgo(days).Format(time.DateOnly) }
+	assert.Equal(t, strings.Join([]string{
+		"would remove container: old-job (stopped " + date(60) + ")",
+		"would run: docker builder prune -af --filter until=720h",
+		"would run: docker builder prune -af --keep-storage 48318382080",
+		"would remove image: 0123456789ab (1.0 GiB, last used " + date(200) + ")",
+		"would remove image: job:1 (1.0 GiB, last used " + date(60) + ")",
+	}, "\n"), result.Output)
+	assert.Empty(t, dockerCalls(t, log, "volume"))
+}
+
+func TestDockerSmartClean_ImagesOverLimit(t *testing.T) {
+	log := fakeDockerEngine(t, standardDockerFixture(), "")
+	p, err := NewDockerProvider("docker", config.Provider{
+		Paths:      []string{t.TempDir()},
+		MaxSize:    "2G",
+		MaxAge:     "30d",
+		Categories: []string{config.CategoryImages},
+	})
+	require.NoError(t, err)
+
+	// Without container cleanup only unused images go: the untagged one and
+	// redis are stale; base, over the 2G limit still, goes least recently used.
+	_, err = p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"image rm sha256:0123456789abcdef",
+		"image rm redis:7",
+		"image rm base:1",
+	}, dockerCalls(t, log, "image rm"))
+}
+
+func TestDockerCleanCmd(t *testing.T) {
+	fakeDockerBin(t, `exit 0`)
+
+	p := newTestDockerProvider(t, []string{t.TempDir()})
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "would run: echo clean", result.Output)
+
+	_, err = NewDockerProvider("docker", config.Provider{
+		Paths:         []string{t.TempDir()},
+		MaxSize:       "10G",
+		CleanCmd:      "docker system prune -af",
+		ProtectLabels: []string{"keep"},
+	})
+	require.ErrorContains(t, err, "clean_cmd would bypass")
+}
+
+func TestDockerLegacyCleanCmd_Ignored(t *testing.T) {
+	log := fakeDockerEngine(t, standardDockerFixture(), "")
+	p, err := NewDockerProvider("docker", config.Provider{
+		Paths:         []string{t.TempDir()},
+		MaxSize:       "10G",
+		CleanCmd:      "docker system prune -af --volumes",
+		ProtectLabels: []string{"keep"},
+	})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true})
+	require.NoError(t, err)
+	lines := strings.Split(result.Output, "\n")
+	assert.Equal(t, `warning: ignoring clean_cmd "docker system prune -af --volumes", the old default that also deletes volumes; remove it from the config`, lines[0])
+	assert.Contains(t, lines, "would remove container: old-job (stopped "+daysAgo(60).Format(time.DateOnly)+")")
+	assert.Empty(t, dockerCalls(t, log, "system prune"))
+}
+
+func TestDockerProtection(t *testing.T) {
+	dp := dockerProtection{
+		images:  []string{"redis", "ghcr.io/acme/**", "localhost:5000/app:1.*"},
+		volumes: []string{"*-db"},
+		labels:  []string{"keep", "env=prod"},
+	}
+
+	assert.True(t, dp.image([]string{"redis:7"}, nil))
+	assert.True(t, dp.image([]string{"ghcr.io/acme/api/web:2"}, nil))
+	assert.True(t, dp.image([]string{"localhost:5000/app:1.4"}, nil))
+	assert.False(t, dp.image([]string{"localhost:5000/app:2.0"}, nil))
+	assert.False(t, dp.image([]string{"redis-exporter:1"}, nil))
+	assert.True(t, dp.image(nil, map[string]string{"keep": ""}))
+	assert.True(t, dp.image(nil, map[string]string{"env": "prod"}))
+	assert.False(t, dp.image(nil, map[string]string{"env": "dev"}))
+
+	assert.True(t, dp.volume(&dockerVolume{Name: "orders-db"}))
+	assert.False(t, dp.volume(&dockerVolume{Name: "scratch"}))
+}
+
+func TestDockerEngine_Podman(t *testing.T) {
+	log := fakeEngine(t, "podman", standardDockerFixture(), "")
+	p, err := NewDockerProvider("podman", config.Provider{
+		Paths:   []string{t.TempDir()},
+		MaxSize: "50G",
+		Engine:  config.EnginePodman,
+	})
+	require.NoError(t, err)
+	require.True(t, p.Available())
+
+	size, err := p.CurrentSize(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, int64(5*1024*1024*1024), size)
+
+	// Podman has no build cache, so the default categories skip it.
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Equal(t, "removed 3 containers\nremoved 6 images", result.Output)
+	assert.Empty(t, dockerCalls(t, log, "builder prune"))
+
+	p.categories = []string{config.CategoryBuildCache}
+	result, err = p.Clean(t.Context(), CleanOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "skipping build cache: podman has none", result.Output)
+}
+
+func TestDockerEngine_NerdctlBuildCache(t *testing.T) {
+	fakeEngine(t, "nerdctl", standardDockerFixture(), "")
+	p, err := NewDockerProvider("docker", config.Provider{
+		Paths:      []string{t.TempDir()},
+		MaxSize:    "50G",
+		Engine:     config.EngineNerdctl,
+		Categories: []string{config.CategoryBuildCache},
+	})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true, Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Equal(t, "would run: nerdctl builder prune -af", result.Output)
+
+	result, err = p.Clean(t.Context(), CleanOptions{DryRun: true, Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.Equal(t, "skipping build cache: nerdctl can only empty it, use full clean", result.Output)
+}
+
+func TestDockerAvailable_HonorsHost(t *testing.T) {
+	fakeDockerBin(t, `exit 0`)
+	p := newTestDockerProvider(t, []string{t.TempDir()})
+	assert.True(t, p.Available())
+
+	t.Setenv("DOCKER_HOST", "unix://"+filepath.Join(t.TempDir(), "missing.sock"))
+	assert.False(t, p.Available())
+
+	// A remote daemon is left to the CLI.
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:2375")
+	assert.True(t, p.Available())
+}
+
+func TestDockerContextHost(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("DOCKER_CONTEXT", "")
+	t.Setenv("DOCKER_HOST", "")
+	engine := containerEngines[config.EngineDocker]
+	assert.Equal(t, "unix:///var/run/docker.sock", engine.host())
+
+	sum := sha256.Sum256([]byte("colima"))
+	meta := filepath.Join(dir, "contexts", "meta", hex.EncodeToString(sum[:]))
+	require.NoError(t, os.MkdirAll(meta, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(meta, "meta.json"),
+		[]byte(`{"Name":"colima","Endpoints":{"docker":{"Host":"unix:///home/me/.colima/docker.sock"}}}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"currentContext":"colima"}`), 0o600))
+	assert.Equal(t, "unix:///home/me/.colima/docker.sock", engine.host())
+
+	t.Setenv("DOCKER_CONTEXT", "default")
+	assert.Equal(t, "unix:///var/run/docker.sock", engine.host())
+
+	t.Setenv("DOCKER_HOST", "tcp://build-host:2376")
+	assert.Equal(t, "tcp://build-host:2376", engine.host())
+}
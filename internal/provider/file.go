@@ -3,16 +3,15 @@ package provider
 import (
 	"context"
 	"fmt"
-	"os"
-	"sort"
 	"strings"
+	"time"
 
 	"github.com/Automaat/cache-buster/internal/cache"
 	"github.com/Automaat/cache-buster/internal/config"
 	"github.com/Automaat/cache-buster/pkg/size"
 )
 
-// FileProvider cleans caches by deleting oldest files until under limit.
+// FileProvider cleans caches by deleting files in eviction order until under limit.
 type FileProvider struct {
 	*BaseProvider
 }
@@ -39,9 +38,15 @@ func (p *FileProvider) Clean(ctx context.Context, opts CleanOptions) (CleanResul
 
 func (p *FileProvider) smartClean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
 	trimResult, err := cache.Trim(ctx, p.paths, cache.TrimOptions{
-		MaxSize: p.maxSize,
-		MaxAge:  p.maxAge,
-		DryRun:  opts.DryRun,
+		MaxSize:       p.maxSize,
+		MaxAge:        p.maxAge,
+		LRUKey:        p.lruKey,
+		Strategy:      p.eviction,
+		Headroom:      p.headroom,
+		AccessLog:     opts.AccessLog,
+		Filter:        p.filter,
+		DryRun:        opts.DryRun,
+		SkipOpenFiles: p.skipOpenFiles,
 	})
 	if err != nil {
 		return CleanResult{}, err
@@ -50,12 +55,14 @@ func (p *FileProvider) smartClean(ctx context.Context, opts CleanOptions) (Clean
 	result := CleanResult{
 		BytesCleaned: trimResult.FreedBytes,
 		FilesDeleted: trimResult.DeletedCount,
+		Fixups:       trimResult.Fixups,
 		Output:       trimResult.Output,
 	}
 
 	if len(trimResult.Errors) > 0 {
 		result.Output = formatResultWithErrors(trimResult.Output, trimResult.DeletedCount, trimResult.Errors)
 	}
+	result.Output = withWarning(p.lruWarning(), result.Output)
 
 	return result, nil
 }
@@ -72,15 +79,14 @@ func (p *FileProvider) fullClean(ctx context.Context, opts CleanOptions) (CleanR
 		}, nil
 	}
 
-	listResult, err := cache.ListFilesContext(ctx, p.paths)
+	listResult, err := cache.ListFilesByRecency(ctx, p.paths, p.filter, p.lruKey)
 	if err != nil {
 		return CleanResult{}, err
 	}
 
 	files := listResult.Files
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].ModTime.Before(files[j].ModTime)
-	})
+	opts.AccessLog.Apply(files)
+	p.eviction.Order(files, time.Now())
 
 	var (
 		bytesToDelete = currentSize - p.maxSize
@@ -88,21 +94,40 @@ func (p *FileProvider) fullClean(ctx context.Context, opts CleanOptions) (CleanR
 		filesDeleted  int64
 		deleteErrors  []cache.AccessError
 		output        strings.Builder
+		remover       = cache.Remover{Roots: p.paths}
 	)
 
 	// Carry forward scan warnings
 	deleteErrors = append(deleteErrors, listResult.Warnings...)
 
+	if p.skipOpenFiles {
+		kept, err := cache.ProtectOpen(files, p.paths)
+		if err != nil {
+			return CleanResult{}, err
+		}
+		if opts.DryRun {
+			for _, k := range kept {
+				fmt.Fprintf(&output, "skipping open file: %s\n", k.Path)
+			}
+		}
+		deleteErrors = append(deleteErrors, kept...)
+	}
+
 	for _, f := range files {
 		if bytesDeleted >= bytesToDelete {
 			break
 		}
+		if f.Protected {
+			continue
+		}
 
 		select {
 		case <-ctx.Done():
+			remover.Restore()
 			return CleanResult{
 				BytesCleaned: bytesDeleted,
 				FilesDeleted: filesDeleted,
+				Fixups:       remover.Fixups(),
 				Output:       "interrupted",
 			}, ctx.Err()
 		default:
@@ -115,7 +140,7 @@ func (p *FileProvider) fullClean(ctx context.Context, opts CleanOptions) (CleanR
 			continue
 		}
 
-		if err := os.Remove(f.Path); err != nil {
+		if err := remover.Remove(f.Path); err != nil {
 			deleteErrors = append(deleteErrors, cache.ClassifyError(f.Path, err))
 			continue
 		}
@@ -128,19 +153,23 @@ func (p *FileProvider) fullClean(ctx context.Context, opts CleanOptions) (CleanR
 		return CleanResult{
 			BytesCleaned: bytesDeleted,
 			FilesDeleted: filesDeleted,
-			Output:       output.String(),
+			Output:       withWarning(p.lruWarning(), output.String()),
 		}, nil
 	}
 
+	deleteErrors = append(deleteErrors, remover.Restore()...)
+
 	result := CleanResult{
 		BytesCleaned: bytesDeleted,
 		FilesDeleted: filesDeleted,
-		Output:       fmt.Sprintf("deleted %d files", filesDeleted),
+		Fixups:       remover.Fixups(),
+		Output:       cache.FormatDeleted(filesDeleted, remover.Fixups()),
 	}
 
 	if len(deleteErrors) > 0 {
 		result.Output = formatResultWithErrors(result.Output, filesDeleted, deleteErrors)
 	}
+	result.Output = withWarning(p.lruWarning(), result.Output)
 
 	return result, nil
 }
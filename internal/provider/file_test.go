@@ -1,9 +1,14 @@
 package provider
 
 import (
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFormatResultWithErrors(t *testing.T) {
@@ -97,3 +102,28 @@ func TestFormatResultWithErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestFileProvider_Eviction(t *testing.T) {
+	for name, mode := range map[string]CleanMode{"smart": CleanModeSmart, "full": CleanModeFull} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeAged(t, filepath.Join(dir, "old-a.bin"), 500, 20*24*time.Hour)
+			writeAged(t, filepath.Join(dir, "old-b.bin"), 500, 15*24*time.Hour)
+			writeAged(t, filepath.Join(dir, "big.bin"), 2000, 24*time.Hour)
+
+			p, err := NewFileProvider("test", config.Provider{
+				Paths:    []string{dir},
+				MaxSize:  "2500B",
+				Eviction: config.EvictLargest,
+				Headroom: "0%",
+			})
+			require.NoError(t, err)
+
+			result, err := p.Clean(t.Context(), CleanOptions{Mode: mode})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), result.FilesDeleted)
+			assert.NoFileExists(t, filepath.Join(dir, "big.bin"))
+			assert.FileExists(t, filepath.Join(dir, "old-a.bin"))
+		})
+	}
+}
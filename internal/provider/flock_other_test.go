@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package provider
+
+import (
+	"os"
+	"testing"
+)
+
+// flockFile skips the test: lock checks only run on Linux and macOS.
+func flockFile(t *testing.T, _ *os.File) func() {
+	t.Helper()
+	t.Skip("flock needs Linux or macOS")
+	return nil
+}
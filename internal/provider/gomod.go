@@ -0,0 +1,479 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/Automaat/cache-buster/pkg/size"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// goToolchainModule holds toolchains downloaded by GOTOOLCHAIN; the one running
+// may be any of them, so they are left to the go command.
+const goToolchainModule = "golang.org/toolchain"
+
+// downloadSuffixes are the per-version files under cache/download/<module>/@v.
+var downloadSuffixes = []string{".info", ".mod", ".zip", ".ziphash", ".lock", ".partial"}
+
+// GoModProvider prunes whole module versions from the Go module cache, keeping
+// versions referenced by go.sum files under the configured workspaces and the
+// newest keep versions of every module.
+type GoModProvider struct {
+	*BaseProvider
+	cleanCmd   string // run by full cleans instead of pruning when set
+	workspaces []string
+	keep       int
+}
+
+// NewGoModProvider creates a version-aware Go module cache provider.
+func NewGoModProvider(name string, cfg config.Provider) (*GoModProvider, error) {
+	base, err := NewBaseProvider(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces, err := config.ExpandPaths(cfg.Workspaces)
+	if err != nil {
+		return nil, fmt.Errorf("expand workspaces: %w", err)
+	}
+
+	return &GoModProvider{
+		BaseProvider: base,
+		cleanCmd:     cfg.CleanCmd,
+		workspaces:   workspaces,
+		keep:         cfg.Keep,
+	}, nil
+}
+
+// modVersion is one module version: its extracted source tree and download files.
+type modVersion struct {
+	lastUsed time.Time
+	module   string
+	version  string
+	root     string // module cache the version lives in
+	dir      string // extracted source, empty if only downloaded
+	files    []string
+	size     int64
+}
+
+func (v *modVersion) String() string {
+	return v.module + "@" + v.version
+}
+
+// paths returns the extracted tree, if any, and the download files.
+func (v *modVersion) paths() []string {
+	if v.dir == "" {
+		return v.files
+	}
+	return append([]string{v.dir}, v.files...)
+}
+
+// Clean implements Provider. Full mode removes every version that is neither
+// referenced nor among the newest; smart mode removes those unused for max_age,
+// then more in eviction order until the cache is under max_size. A
+// configured clean_cmd (e.g. go clean -modcache) replaces full mode.
+func (p *GoModProvider) Clean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
+	if opts.Mode == CleanModeFull && p.cleanCmd != "" {
+		return runCleanCmd(ctx, p.name, p.cleanCmd, opts.DryRun, p.CurrentSize)
+	}
+
+	removable, err := p.findRemovableVersions(ctx)
+	if err != nil {
+		return CleanResult{}, err
+	}
+	open, err := p.openFiles()
+	if err != nil {
+		return CleanResult{}, err
+	}
+	removable, skipped := skipOpen(open, removable, (*modVersion).paths, (*modVersion).String)
+
+	if opts.Mode == CleanModeSmart {
+		current, err := p.CurrentSize(ctx)
+		if err != nil {
+			return CleanResult{}, err
+		}
+		removable = p.selectSmart(removable, current)
+	}
+
+	result, err := p.removeVersions(ctx, removable, opts)
+	result.Output = withWarning(p.lruWarning(), withWarning(skipped, result.Output))
+	return result, err
+}
+
+// selectSmart picks versions unused for max_age, then further versions in
+// eviction order while the cache would still exceed max_size less headroom.
+func (p *GoModProvider) selectSmart(removable []*modVersion, current int64) []*modVersion {
+	evictionOrder(p.eviction, removable, func(v *modVersion) cache.FileInfo {
+		return cache.FileInfo{Path: v.root + "\x00" + v.String(), Size: v.size, LastUsed: v.lastUsed}
+	})
+
+	cutoff := time.Now().Add(-p.maxAge)
+	target := p.sizeTarget()
+	var selected []*modVersion
+	for _, v := range removable {
+		if (p.maxAge > 0 && v.lastUsed.Before(cutoff)) || current > target {
+			selected = append(selected, v)
+			current -= v.size
+		}
+	}
+	return selected
+}
+
+func (p *GoModProvider) removeVersions(ctx context.Context, versions []*modVersion, opts CleanOptions) (CleanResult, error) {
+	if len(versions) == 0 {
+		return CleanResult{Output: "no module versions to clean"}, nil
+	}
+
+	var (
+		result  CleanResult
+		output  strings.Builder
+		removed int
+	)
+	dropped := make(map[string][]string) // @v dir -> versions removed from it
+
+	for _, v := range versions {
+		select {
+		case <-ctx.Done():
+			result.Output = "interrupted"
+			return result, ctx.Err()
+		default:
+		}
+
+		if opts.DryRun {
+			fmt.Fprintf(&output, "would remove: %s (%s)\n", v, size.FormatSize(v.size))
+			result.BytesCleaned += v.size
+			continue
+		}
+
+		if err := removeModVersion(v); err != nil {
+			fmt.Fprintf(&output, "error removing %s: %v\n", v, err)
+			continue
+		}
+		if len(v.files) > 0 {
+			atV := filepath.Dir(v.files[0])
+			dropped[atV] = append(dropped[atV], v.version)
+		}
+		result.BytesCleaned += v.size
+		removed++
+	}
+
+	if opts.DryRun {
+		result.Output = strings.TrimSpace(output.String())
+		return result, nil
+	}
+
+	for atV, vers := range dropped {
+		if err := pruneVersionList(atV, vers); err != nil {
+			fmt.Fprintf(&output, "warning: update %s: %v\n", filepath.Join(atV, "list"), err)
+		}
+	}
+
+	result.Output = fmt.Sprintf("removed %d module versions", removed)
+	if output.Len() > 0 {
+		result.Output += "\n" + strings.TrimSpace(output.String())
+	}
+	return result, nil
+}
+
+// findRemovableVersions lists cached versions that are not referenced by a
+// workspace go.sum, not among the newest keep of their module, and not guarded
+// by exclude/protect patterns.
+func (p *GoModProvider) findRemovableVersions(ctx context.Context) ([]*modVersion, error) {
+	referenced, err := p.referencedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make(map[string][]*modVersion)
+	for _, root := range p.paths {
+		versions, err := scanModCache(ctx, root)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			modules[v.root+"\x00"+v.module] = append(modules[v.root+"\x00"+v.module], v)
+		}
+	}
+
+	var removable []*modVersion
+	for _, versions := range modules {
+		sort.Slice(versions, func(i, j int) bool {
+			return semver.Compare(versions[i].version, versions[j].version) > 0
+		})
+
+		for i, v := range versions {
+			if i < p.keep || referenced[v.String()] || v.module == goToolchainModule {
+				continue
+			}
+			guarded, err := p.guarded(v)
+			if err != nil {
+				return nil, err
+			}
+			if guarded {
+				continue
+			}
+			if err := sizeModVersion(ctx, v, p.lruKey); err != nil {
+				return nil, err
+			}
+			removable = append(removable, v)
+		}
+	}
+
+	sort.Slice(removable, func(i, j int) bool {
+		return removable[i].String() < removable[j].String()
+	})
+	return removable, nil
+}
+
+func (p *GoModProvider) guarded(v *modVersion) (bool, error) {
+	if v.dir != "" {
+		if guarded, err := p.filter.Guards(v.root, v.dir); err != nil || guarded {
+			return guarded, err
+		}
+	}
+	for _, f := range v.files {
+		if p.filter.Excluded(v.root, f) || p.filter.Protected(v.root, f) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// referencedVersions collects module@version pairs from go.sum and go.work.sum
+// files under the workspace roots.
+func (p *GoModProvider) referencedVersions(ctx context.Context) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	err := walkWorkspaceFiles(ctx, p.workspaces, []string{"go.sum", "go.work.sum"}, func(path string) error {
+		return readGoSum(path, referenced)
+	})
+	return referenced, err
+}
+
+// readGoSum adds every "module version[/go.mod] hash" line of a go.sum file to referenced.
+func readGoSum(path string, referenced map[string]bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		version := strings.TrimSuffix(fields[1], "/go.mod")
+		referenced[fields[0]+"@"+version] = true
+	}
+	return scanner.Err()
+}
+
+// scanModCache finds module versions in a module cache: extracted trees named
+// <escaped module>@<version> and download files under cache/download.
+func scanModCache(ctx context.Context, root string) ([]*modVersion, error) {
+	byKey := make(map[string]*modVersion)
+	get := func(escMod, escVer string) *modVersion {
+		mod, err := module.UnescapePath(escMod)
+		if err != nil {
+			return nil
+		}
+		ver, err := module.UnescapeVersion(escVer)
+		if err != nil {
+			return nil
+		}
+		key := mod + "@" + ver
+		if byKey[key] == nil {
+			byKey[key] = &modVersion{module: mod, version: ver, root: root}
+		}
+		return byKey[key]
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || !d.IsDir() || path == root {
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return nil
+		}
+		if rel == "cache" {
+			return filepath.SkipDir
+		}
+		escMod, escVer, ok := strings.Cut(filepath.ToSlash(rel), "@")
+		if !ok {
+			return nil
+		}
+		if v := get(escMod, escVer); v != nil {
+			v.dir = path
+		}
+		return filepath.SkipDir
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("scan %s: %w", root, err)
+	}
+
+	if err := scanDownloads(ctx, root, get); err != nil {
+		return nil, err
+	}
+
+	versions := make([]*modVersion, 0, len(byKey))
+	for _, v := range byKey {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// scanDownloads attaches cache/download/<module>/@v/<version>.* files to their versions.
+func scanDownloads(ctx context.Context, root string, get func(escMod, escVer string) *modVersion) error {
+	downloads := filepath.Join(root, "cache", "download")
+
+	err := filepath.WalkDir(downloads, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path == filepath.Join(downloads, "sumdb") {
+			return filepath.SkipDir
+		}
+		if d.Name() != "@v" {
+			return nil
+		}
+
+		escMod, relErr := filepath.Rel(downloads, filepath.Dir(path))
+		if relErr != nil {
+			return filepath.SkipDir
+		}
+		entries, readErr := os.ReadDir(path)
+		if readErr != nil {
+			return filepath.SkipDir
+		}
+		for _, e := range entries {
+			escVer, ok := trimDownloadSuffix(e.Name())
+			if !ok || e.IsDir() {
+				continue
+			}
+			if v := get(filepath.ToSlash(escMod), escVer); v != nil {
+				v.files = append(v.files, filepath.Join(path, e.Name()))
+			}
+		}
+		return filepath.SkipDir
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("scan %s: %w", downloads, err)
+	}
+	return nil
+}
+
+func trimDownloadSuffix(name string) (string, bool) {
+	for _, suffix := range downloadSuffixes {
+		if ver, ok := strings.CutSuffix(name, suffix); ok {
+			return ver, true
+		}
+	}
+	return "", false
+}
+
+// sizeModVersion fills in the version's size and when key says a file of it
+// was last used.
+func sizeModVersion(ctx context.Context, v *modVersion, key cache.LRUKey) error {
+	listing, err := cache.ListFilesByRecency(ctx, v.paths(), cache.Filter{}, key)
+	if err != nil {
+		return err
+	}
+	for _, f := range listing.Files {
+		v.size += f.Size
+		if f.LastUsed.After(v.lastUsed) {
+			v.lastUsed = f.LastUsed
+		}
+	}
+	return nil
+}
+
+// removeModVersion deletes a version's extracted tree and download files. The
+// go command makes extracted trees read-only, so write permission is restored first.
+func removeModVersion(v *modVersion) error {
+	if v.dir != "" {
+		if err := makeTreeWritable(v.dir); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(v.dir); err != nil {
+			return err
+		}
+	}
+	for _, f := range v.files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeTreeWritable adds owner write permission to every directory under root.
+func makeTreeWritable(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm()&0o200 == 0 {
+			return os.Chmod(path, info.Mode().Perm()|0o200)
+		}
+		return nil
+	})
+}
+
+// pruneVersionList drops removed versions from an @v/list file so the go
+// command does not offer them when resolving queries offline.
+func pruneVersionList(atV string, removed []string) error {
+	listPath := filepath.Join(atV, "list")
+	data, err := os.ReadFile(listPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	drop := make(map[string]bool, len(removed))
+	for _, v := range removed {
+		drop[v] = true
+	}
+
+	var kept []string
+	for line := range strings.SplitSeq(strings.TrimSpace(string(data)), "\n") {
+		if line != "" && !drop[strings.Fields(line)[0]] {
+			kept = append(kept, line)
+		}
+	}
+
+	content := ""
+	if len(kept) > 0 {
+		content = strings.Join(kept, "\n") + "\n"
+	}
+	return os.WriteFile(listPath, []byte(content), 0o644)
+}
@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeModVersion lays out a module version the way the go command does: a
+// read-only extracted tree plus info/mod/zip download files, all aged by age.
+func writeModVersion(t *testing.T, root, escMod, version string, age time.Duration) {
+	t.Helper()
+	dir := filepath.Join(root, filepath.FromSlash(escMod)+"@"+version)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "x.go"), make([]byte, 100), 0o444))
+
+	atV := filepath.Join(root, "cache", "download", filepath.FromSlash(escMod), "@v")
+	require.NoError(t, os.MkdirAll(atV, 0o755))
+	for _, ext := range []string{".info", ".mod", ".zip"} {
+		require.NoError(t, os.WriteFile(filepath.Join(atV, version+ext), make([]byte, 10), 0o644))
+	}
+	list, err := os.OpenFile(filepath.Join(atV, "list"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = list.WriteString(version + "\n")
+	require.NoError(t, err)
+	require.NoError(t, list.Close())
+
+	when := time.Now().Add(-age)
+	for _, path := range []string{filepath.Join(dir, "sub", "x.go"), filepath.Join(atV, version+".info"),
+		filepath.Join(atV, version+".mod"), filepath.Join(atV, version+".zip")} {
+		require.NoError(t, os.Chtimes(path, when, when))
+	}
+	require.NoError(t, os.Chmod(filepath.Join(dir, "sub"), 0o555))
+	require.NoError(t, os.Chmod(dir, 0o555))
+}
+
+func modVersionExists(root, escMod, version string) bool {
+	_, dirErr := os.Stat(filepath.Join(root, filepath.FromSlash(escMod)+"@"+version))
+	_, zipErr := os.Stat(filepath.Join(root, "cache", "download", filepath.FromSlash(escMod), "@v", version+".zip"))
+	return dirErr == nil || zipErr == nil
+}
+
+// setupModCache builds a module cache and a workspace whose go.sum references bar v1.0.0.
+func setupModCache(t *testing.T) (modCache, workspace string) {
+	t.Helper()
+	modCache = t.TempDir()
+	workspace = t.TempDir()
+	t.Cleanup(func() { _ = makeTreeWritable(modCache) })
+
+	old := 90 * 24 * time.Hour
+	writeModVersion(t, modCache, "github.com/foo/bar", "v1.0.0", old)
+	writeModVersion(t, modCache, "github.com/foo/bar", "v1.1.0", old)
+	writeModVersion(t, modCache, "github.com/foo/bar", "v1.2.0", old)
+	writeModVersion(t, modCache, "github.com/!burnt!sushi/toml", "v0.9.0", 0)
+	writeModVersion(t, modCache, "github.com/!burnt!sushi/toml", "v1.0.0", 0)
+	writeModVersion(t, modCache, "golang.org/toolchain", "v0.0.1-go1.21.0.linux-amd64", old)
+	writeModVersion(t, modCache, "golang.org/toolchain", "v0.0.1-go1.22.0.linux-amd64", old)
+
+	app := filepath.Join(workspace, "app")
+	require.NoError(t, os.MkdirAll(app, 0o755))
+	goSum := "github.com/foo/bar v1.0.0 h1:abc=\ngithub.com/foo/bar v1.0.0/go.mod h1:def=\n"
+	require.NoError(t, os.WriteFile(filepath.Join(app, "go.sum"), []byte(goSum), 0o644))
+	return modCache, workspace
+}
+
+func newTestGoModProvider(t *testing.T, modCache, workspace, maxSize string) *GoModProvider {
+	t.Helper()
+	p, err := NewGoModProvider("go-mod", config.Provider{
+		Paths:      []string{modCache},
+		Workspaces: []string{workspace},
+		MaxSize:    maxSize,
+		MaxAge:     "30d",
+		Keep:       1,
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestGoModProvider_FindRemovableVersions(t *testing.T) {
+	modCache, workspace := setupModCache(t)
+	p := newTestGoModProvider(t, modCache, workspace, "1G")
+
+	removable, err := p.findRemovableVersions(t.Context())
+	require.NoError(t, err)
+
+	var names []string
+	for _, v := range removable {
+		names = append(names, v.String())
+		assert.Equal(t, int64(130), v.size)
+	}
+	// bar v1.0.0 is in go.sum, v1.2.0 and toml v1.0.0 are newest, toolchains are never touched.
+	assert.Equal(t, []string{"github.com/BurntSushi/toml@v0.9.0", "github.com/foo/bar@v1.1.0"}, names)
+}
+
+func TestGoModProvider_FullClean(t *testing.T) {
+	modCache, workspace := setupModCache(t)
+	p := newTestGoModProvider(t, modCache, workspace, "1G")
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Equal(t, int64(260), result.BytesCleaned)
+	assert.Contains(t, result.Output, "removed 2 module versions")
+
+	assert.False(t, modVersionExists(modCache, "github.com/foo/bar", "v1.1.0"))
+	assert.False(t, modVersionExists(modCache, "github.com/!burnt!sushi/toml", "v0.9.0"))
+	assert.True(t, modVersionExists(modCache, "github.com/foo/bar", "v1.0.0"))
+	assert.True(t, modVersionExists(modCache, "github.com/foo/bar", "v1.2.0"))
+	assert.True(t, modVersionExists(modCache, "golang.org/toolchain", "v0.0.1-go1.21.0.linux-amd64"))
+
+	list, err := os.ReadFile(filepath.Join(modCache, "cache", "download", "github.com", "foo", "bar", "@v", "list"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0\nv1.2.0\n", string(list))
+}
+
+func TestGoModProvider_SmartClean(t *testing.T) {
+	modCache, workspace := setupModCache(t)
+	p := newTestGoModProvider(t, modCache, workspace, "1G")
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.Equal(t, int64(130), result.BytesCleaned)
+
+	// Only the version older than max_age goes while under max_size.
+	assert.False(t, modVersionExists(modCache, "github.com/foo/bar", "v1.1.0"))
+	assert.True(t, modVersionExists(modCache, "github.com/!burnt!sushi/toml", "v0.9.0"))
+}
+
+func TestGoModProvider_SmartCleanOverLimit(t *testing.T) {
+	modCache, workspace := setupModCache(t)
+	p := newTestGoModProvider(t, modCache, workspace, "1")
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.Equal(t, int64(260), result.BytesCleaned)
+	assert.False(t, modVersionExists(modCache, "github.com/!burnt!sushi/toml", "v0.9.0"))
+}
+
+func TestGoModProvider_DryRun(t *testing.T) {
+	modCache, workspace := setupModCache(t)
+	p := newTestGoModProvider(t, modCache, workspace, "1G")
+
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true, Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Equal(t, int64(260), result.BytesCleaned)
+	assert.Contains(t, result.Output, "would remove: github.com/foo/bar@v1.1.0")
+	assert.Len(t, strings.Split(result.Output, "\n"), 2)
+	assert.True(t, modVersionExists(modCache, "github.com/foo/bar", "v1.1.0"))
+}
+
+func TestGoModProvider_FullCleanRunsCleanCmd(t *testing.T) {
+	modCache, workspace := setupModCache(t)
+	marker := filepath.Join(t.TempDir(), "ran")
+	p, err := NewGoModProvider("go-mod", config.Provider{
+		Paths:      []string{modCache},
+		Workspaces: []string{workspace},
+		MaxSize:    "1G",
+		Keep:       1,
+		CleanCmd:   "touch " + marker,
+	})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "would run: touch "+marker, result.Output)
+
+	_, err = p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.FileExists(t, marker)
+	assert.True(t, modVersionExists(modCache, "github.com/foo/bar", "v1.1.0"), "clean_cmd replaces pruning")
+}
+
+func TestGoModProvider_KeepZero(t *testing.T) {
+	modCache, workspace := setupModCache(t)
+	p := newTestGoModProvider(t, modCache, workspace, "1G")
+	p.keep = 0
+
+	removable, err := p.findRemovableVersions(t.Context())
+	require.NoError(t, err)
+	// Without keep only go.sum references (and toolchains) survive.
+	assert.Len(t, removable, 4)
+}
+
+func TestGoModProvider_Protect(t *testing.T) {
+	modCache, workspace := setupModCache(t)
+	p, err := NewGoModProvider("go-mod", config.Provider{
+		Paths:      []string{modCache},
+		Workspaces: []string{workspace},
+		MaxSize:    "1G",
+		Keep:       1,
+		Protect:    []string{"github.com/foo/**"},
+	})
+	require.NoError(t, err)
+
+	removable, err := p.findRemovableVersions(t.Context())
+	require.NoError(t, err)
+	require.Len(t, removable, 1)
+	assert.Equal(t, "github.com/BurntSushi/toml", removable[0].module)
+}
+
+func TestNewProvider_GoMod(t *testing.T) {
+	p, err := NewProvider("go-mod", config.Provider{Paths: []string{t.TempDir()}, MaxSize: "1G"})
+	require.NoError(t, err)
+	assert.IsType(t, &GoModProvider{}, p)
+}
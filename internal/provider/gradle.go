@@ -0,0 +1,367 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/Automaat/cache-buster/pkg/size"
+)
+
+var (
+	// gradleVersionDirPattern matches per-version dirs like caches/8.5 or caches/8.11-rc-1.
+	gradleVersionDirPattern = regexp.MustCompile(`^(\d+(?:\.\d+)+(?:-[A-Za-z0-9.-]+)?)$`)
+	// gradleDistDirPattern matches wrapper/dists/gradle-8.5-bin and gradle-8.5-all.
+	gradleDistDirPattern = regexp.MustCompile(`^gradle-(\d+(?:\.\d+)+(?:-[A-Za-z0-9.]+)*)-(?:bin|all)$`)
+	// gradleDistURLPattern extracts the version from a wrapper distributionUrl.
+	gradleDistURLPattern = regexp.MustCompile(`gradle-([^/]+?)-(?:bin|all)\.zip`)
+)
+
+// gradleArtifactsDir is the dependency cache, laid out as <group>/<module>/<version>/<sha1>/<file>.
+var gradleArtifactsDir = filepath.Join("modules-2", "files-2.1")
+
+// gradleItemKind tells apart what the gradle provider removes.
+type gradleItemKind int
+
+const (
+	gradleKindCache    gradleItemKind = iota // caches/<gradle-version>
+	gradleKindDist                           // wrapper/dists/gradle-<version>-<type>
+	gradleKindArtifact                       // caches/modules-2/files-2.1/<group>/<module>/<version>
+)
+
+// gradleItem is a directory the gradle provider removes as a whole.
+type gradleItem struct {
+	lastUsed time.Time
+	path     string
+	label    string
+	size     int64
+	kind     gradleItemKind
+}
+
+// GradleProvider cleans the Gradle user home: per-version cache dirs and
+// wrapper distributions beyond the newest keep (or referenced by a workspace
+// gradle-wrapper.properties), and dependency artifacts one version at a time.
+type GradleProvider struct {
+	*BaseProvider
+	workspaces []string
+	keep       int
+}
+
+// NewGradleProvider creates a version-aware Gradle provider.
+func NewGradleProvider(name string, cfg config.Provider) (*GradleProvider, error) {
+	base, err := NewBaseProvider(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces, err := config.ExpandPaths(cfg.Workspaces)
+	if err != nil {
+		return nil, fmt.Errorf("expand workspaces: %w", err)
+	}
+
+	return &GradleProvider{
+		BaseProvider: base,
+		workspaces:   workspaces,
+		keep:         cfg.Keep,
+	}, nil
+}
+
+// Clean implements Provider. Unused Gradle versions and distributions go in
+// full mode, or once older than max_age in smart mode. Artifact versions are
+// then removed in eviction order until under max_size less headroom; smart
+// mode also removes those untouched for max_age.
+func (p *GradleProvider) Clean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
+	versioned, err := p.findVersionedDirs(ctx)
+	if err != nil {
+		return CleanResult{}, err
+	}
+	artifacts, err := p.findArtifacts(ctx)
+	if err != nil {
+		return CleanResult{}, err
+	}
+	current, err := p.CurrentSize(ctx)
+	if err != nil {
+		return CleanResult{}, err
+	}
+	open, err := p.openFiles()
+	if err != nil {
+		return CleanResult{}, err
+	}
+	itemPaths := func(item gradleItem) []string { return []string{item.path} }
+	itemLabel := func(item gradleItem) string { return item.label }
+	versioned, skipped := skipOpen(open, versioned, itemPaths, itemLabel)
+	artifacts, skippedArtifacts := skipOpen(open, artifacts, itemPaths, itemLabel)
+	skipped = withWarning(skipped, skippedArtifacts)
+
+	cutoff := time.Now().Add(-p.maxAge)
+	expired := func(item gradleItem) bool {
+		return opts.Mode == CleanModeSmart && p.maxAge > 0 && item.lastUsed.Before(cutoff)
+	}
+
+	var selected []gradleItem
+	for _, item := range versioned {
+		if opts.Mode == CleanModeFull || expired(item) {
+			selected = append(selected, item)
+			current -= item.size
+		}
+	}
+	evictionOrder(p.eviction, artifacts, func(item gradleItem) cache.FileInfo {
+		return cache.FileInfo{Path: item.path, Size: item.size, LastUsed: item.lastUsed}
+	})
+	target := p.sizeTarget()
+	for _, item := range artifacts {
+		if expired(item) || current > target {
+			selected = append(selected, item)
+			current -= item.size
+		}
+	}
+
+	result, err := p.removeItems(ctx, selected, opts.DryRun)
+	result.Output = withWarning(p.lruWarning(), withWarning(skipped, result.Output))
+	return result, err
+}
+
+func (p *GradleProvider) removeItems(ctx context.Context, items []gradleItem, dryRun bool) (CleanResult, error) {
+	if len(items) == 0 {
+		return CleanResult{Output: "nothing to clean"}, nil
+	}
+
+	var (
+		result CleanResult
+		output strings.Builder
+		counts = make(map[gradleItemKind]int)
+	)
+
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			result.Output = "interrupted"
+			return result, err
+		}
+
+		if dryRun {
+			fmt.Fprintf(&output, "would remove: %s (%s)\n", item.label, size.FormatSize(item.size))
+			result.BytesCleaned += item.size
+			continue
+		}
+
+		if err := os.RemoveAll(item.path); err != nil {
+			fmt.Fprintf(&output, "error removing %s: %v\n", item.label, err)
+			continue
+		}
+		result.BytesCleaned += item.size
+		result.FilesDeleted++
+		counts[item.kind]++
+	}
+
+	if dryRun {
+		result.Output = strings.TrimSpace(output.String())
+		return result, nil
+	}
+
+	result.Output = fmt.Sprintf("removed %d gradle versions, %d distributions, %d artifact versions",
+		counts[gradleKindCache], counts[gradleKindDist], counts[gradleKindArtifact])
+	if output.Len() > 0 {
+		result.Output += "\n" + strings.TrimSpace(output.String())
+	}
+	return result, nil
+}
+
+// gradleLayout returns the caches and wrapper/dists dirs for a provider path,
+// which may be the Gradle user home itself or one of the two.
+func gradleLayout(root string) (caches, dists string) {
+	switch filepath.Base(root) {
+	case "caches":
+		return root, ""
+	case "dists":
+		return "", root
+	}
+	return filepath.Join(root, "caches"), filepath.Join(root, "wrapper", "dists")
+}
+
+// findVersionedDirs returns per-version cache dirs and wrapper distributions
+// that are neither among the newest keep nor referenced by a workspace.
+func (p *GradleProvider) findVersionedDirs(ctx context.Context) ([]gradleItem, error) {
+	referenced, err := p.referencedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var removable []gradleItem
+	for _, root := range p.paths {
+		caches, dists := gradleLayout(root)
+		for _, group := range []struct {
+			pattern *regexp.Regexp
+			dir     string
+			kind    gradleItemKind
+		}{
+			{dir: caches, pattern: gradleVersionDirPattern, kind: gradleKindCache},
+			{dir: dists, pattern: gradleDistDirPattern, kind: gradleKindDist},
+		} {
+			if group.dir == "" {
+				continue
+			}
+			items, err := p.unusedVersions(ctx, root, group.dir, group.pattern, group.kind, referenced)
+			if err != nil {
+				return nil, err
+			}
+			removable = append(removable, items...)
+		}
+	}
+	return removable, nil
+}
+
+// unusedVersions lists dir entries whose name matches pattern (version in the
+// first group), newest keep versions and referenced ones excluded. Both the bin and all distributions of a version count as one version.
+func (p *GradleProvider) unusedVersions(ctx context.Context, root, dir string, pattern *regexp.Regexp, kind gradleItemKind, referenced map[string]bool) ([]gradleItem, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	byVersion := make(map[string][]string)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		m := pattern.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		byVersion[m[1]] = append(byVersion[m[1]], filepath.Join(dir, e.Name()))
+	}
+
+	versions := make([]string, 0, len(byVersion))
+	for v := range byVersion {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) > 0
+	})
+
+	var items []gradleItem
+	for i, v := range versions {
+		if i < p.keep || referenced[v] {
+			continue
+		}
+		for _, path := range byVersion[v] {
+			item, ok, err := p.sizeItem(ctx, root, path, kind)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				item.label = filepath.Base(path)
+				items = append(items, item)
+			}
+		}
+	}
+	return items, nil
+}
+
+// findArtifacts returns modules-2/files-2.1/<group>/<module>/<version> dirs, least recently modified first.
+func (p *GradleProvider) findArtifacts(ctx context.Context) ([]gradleItem, error) {
+	var items []gradleItem
+
+	for _, root := range p.paths {
+		caches, _ := gradleLayout(root)
+		if caches == "" {
+			continue
+		}
+		base := filepath.Join(caches, gradleArtifactsDir)
+		versions, err := filepath.Glob(filepath.Join(base, "*", "*", "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range versions {
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				continue
+			}
+			item, ok, err := p.sizeItem(ctx, root, path, gradleKindArtifact)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			rel, _ := filepath.Rel(base, path)
+			item.label = strings.Join(strings.Split(rel, string(filepath.Separator)), ":")
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].lastUsed.Before(items[j].lastUsed)
+	})
+	return items, nil
+}
+
+// sizeItem measures a directory and when a file in it was last used; ok is
+// false when exclude or protect patterns cover anything inside it.
+func (p *GradleProvider) sizeItem(ctx context.Context, root, path string, kind gradleItemKind) (gradleItem, bool, error) {
+	guarded, err := p.filter.Guards(root, path)
+	if err != nil || guarded {
+		return gradleItem{}, false, err
+	}
+
+	listing, err := cache.ListFilesByRecency(ctx, []string{path}, cache.Filter{}, p.lruKey)
+	if err != nil {
+		return gradleItem{}, false, err
+	}
+
+	item := gradleItem{path: path, kind: kind}
+	for _, f := range listing.Files {
+		item.size += f.Size
+		if f.LastUsed.After(item.lastUsed) {
+			item.lastUsed = f.LastUsed
+		}
+	}
+	return item, true, nil
+}
+
+// referencedVersions collects Gradle versions named by distributionUrl in
+// gradle-wrapper.properties files under the workspaces.
+func (p *GradleProvider) referencedVersions(ctx context.Context) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	err := walkWorkspaceFiles(ctx, p.workspaces, []string{"gradle-wrapper.properties"}, func(path string) error {
+		version, err := readWrapperVersion(path)
+		if err != nil {
+			return err
+		}
+		if version != "" {
+			referenced[version] = true
+		}
+		return nil
+	})
+	return referenced, err
+}
+
+// readWrapperVersion returns the Gradle version of a wrapper's distributionUrl, or "" if absent.
+func readWrapperVersion(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || strings.TrimSpace(key) != "distributionUrl" {
+			continue
+		}
+		if m := gradleDistURLPattern.FindStringSubmatch(value); m != nil {
+			return m[1], nil
+		}
+	}
+	return "", scanner.Err()
+}
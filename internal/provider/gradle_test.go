@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupGradleHome builds a Gradle user home with three cache versions, three
+// wrapper distributions, and two versions of one artifact.
+func setupGradleHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	old := 60 * 24 * time.Hour
+
+	writeAged(t, filepath.Join(home, "caches", "7.6.1", "kotlin-dsl", "x"), 100, old)
+	writeAged(t, filepath.Join(home, "caches", "8.5", "kotlin-dsl", "x"), 100, time.Hour)
+	writeAged(t, filepath.Join(home, "caches", "8.10.2", "kotlin-dsl", "x"), 100, time.Hour)
+	writeAged(t, filepath.Join(home, "caches", "jars-9", "x.jar"), 100, old)
+
+	dists := filepath.Join(home, "wrapper", "dists")
+	writeAged(t, filepath.Join(dists, "gradle-7.6.1-bin", "abc", "gradle-7.6.1", "lib.jar"), 100, old)
+	writeAged(t, filepath.Join(dists, "gradle-8.5-all", "def", "gradle-8.5", "lib.jar"), 100, old)
+	writeAged(t, filepath.Join(dists, "gradle-8.10.2-bin", "ghi", "gradle-8.10.2", "lib.jar"), 100, time.Hour)
+
+	files := filepath.Join(home, "caches", "modules-2", "files-2.1")
+	writeAged(t, filepath.Join(files, "com.google.guava", "guava", "31.0-jre", "sha1a", "guava.jar"), 300, old)
+	writeAged(t, filepath.Join(files, "com.google.guava", "guava", "31.0-jre", "sha1b", "guava.pom"), 10, old)
+	writeAged(t, filepath.Join(files, "com.google.guava", "guava", "33.0-jre", "sha1c", "guava.jar"), 300, time.Hour)
+
+	return home
+}
+
+// setupGradleWorkspace creates a project whose wrapper pins Gradle 8.5.
+func setupGradleWorkspace(t *testing.T) string {
+	t.Helper()
+	ws := t.TempDir()
+	props := "distributionBase=GRADLE_USER_HOME\n" +
+		"distributionUrl=https\\://services.gradle.org/distributions/gradle-8.5-all.zip\n"
+	path := filepath.Join(ws, "app", "gradle", "wrapper", "gradle-wrapper.properties")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(props), 0o644))
+	return ws
+}
+
+func newTestGradleProvider(t *testing.T, paths []string, workspace string) *GradleProvider {
+	t.Helper()
+	p, err := NewGradleProvider("gradle", config.Provider{
+		Paths:      paths,
+		Workspaces: []string{workspace},
+		MaxSize:    "1G",
+		MaxAge:     "30d",
+		Keep:       1,
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func itemLabels(items []gradleItem) []string {
+	labels := make([]string, len(items))
+	for i, item := range items {
+		labels[i] = item.label
+	}
+	return labels
+}
+
+func TestReadWrapperVersion(t *testing.T) {
+	ws := setupGradleWorkspace(t)
+	version, err := readWrapperVersion(filepath.Join(ws, "app", "gradle", "wrapper", "gradle-wrapper.properties"))
+	require.NoError(t, err)
+	assert.Equal(t, "8.5", version)
+}
+
+func TestGradleProvider_FindVersionedDirs(t *testing.T) {
+	home := setupGradleHome(t)
+	p := newTestGradleProvider(t, []string{home}, setupGradleWorkspace(t))
+
+	items, err := p.findVersionedDirs(t.Context())
+	require.NoError(t, err)
+
+	// 8.10.2 is newest and 8.5 is referenced by the wrapper; jars-9 is not a version dir.
+	assert.ElementsMatch(t, []string{"7.6.1", "gradle-7.6.1-bin"}, itemLabels(items))
+}
+
+func TestGradleProvider_FindVersionedDirs_NoWorkspace(t *testing.T) {
+	home := setupGradleHome(t)
+	p := newTestGradleProvider(t, []string{filepath.Join(home, "caches"), filepath.Join(home, "wrapper", "dists")}, "")
+
+	items, err := p.findVersionedDirs(t.Context())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"7.6.1", "8.5", "gradle-7.6.1-bin", "gradle-8.5-all"}, itemLabels(items))
+}
+
+func TestGradleProvider_FindArtifacts(t *testing.T) {
+	home := setupGradleHome(t)
+	p := newTestGradleProvider(t, []string{home}, "")
+
+	items, err := p.findArtifacts(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"com.google.guava:guava:31.0-jre", "com.google.guava:guava:33.0-jre"}, itemLabels(items))
+	assert.Equal(t, int64(310), items[0].size)
+}
+
+func TestGradleProvider_SmartClean(t *testing.T) {
+	home := setupGradleHome(t)
+	p := newTestGradleProvider(t, []string{home}, setupGradleWorkspace(t))
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "removed 1 gradle versions, 1 distributions, 1 artifact versions")
+
+	assert.NoDirExists(t, filepath.Join(home, "caches", "7.6.1"))
+	assert.NoDirExists(t, filepath.Join(home, "wrapper", "dists", "gradle-7.6.1-bin"))
+	assert.DirExists(t, filepath.Join(home, "wrapper", "dists", "gradle-8.5-all"))
+	assert.NoDirExists(t, filepath.Join(home, "caches", "modules-2", "files-2.1", "com.google.guava", "guava", "31.0-jre"))
+	assert.DirExists(t, filepath.Join(home, "caches", "modules-2", "files-2.1", "com.google.guava", "guava", "33.0-jre"))
+	assert.DirExists(t, filepath.Join(home, "caches", "jars-9"))
+}
+
+func TestGradleProvider_SmartCleanLRUKeyAtime(t *testing.T) {
+	home := setupGradleHome(t)
+	version := filepath.Join(home, "caches", "modules-2", "files-2.1", "com.google.guava", "guava", "31.0-jre")
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(version, "sha1a", "guava.jar"), time.Now(), old))
+
+	p, err := NewGradleProvider("gradle", config.Provider{
+		Paths:   []string{home},
+		MaxSize: "1G",
+		MaxAge:  "30d",
+		LRUKey:  config.LRUAtime,
+	})
+	require.NoError(t, err)
+
+	_, err = p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.DirExists(t, version, "read recently, though not modified")
+}
+
+func TestGradleProvider_FullClean(t *testing.T) {
+	home := setupGradleHome(t)
+	p := newTestGradleProvider(t, []string{home}, "")
+	p.maxAge = 0
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	// Under max_size: every unused version goes, artifacts stay.
+	assert.Contains(t, result.Output, "removed 2 gradle versions, 2 distributions, 0 artifact versions")
+	assert.DirExists(t, filepath.Join(home, "caches", "8.10.2"))
+}
+
+func TestGradleProvider_FullCleanOverLimit(t *testing.T) {
+	home := setupGradleHome(t)
+	p := newTestGradleProvider(t, []string{home}, "")
+
+	current, err := p.CurrentSize(t.Context())
+	require.NoError(t, err)
+	// Unused versions free 400 bytes; the oldest artifact version must go as well.
+	p.maxSize = current - 500
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "1 artifact versions")
+	assert.Equal(t, int64(710), result.BytesCleaned)
+}
+
+func TestGradleProvider_DryRun(t *testing.T) {
+	home := setupGradleHome(t)
+	p := newTestGradleProvider(t, []string{home}, setupGradleWorkspace(t))
+
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true, Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "would remove: com.google.guava:guava:31.0-jre")
+	assert.Contains(t, result.Output, "would remove: gradle-7.6.1-bin")
+	assert.DirExists(t, filepath.Join(home, "caches", "7.6.1"))
+}
+
+func TestNewProvider_Gradle(t *testing.T) {
+	p, err := NewProvider("gradle", config.Provider{Paths: []string{t.TempDir()}, MaxSize: "1G"})
+	require.NoError(t, err)
+	assert.IsType(t, &GradleProvider{}, p)
+}
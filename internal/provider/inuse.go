@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// InUsePollInterval is how often WaitIdle checks a provider in use again.
+var InUsePollInterval = 2 * time.Second
+
+// InUseError reports a clean that did not run because the owning tool was
+// using the cache.
+type InUseError struct {
+	Provider string
+	Reason   string
+	Abort    bool // on_busy: abort; the remaining providers are not cleaned either
+}
+
+func (e *InUseError) Error() string {
+	return fmt.Sprintf("%s in use: %s", e.Provider, e.Reason)
+}
+
+// InUse implements InUseChecker: a running process with one of the
+// configured names, or a held lock on a file matching lock_files.
+func (b *BaseProvider) InUse() string {
+	if procs, err := cache.FindProcesses(b.processes); err == nil && len(procs) > 0 {
+		return procs[0].String() + " is running"
+	}
+
+	for _, pattern := range b.lockFiles {
+		matches, err := doublestar.FilepathGlob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, path := range matches {
+			holder, locked, err := cache.LockedBy(path)
+			if err != nil || !locked {
+				continue
+			}
+			if holder.PID != 0 {
+				return fmt.Sprintf("%s holds a lock on %s", holder, path)
+			}
+			return path + " is locked"
+		}
+	}
+	return ""
+}
+
+// OnBusy implements InUseChecker.
+func (b *BaseProvider) OnBusy() string {
+	if b.onBusy == "" {
+		return config.BusySkip
+	}
+	return b.onBusy
+}
+
+// WaitIdle applies the on_busy policy of a provider in use before a clean.
+// It returns nil once the provider is free, waiting for that under
+// on_busy: wait and calling waiting with the reason first; an *InUseError
+// under skip and abort; or ctx's error if ctx ends while waiting.
+func WaitIdle(ctx context.Context, p Provider, waiting func(reason string)) error {
+	checker, ok := p.(InUseChecker)
+	if !ok {
+		return nil
+	}
+	reason := checker.InUse()
+	if reason == "" {
+		return nil
+	}
+
+	switch checker.OnBusy() {
+	case config.BusyWait:
+	case config.BusyAbort:
+		return &InUseError{Provider: p.Name(), Reason: reason, Abort: true}
+	default:
+		return &InUseError{Provider: p.Name(), Reason: reason}
+	}
+
+	if waiting != nil {
+		waiting(reason)
+	}
+	ticker := time.NewTicker(InUsePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if checker.InUse() == "" {
+				return nil
+			}
+		}
+	}
+}
+
+// openFiles returns the files another process has open under the provider
+// paths when skip_open_files is set, and nil otherwise.
+func (b *BaseProvider) openFiles() (map[string]bool, error) {
+	if !b.skipOpenFiles {
+		return nil, nil
+	}
+	open, err := cache.OpenFiles(b.paths)
+	if err != nil {
+		return nil, fmt.Errorf("list open files: %w", err)
+	}
+	return open, nil
+}
+
+// holdsOpenFile reports whether a file in open is one of paths or lies in one
+// of them.
+func holdsOpenFile(open map[string]bool, paths ...string) bool {
+	for path := range open {
+		for _, p := range paths {
+			if path == p || strings.HasPrefix(path, p+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// skipOpen drops the items whose paths hold a file in open, for cleans that
+// remove whole directories or entries rather than single files. The note
+// names every item skipped, one per line.
+func skipOpen[T any](open map[string]bool, items []T, paths func(T) []string, label func(T) string) (kept []T, note string) {
+	if len(open) == 0 {
+		return items, ""
+	}
+	var skipped []string
+	for _, item := range items {
+		if holdsOpenFile(open, paths(item)...) {
+			skipped = append(skipped, "skipping "+label(item)+": has files open in another process")
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept, strings.Join(skipped, "\n")
+}
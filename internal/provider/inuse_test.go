@@ -0,0 +1,243 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedProvider returns a provider whose lock file this test holds, and a
+// func releasing the lock.
+func lockedProvider(t *testing.T, onBusy string) (p *FileProvider, unlock func()) {
+	t.Helper()
+	dir := t.TempDir()
+	lock := filepath.Join(dir, ".package-cache")
+	require.NoError(t, os.WriteFile(lock, nil, 0o600))
+
+	p, err := NewFileProvider("cargo", config.Provider{
+		Paths:     []string{dir},
+		MaxSize:   "1G",
+		LockFiles: []string{filepath.Join(dir, "*-cache")},
+		OnBusy:    onBusy,
+	})
+	require.NoError(t, err)
+
+	f, err := os.Open(lock)
+	require.NoError(t, err)
+	unlock = flockFile(t, f)
+	t.Cleanup(func() { _ = f.Close() })
+	return p, unlock
+}
+
+func TestInUse_LockFile(t *testing.T) {
+	p, unlock := lockedProvider(t, "")
+	assert.Contains(t, p.InUse(), ".package-cache is locked")
+	assert.Equal(t, config.BusySkip, p.OnBusy())
+
+	unlock()
+	assert.Empty(t, p.InUse())
+}
+
+func TestInUse_Process(t *testing.T) {
+	if _, err := os.Stat("/proc/self/comm"); err != nil {
+		t.Skip("needs /proc")
+	}
+	p, err := NewFileProvider("test", config.Provider{
+		Paths:     []string{t.TempDir()},
+		MaxSize:   "1G",
+		Processes: []string{"sleep"},
+	})
+	require.NoError(t, err)
+
+	cmd := exec.Command("sleep", "30")
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	assert.Regexp(t, `^sleep \(pid \d+\) is running$`, p.InUse())
+}
+
+func TestWaitIdle_Policies(t *testing.T) {
+	p, _ := lockedProvider(t, config.BusySkip)
+	err := WaitIdle(t.Context(), p, nil)
+	var inUse *InUseError
+	require.ErrorAs(t, err, &inUse)
+	assert.False(t, inUse.Abort)
+	assert.Equal(t, "cargo", inUse.Provider)
+
+	p, _ = lockedProvider(t, config.BusyAbort)
+	require.ErrorAs(t, WaitIdle(t.Context(), p, nil), &inUse)
+	assert.True(t, inUse.Abort)
+
+	free, err := NewFileProvider("free", config.Provider{Paths: []string{t.TempDir()}, MaxSize: "1G", OnBusy: config.BusyAbort})
+	require.NoError(t, err)
+	require.NoError(t, WaitIdle(t.Context(), free, nil))
+}
+
+func TestWaitIdle_Wait(t *testing.T) {
+	orig := InUsePollInterval
+	InUsePollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { InUsePollInterval = orig })
+
+	p, unlock := lockedProvider(t, config.BusyWait)
+	var waitedFor string
+	time.AfterFunc(50*time.Millisecond, unlock)
+	require.NoError(t, WaitIdle(t.Context(), p, func(reason string) { waitedFor = reason }))
+	assert.Contains(t, waitedFor, "is locked")
+
+	p, _ = lockedProvider(t, config.BusyWait)
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, WaitIdle(ctx, p, nil), context.DeadlineExceeded)
+}
+
+// holdOpen starts a process that keeps path open until the test ends.
+func holdOpen(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("needs /proc")
+	}
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	cmd := exec.Command("sleep", "30")
+	cmd.ExtraFiles = []*os.File{f}
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+}
+
+func TestFileProvider_SkipOpenFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeAged(t, filepath.Join(dir, "index.bin"), 600, 48*time.Hour)
+	writeAged(t, filepath.Join(dir, "old.bin"), 600, 24*time.Hour)
+	holdOpen(t, filepath.Join(dir, "index.bin"))
+
+	p, err := NewFileProvider("test", config.Provider{Paths: []string{dir}, MaxSize: "1K", SkipOpenFiles: true})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.FilesDeleted)
+	assert.FileExists(t, filepath.Join(dir, "index.bin"))
+	assert.NoFileExists(t, filepath.Join(dir, "old.bin"))
+	assert.Contains(t, result.Output, "1 locked")
+}
+
+func TestCommandProvider_SkipOpenFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeAged(t, filepath.Join(dir, "index.bin"), 600, 48*24*time.Hour)
+	writeAged(t, filepath.Join(dir, "old.bin"), 600, 40*24*time.Hour)
+	holdOpen(t, filepath.Join(dir, "index.bin"))
+
+	p, err := NewCommandProvider("test", config.Provider{Paths: []string{dir}, MaxSize: "10G", CleanCmd: "echo cleaned", SkipOpenFiles: true})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.FilesDeleted)
+	assert.FileExists(t, filepath.Join(dir, "index.bin"))
+	assert.NoFileExists(t, filepath.Join(dir, "old.bin"))
+	assert.Contains(t, result.Output, "1 locked")
+}
+
+func TestVersionedProvider_SkipOpenFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"1.0.0", "2.0.0", "3.0.0"} {
+		writeVersionDir(t, dir, name, 100, time.Hour)
+	}
+	holdOpen(t, filepath.Join(dir, "1.0.0", "data.bin"))
+
+	p, err := NewVersionedProvider("test", config.Provider{
+		Type:          config.TypeVersioned,
+		Paths:         []string{dir},
+		MaxSize:       "1G",
+		Pattern:       `(?P<version>\d+\.\d+\.\d+)`,
+		SkipOpenFiles: true,
+	})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull, DryRun: true})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "keep: 1.0.0 (has files open in another process)")
+
+	_, err = p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1.0.0", "3.0.0"}, remainingDirs(t, dir))
+}
+
+func TestGoModProvider_SkipOpenFiles(t *testing.T) {
+	modCache, workspace := setupModCache(t)
+	holdOpen(t, filepath.Join(modCache, "github.com", "foo", "bar@v1.1.0", "sub", "x.go"))
+
+	p := newTestGoModProvider(t, modCache, workspace, "1G")
+	p.skipOpenFiles = true
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "skipping github.com/foo/bar@v1.1.0: has files open in another process")
+	assert.True(t, modVersionExists(modCache, "github.com/foo/bar", "v1.1.0"))
+	assert.False(t, modVersionExists(modCache, "github.com/!burnt!sushi/toml", "v0.9.0"))
+}
+
+func TestCargoProvider_SkipOpenFiles(t *testing.T) {
+	home := setupCargoHome(t)
+	crate := filepath.Join(home, "registry", "cache", testCargoRegistry, "serde-1.0.100.crate")
+	holdOpen(t, crate)
+
+	p := newTestCargoProvider(t, cargoPaths(home), "1G")
+	p.skipOpenFiles = true
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "skipping crate serde-1.0.100.crate: has files open in another process")
+	assert.FileExists(t, crate)
+	assert.NoFileExists(t, filepath.Join(home, "registry", "cache", testCargoRegistry, "wasm-bindgen-0.2.89.crate"))
+}
+
+func TestMavenProvider_SkipOpenFiles(t *testing.T) {
+	repo := setupMavenRepo(t)
+	jar := filepath.Join(repo, "com", "google", "guava", "guava", "30.0-jre", "guava-30.0-jre.jar")
+	build := filepath.Join(repo, "org", "example", "lib", "1.0-SNAPSHOT", "lib-1.0-20240101.000000-1.jar")
+	holdOpen(t, jar)
+	holdOpen(t, build)
+
+	p := newTestMavenProvider(t, repo)
+	p.skipOpenFiles = true
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "skipping com.google.guava:guava:30.0-jre: has files open in another process")
+	assert.Contains(t, result.Output, "skipping snapshot build lib-1.0-20240101.000000-1.jar: open in another process")
+	assert.FileExists(t, jar)
+	assert.FileExists(t, build)
+	assert.NoDirExists(t, filepath.Join(repo, "com", "google", "guava", "guava", "31.0-jre"))
+}
+
+func TestWorkspaceProvider_SkipOpenFiles(t *testing.T) {
+	root := t.TempDir()
+	old := 60 * 24 * time.Hour
+	makeProject(t, filepath.Join(root, "web"), "package.json", []string{"node_modules"}, 100, old)
+	makeProject(t, filepath.Join(root, "crate"), "Cargo.toml", []string{"target"}, 100, old)
+	holdOpen(t, filepath.Join(root, "web", "node_modules", "blob"))
+
+	p := newTestWorkspaceProvider(t, root, "1G")
+	p.skipOpenFiles = true
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "skipping "+filepath.Join(root, "web")+": has files open in another process")
+	assert.DirExists(t, filepath.Join(root, "web", "node_modules"))
+	assert.NoDirExists(t, filepath.Join(root, "crate", "target"))
+}
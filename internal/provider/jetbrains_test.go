@@ -1,48 +1,90 @@
 package provider
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/Automaat/cache-buster/internal/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestCompareVersions(t *testing.T) {
-	tests := []struct {
-		name string
-		v1   string
-		v2   string
-		want int
-	}{
-		{"equal", "2024.1", "2024.1", 0},
-		{"minor less", "2024.1", "2024.2", -1},
-		{"minor greater", "2024.2", "2024.1", 1},
-		{"double digit minor", "2024.9", "2024.10", -1},
-		{"major greater", "2025.1", "2024.9", 1},
-		{"missing segment counts as zero", "2024", "2024.1", -1},
-		{"missing segment equal", "2024", "2024.0", 0},
-	}
+func TestMain(m *testing.M) {
+	// Keep JetBrains tests independent of the IDEs installed on the machine.
+	jetbrainsInstallRoots = nil
+	os.Exit(m.Run())
+}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			assert.Equal(t, tt.want, compareVersions(tt.v1, tt.v2))
-		})
-	}
+// fakeJetBrainsInstalls points install detection at a temp dir holding one
+// macOS app bundle and one Linux Toolbox install, one per dataDirectoryName.
+func fakeJetBrainsInstalls(t *testing.T, macDir, linuxDir string) {
+	t.Helper()
+	root := t.TempDir()
+	writeProductInfo(t, filepath.Join(root, "GoLand.app", "Contents", "Resources", "product-info.json"), macDir)
+	writeProductInfo(t, filepath.Join(root, "apps", "pycharm", "ch-0", "241.1", "product-info.json"), linuxDir)
+
+	saved := jetbrainsInstallRoots
+	jetbrainsInstallRoots = []string{root}
+	t.Cleanup(func() { jetbrainsInstallRoots = saved })
 }
 
-// TestCompareVersions_MalformedSegment verifies a non-numeric segment is not
-// silently coerced to 0: two versions differing only in a malformed segment
-// must order deterministically rather than compare equal.
-func TestCompareVersions_MalformedSegment(t *testing.T) {
-	assert.NotEqual(t, 0, compareVersions("2024.x", "2024.y"),
-		"distinct non-numeric segments must not collapse to equal")
-	assert.Equal(t, -compareVersions("2024.y", "2024.x"),
-		compareVersions("2024.x", "2024.y"), "comparison must be antisymmetric")
+func writeProductInfo(t *testing.T, path, dataDir string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(`{"name":"IDE","dataDirectoryName":"`+dataDir+`"}`), 0o644))
 }
 
-// TestCompareVersions_OutOfRangeSegment verifies a numeric segment too large
-// for int falls back to lexical comparison instead of silently becoming 0.
-func TestCompareVersions_OutOfRangeSegment(t *testing.T) {
-	huge := "99999999999999999999999999"
-	assert.NotEqual(t, 0, compareVersions("2024."+huge, "2024.1"),
-		"out-of-range segment must not collapse to 0")
+func TestInstalledJetBrainsVersions(t *testing.T) {
+	fakeJetBrainsInstalls(t, "GoLand2024.1", "PyCharm2024.1")
+
+	assert.Equal(t, map[string]bool{"GoLand2024.1": true, "PyCharm2024.1": true}, installedJetBrainsVersions())
+}
+
+func TestJetBrainsProvider_InstalledAndUninstalled(t *testing.T) {
+	fakeJetBrainsInstalls(t, "GoLand2024.1", "PyCharm2024.1")
+
+	caches, logs := t.TempDir(), t.TempDir()
+	for _, name := range []string{"GoLand2023.3", "GoLand2024.1", "GoLand2024.2", "WebStorm2023.1", "WebStorm2023.2"} {
+		writeVersionDir(t, caches, name, 100, time.Hour)
+	}
+	writeVersionDir(t, logs, "GoLand2023.3", 10, time.Hour)
+	writeVersionDir(t, logs, "WebStorm2023.2", 10, time.Hour)
+
+	p, err := NewJetBrainsProvider("jetbrains", config.Provider{Paths: []string{caches, logs}, MaxSize: "3G", Keep: 1})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "keep: GoLand2024.2 (newest of GoLand)\n"+
+		"keep: GoLand2024.1 (installed)\n"+
+		"keep: WebStorm2023.2 (newest of WebStorm, no install detected)\n"+
+		"would remove: GoLand2023.3 (110 B)\n"+
+		"would remove: WebStorm2023.1 (100 B, no install detected)", result.Output)
+	assert.Equal(t, int64(210), result.BytesCleaned)
+
+	result, err = p.Clean(t.Context(), CleanOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "removed 1 old version directories\nremoved 1 version directories with no install detected", result.Output)
+	assert.Equal(t, int64(210), result.BytesCleaned)
+	assert.Equal(t, int64(3), result.FilesDeleted) // GoLand2023.3 under both roots, WebStorm2023.1
+	assert.ElementsMatch(t, []string{"GoLand2024.1", "GoLand2024.2", "WebStorm2023.2"}, remainingDirs(t, caches))
+	assert.ElementsMatch(t, []string{"WebStorm2023.2"}, remainingDirs(t, logs))
+}
+
+func TestJetBrainsProvider_Keep(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"GoLand2023.3", "GoLand2024.1", "GoLand2024.2"} {
+		writeVersionDir(t, dir, name, 100, time.Hour)
+	}
+
+	p, err := NewJetBrainsProvider("jetbrains", config.Provider{Paths: []string{dir}, MaxSize: "3G", Keep: 2})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "keep: GoLand2024.2 (newest 2 of GoLand)\n"+
+		"keep: GoLand2024.1 (newest 2 of GoLand)\n"+
+		"would remove: GoLand2023.3 (100 B)", result.Output)
 }
@@ -0,0 +1,342 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/Automaat/cache-buster/pkg/size"
+)
+
+const mavenSnapshotSuffix = "-SNAPSHOT"
+
+// mavenTimestampPattern matches the "<yyyyMMdd.HHmmss>-<build>" part of a
+// deployed snapshot file name, e.g. lib-1.0-20240102.030405-7.jar.
+var mavenTimestampPattern = regexp.MustCompile(`^-(\d{8}\.\d{6})-(\d+)`)
+
+// mavenVersion is a <group>/<artifact>/<version> directory in the local repository.
+type mavenVersion struct {
+	lastAccess time.Time
+	root       string
+	path       string
+	artifact   string // group path and artifactId, e.g. com/google/guava/guava
+	version    string
+	size       int64
+}
+
+// coordinates formats the version as group:artifact:version.
+func (v *mavenVersion) coordinates() string {
+	i := strings.LastIndex(v.artifact, "/")
+	return strings.ReplaceAll(v.artifact[:max(i, 0)], "/", ".") + ":" + v.artifact[i+1:] + ":" + v.version
+}
+
+// MavenProvider cleans the local Maven repository (~/.m2/repository). Old
+// timestamped SNAPSHOT builds are always pruned down to the newest one; whole
+// version directories go by last access, sparing the newest keep releases and
+// the newest snapshot of each artifact.
+type MavenProvider struct {
+	*BaseProvider
+	keep int
+}
+
+// NewMavenProvider creates a Maven local repository provider.
+func NewMavenProvider(name string, cfg config.Provider) (*MavenProvider, error) {
+	base, err := NewBaseProvider(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MavenProvider{
+		BaseProvider: base,
+		keep:         cfg.Keep,
+	}, nil
+}
+
+// Clean implements Provider. Both modes prune stale snapshot builds. Full mode
+// then removes every version but the newest keep releases and newest snapshot; smart mode removes
+// versions not accessed for max_age, then more in eviction order while over max_size less headroom.
+func (p *MavenProvider) Clean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
+	versions, err := p.findVersions(ctx)
+	if err != nil {
+		return CleanResult{}, err
+	}
+
+	var (
+		result  CleanResult
+		output  strings.Builder
+		remover = cache.Remover{Roots: p.paths}
+	)
+
+	current, err := p.CurrentSize(ctx)
+	if err != nil {
+		return CleanResult{}, err
+	}
+	open, err := p.openFiles()
+	if err != nil {
+		return CleanResult{}, err
+	}
+
+	for _, v := range versions {
+		if !strings.HasSuffix(v.version, mavenSnapshotSuffix) {
+			continue
+		}
+		freed, deleted, err := p.pruneSnapshotBuilds(v, open, opts.DryRun, &remover, &output)
+		if err != nil {
+			return result, err
+		}
+		result.BytesCleaned += freed
+		result.FilesDeleted += deleted
+		v.size -= freed
+		current -= freed
+	}
+
+	removable, skipped := skipOpen(open, p.removableVersions(versions),
+		func(v *mavenVersion) []string { return []string{v.path} }, (*mavenVersion).coordinates)
+	if skipped != "" {
+		output.WriteString(skipped + "\n")
+	}
+	removable = p.selectVersions(removable, current, opts.Mode)
+	for _, v := range removable {
+		if err := ctx.Err(); err != nil {
+			result.Output = "interrupted"
+			return result, err
+		}
+
+		label := v.coordinates()
+		if opts.DryRun {
+			fmt.Fprintf(&output, "would remove: %s (%s)\n", label, size.FormatSize(v.size))
+			result.BytesCleaned += v.size
+			continue
+		}
+		if err := os.RemoveAll(v.path); err != nil {
+			fmt.Fprintf(&output, "error removing %s: %v\n", label, err)
+			continue
+		}
+		result.BytesCleaned += v.size
+		result.FilesDeleted++
+	}
+
+	for _, e := range remover.Restore() {
+		fmt.Fprintf(&output, "warning: %v\n", e)
+	}
+	result.Fixups = remover.Fixups()
+
+	if opts.DryRun {
+		result.Output = strings.TrimSpace(output.String())
+		if result.Output == "" {
+			result.Output = "nothing to clean"
+		}
+	} else {
+		result.Output = fmt.Sprintf("freed %s from %d files and version directories", size.FormatSize(result.BytesCleaned), result.FilesDeleted)
+		if output.Len() > 0 {
+			result.Output += "\n" + strings.TrimSpace(output.String())
+		}
+	}
+	result.Output = withWarning(p.lruWarning(), result.Output)
+	return result, nil
+}
+
+// removableVersions drops the newest keep release versions of each artifact,
+// and its newest snapshot version: that may be a local mvn install other
+// builds depend on. Snapshots do not count toward keep; keep 0 spares none.
+func (p *MavenProvider) removableVersions(versions []*mavenVersion) []*mavenVersion {
+	byArtifact := make(map[string][]*mavenVersion)
+	for _, v := range versions {
+		key := v.root + "\x00" + v.artifact
+		byArtifact[key] = append(byArtifact[key], v)
+	}
+
+	var removable []*mavenVersion
+	for _, group := range byArtifact {
+		sort.Slice(group, func(i, j int) bool {
+			return compareVersions(group[i].version, group[j].version) > 0
+		})
+		kept, keptSnapshot := 0, false
+		for _, v := range group {
+			if strings.HasSuffix(v.version, mavenSnapshotSuffix) {
+				if !keptSnapshot && p.keep > 0 {
+					keptSnapshot = true
+					continue
+				}
+			} else if kept < p.keep {
+				kept++
+				continue
+			}
+			removable = append(removable, v)
+		}
+	}
+	return removable
+}
+
+// selectVersions puts removable versions in eviction order and picks the ones the mode removes.
+func (p *MavenProvider) selectVersions(removable []*mavenVersion, current int64, mode CleanMode) []*mavenVersion {
+	evictionOrder(p.eviction, removable, func(v *mavenVersion) cache.FileInfo {
+		return cache.FileInfo{Path: v.path, Size: v.size, LastUsed: v.lastAccess}
+	})
+	if mode == CleanModeFull {
+		return removable
+	}
+
+	cutoff := time.Now().Add(-p.maxAge)
+	target := p.sizeTarget()
+	var selected []*mavenVersion
+	for _, v := range removable {
+		if (p.maxAge > 0 && v.lastAccess.Before(cutoff)) || current > target {
+			selected = append(selected, v)
+			current -= v.size
+		}
+	}
+	return selected
+}
+
+// pruneSnapshotBuilds deletes files of every timestamped build in a SNAPSHOT
+// version dir except the newest. Locally installed -SNAPSHOT files are kept,
+// as are files in open, which another process has open.
+func (p *MavenProvider) pruneSnapshotBuilds(v *mavenVersion, open map[string]bool, dryRun bool, remover *cache.Remover, output *strings.Builder) (freed, deleted int64, err error) {
+	entries, err := os.ReadDir(v.path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	prefix := filepath.Base(filepath.Dir(v.path)) + "-" + strings.TrimSuffix(v.version, mavenSnapshotSuffix)
+	builds := make(map[string][]fs.DirEntry)
+	var newest string
+	for _, e := range entries {
+		name, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		m := mavenTimestampPattern.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		number, _ := strconv.Atoi(m[2])
+		build := fmt.Sprintf("%s-%08d", m[1], number) // zero-padded so builds order lexically
+		builds[build] = append(builds[build], e)
+		if build > newest {
+			newest = build
+		}
+	}
+
+	for build, files := range builds {
+		if build == newest {
+			continue
+		}
+		for _, e := range files {
+			path := filepath.Join(v.path, e.Name())
+			if p.filter.Excluded(v.root, path) || p.filter.Protected(v.root, path) {
+				continue
+			}
+			if open[path] {
+				fmt.Fprintf(output, "skipping snapshot build %s: open in another process\n", e.Name())
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			if dryRun {
+				fmt.Fprintf(output, "would remove snapshot build: %s\n", e.Name())
+			} else if err := remover.Remove(path); err != nil {
+				fmt.Fprintf(output, "error removing %s: %v\n", e.Name(), err)
+				continue
+			}
+			freed += info.Size()
+			deleted++
+		}
+	}
+	return freed, deleted, nil
+}
+
+// findVersions walks the repositories for version directories: dirs holding
+// files named <artifactId>-<version>*, with artifactId the parent dir's name.
+func (p *MavenProvider) findVersions(ctx context.Context) ([]*mavenVersion, error) {
+	var versions []*mavenVersion
+
+	for _, root := range p.paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil || !d.IsDir() || path == root {
+				return nil
+			}
+			if !isMavenVersionDir(path) {
+				return nil
+			}
+
+			guarded, err := p.filter.Guards(root, path)
+			if err != nil {
+				return err
+			}
+			if !guarded {
+				v, err := scanMavenVersion(root, path, p.lruKey)
+				if err != nil {
+					return err
+				}
+				versions = append(versions, v)
+			}
+			return filepath.SkipDir
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("scan %s: %w", root, err)
+		}
+	}
+
+	return versions, nil
+}
+
+func isMavenVersionDir(path string) bool {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return false
+	}
+	version := filepath.Base(path)
+	prefix := filepath.Base(filepath.Dir(path)) + "-" + strings.TrimSuffix(version, mavenSnapshotSuffix)
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanMavenVersion sizes a version dir; last access is the newest time key
+// reads from its files, since Maven itself records nothing on reads.
+func scanMavenVersion(root, path string, key cache.LRUKey) (*mavenVersion, error) {
+	artifact, err := filepath.Rel(root, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	v := &mavenVersion{
+		root:     root,
+		path:     path,
+		artifact: filepath.ToSlash(artifact),
+		version:  filepath.Base(path),
+	}
+
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		v.size += info.Size()
+		if t := key.Time(info); t.After(v.lastAccess) {
+			v.lastAccess = t
+		}
+		return nil
+	})
+	return v, err
+}
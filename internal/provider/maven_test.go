@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupMavenRepo builds a local repository with three guava releases and a
+// snapshot holding two deployed builds plus a local install.
+func setupMavenRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	old := 90 * 24 * time.Hour
+
+	guava := filepath.Join(repo, "com", "google", "guava", "guava")
+	for version, age := range map[string]time.Duration{"30.0-jre": old, "31.0-jre": old, "33.0-jre": time.Hour} {
+		writeAged(t, filepath.Join(guava, version, "guava-"+version+".jar"), 100, age)
+		writeAged(t, filepath.Join(guava, version, "guava-"+version+".pom"), 10, age)
+	}
+	writeAged(t, filepath.Join(guava, "maven-metadata-central.xml"), 10, old)
+
+	snapshot := filepath.Join(repo, "org", "example", "lib", "1.0-SNAPSHOT")
+	writeAged(t, filepath.Join(snapshot, "lib-1.0-20240101.000000-1.jar"), 50, time.Hour)
+	writeAged(t, filepath.Join(snapshot, "lib-1.0-20240101.000000-1.pom"), 5, time.Hour)
+	writeAged(t, filepath.Join(snapshot, "lib-1.0-20240102.000000-2.jar"), 50, time.Hour)
+	writeAged(t, filepath.Join(snapshot, "lib-1.0-20240102.000000-2.pom"), 5, time.Hour)
+	writeAged(t, filepath.Join(snapshot, "lib-1.0-SNAPSHOT.jar"), 50, time.Hour)
+	writeAged(t, filepath.Join(snapshot, "maven-metadata-remote.xml"), 5, time.Hour)
+
+	return repo
+}
+
+func newTestMavenProvider(t *testing.T, repo string) *MavenProvider {
+	t.Helper()
+	p, err := NewMavenProvider("maven", config.Provider{Paths: []string{repo}, MaxSize: "1G", MaxAge: "60d", Keep: 1})
+	require.NoError(t, err)
+	return p
+}
+
+func TestMavenProvider_FindVersions(t *testing.T) {
+	repo := setupMavenRepo(t)
+	p := newTestMavenProvider(t, repo)
+
+	versions, err := p.findVersions(t.Context())
+	require.NoError(t, err)
+
+	var coords []string
+	for _, v := range versions {
+		coords = append(coords, v.coordinates())
+	}
+	assert.ElementsMatch(t, []string{
+		"com.google.guava:guava:30.0-jre",
+		"com.google.guava:guava:31.0-jre",
+		"com.google.guava:guava:33.0-jre",
+		"org.example:lib:1.0-SNAPSHOT",
+	}, coords)
+}
+
+func TestMavenProvider_SmartClean(t *testing.T) {
+	repo := setupMavenRepo(t)
+	p := newTestMavenProvider(t, repo)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+
+	// Two old releases (110 each) and the older snapshot build (55).
+	assert.Equal(t, int64(275), result.BytesCleaned)
+
+	guava := filepath.Join(repo, "com", "google", "guava", "guava")
+	assert.NoDirExists(t, filepath.Join(guava, "30.0-jre"))
+	assert.NoDirExists(t, filepath.Join(guava, "31.0-jre"))
+	assert.DirExists(t, filepath.Join(guava, "33.0-jre"))
+
+	snapshot := filepath.Join(repo, "org", "example", "lib", "1.0-SNAPSHOT")
+	assert.NoFileExists(t, filepath.Join(snapshot, "lib-1.0-20240101.000000-1.jar"))
+	assert.NoFileExists(t, filepath.Join(snapshot, "lib-1.0-20240101.000000-1.pom"))
+	assert.FileExists(t, filepath.Join(snapshot, "lib-1.0-20240102.000000-2.jar"))
+	assert.FileExists(t, filepath.Join(snapshot, "lib-1.0-SNAPSHOT.jar"))
+}
+
+func TestMavenProvider_KeepsNewestReleaseRegardlessOfAge(t *testing.T) {
+	repo := t.TempDir()
+	dir := filepath.Join(repo, "org", "old", "tool", "2.0")
+	writeAged(t, filepath.Join(dir, "tool-2.0.jar"), 100, 365*24*time.Hour)
+	writeAged(t, filepath.Join(repo, "org", "old", "tool", "1.0", "tool-1.0.jar"), 100, 365*24*time.Hour)
+
+	p := newTestMavenProvider(t, repo)
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(100), result.BytesCleaned)
+	assert.DirExists(t, dir)
+}
+
+func TestMavenProvider_LRUKey(t *testing.T) {
+	repo := setupMavenRepo(t)
+	guava := filepath.Join(repo, "com", "google", "guava", "guava")
+	// 31.0-jre was downloaded long ago but read by a recent build.
+	jar := filepath.Join(guava, "31.0-jre", "guava-31.0-jre.jar")
+	require.NoError(t, os.Chtimes(jar, time.Now(), time.Now().Add(-90*24*time.Hour)))
+
+	p, err := NewMavenProvider("maven", config.Provider{
+		Paths: []string{repo}, MaxSize: "1G", MaxAge: "60d", Keep: 1, LRUKey: config.LRUAtime,
+	})
+	require.NoError(t, err)
+
+	_, err = p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(guava, "30.0-jre"))
+	assert.FileExists(t, jar)
+}
+
+func TestMavenProvider_FullClean(t *testing.T) {
+	repo := setupMavenRepo(t)
+	p := newTestMavenProvider(t, repo)
+
+	_, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+
+	versions, err := p.findVersions(t.Context())
+	require.NoError(t, err)
+	var coords []string
+	for _, v := range versions {
+		coords = append(coords, v.coordinates())
+	}
+	assert.ElementsMatch(t, []string{"com.google.guava:guava:33.0-jre", "org.example:lib:1.0-SNAPSHOT"}, coords)
+	assert.FileExists(t, filepath.Join(repo, "org", "example", "lib", "1.0-SNAPSHOT", "lib-1.0-SNAPSHOT.jar"))
+}
+
+func TestMavenProvider_KeepsNewestSnapshot(t *testing.T) {
+	repo := t.TempDir()
+	lib := filepath.Join(repo, "org", "example", "lib")
+	writeAged(t, filepath.Join(lib, "1.0-SNAPSHOT", "lib-1.0-SNAPSHOT.jar"), 100, 365*24*time.Hour)
+	writeAged(t, filepath.Join(lib, "1.1-SNAPSHOT", "lib-1.1-SNAPSHOT.jar"), 100, 365*24*time.Hour)
+	writeAged(t, filepath.Join(lib, "1.0", "lib-1.0.jar"), 100, 365*24*time.Hour)
+
+	p := newTestMavenProvider(t, repo)
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(100), result.BytesCleaned)
+	assert.NoDirExists(t, filepath.Join(lib, "1.0-SNAPSHOT"))
+	assert.DirExists(t, filepath.Join(lib, "1.1-SNAPSHOT"))
+	assert.DirExists(t, filepath.Join(lib, "1.0"))
+}
+
+func TestMavenProvider_DryRun(t *testing.T) {
+	repo := setupMavenRepo(t)
+	p := newTestMavenProvider(t, repo)
+
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true, Mode: CleanModeSmart})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(275), result.BytesCleaned)
+	assert.Contains(t, result.Output, "would remove snapshot build: lib-1.0-20240101.000000-1.jar")
+	assert.Contains(t, result.Output, "would remove: com.google.guava:guava:30.0-jre")
+	assert.False(t, strings.Contains(result.Output, "lib-1.0-20240102"), "newest snapshot build is kept")
+	assert.DirExists(t, filepath.Join(repo, "com", "google", "guava", "guava", "30.0-jre"))
+}
+
+func TestNewProvider_Maven(t *testing.T) {
+	p, err := NewProvider("maven", config.Provider{Paths: []string{t.TempDir()}, MaxSize: "1G"})
+	require.NoError(t, err)
+	assert.IsType(t, &MavenProvider{}, p)
+}
@@ -0,0 +1,470 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1" //nolint:gosec // cacache hashes index lines with sha1
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/Automaat/cache-buster/pkg/size"
+)
+
+// cacache layout under the npm cache directory.
+const (
+	cacacheDir     = "_cacache"
+	cacacheIndex   = "index-v5"
+	cacacheContent = "content-v2"
+)
+
+// integrityRank orders the hash algorithms cacache picks content paths by, strongest last.
+var integrityRank = map[string]int{"sha1": 1, "sha256": 2, "sha384": 3, "sha512": 4}
+
+// NpmProvider cleans npm's content-addressed cache (_cacache) by removing
+// index entries together with the content they point at, so npm never sees
+// an entry whose blob is gone. Content no entry references is garbage collected.
+type NpmProvider struct {
+	*BaseProvider
+	cleanCmd string // run by full cleans instead when set
+}
+
+// NewNpmProvider creates an npm cache provider.
+func NewNpmProvider(name string, cfg config.Provider) (*NpmProvider, error) {
+	base, err := NewBaseProvider(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NpmProvider{
+		BaseProvider: base,
+		cleanCmd:     cfg.CleanCmd,
+	}, nil
+}
+
+// cacacheEntry is the latest index line for a key.
+type cacacheEntry struct {
+	Integrity *string `json:"integrity"`
+	Key       string  `json:"key"`
+	line      string
+	Time      int64 `json:"time"` // unix milliseconds of the last write
+}
+
+// cacacheBlob is one content file with the index entries that reference it;
+// they are removed as a unit.
+type cacacheBlob struct {
+	lastAccess time.Time
+	path       string
+	entries    []*cacacheEntry
+	size       int64
+}
+
+// cacacheState is a parsed _cacache directory.
+type cacacheState struct {
+	buckets     map[string][]*cacacheEntry // bucket file -> live entries, in file order
+	orphanSizes map[string]int64
+	blobs       []*cacacheBlob
+	orphans     []string        // content files without a live entry
+	missing     []*cacacheEntry // live entries whose content is gone
+	orphanSize  int64
+}
+
+// Clean implements Provider. Both modes garbage collect orphaned content and
+// index entries whose content is missing. Full mode then removes entries in
+// eviction order until under max_size; smart mode first removes entries older
+// than max_age, then goes on to max_size less headroom. A configured clean_cmd (e.g. npm cache clean
+// --force) replaces full mode.
+func (p *NpmProvider) Clean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
+	if opts.Mode == CleanModeFull && p.cleanCmd != "" {
+		return runCleanCmd(ctx, p.name, p.cleanCmd, opts.DryRun, p.CurrentSize)
+	}
+
+	current, err := p.CurrentSize(ctx)
+	if err != nil {
+		return CleanResult{}, err
+	}
+
+	open, err := p.openFiles()
+	if err != nil {
+		return CleanResult{}, err
+	}
+
+	var (
+		result  CleanResult
+		output  strings.Builder
+		skipped []string
+	)
+	for _, dir := range p.cacacheDirs() {
+		state, err := loadCacache(ctx, dir, p.lruKey)
+		if err != nil {
+			return result, err
+		}
+		if note := state.skipOpen(open); note != "" {
+			skipped = append(skipped, note)
+		}
+		current -= state.orphanSize
+
+		blobs := p.selectBlobs(state.blobs, current, opts.Mode)
+		for _, b := range blobs {
+			current -= b.size
+		}
+
+		res, err := p.applyCacache(ctx, state, blobs, opts.DryRun, &output)
+		result.BytesCleaned += res.BytesCleaned
+		result.FilesDeleted += res.FilesDeleted
+		result.Fixups += res.Fixups
+		if err != nil {
+			result.Output = "interrupted"
+			return result, err
+		}
+	}
+
+	if opts.DryRun {
+		result.Output = strings.TrimSpace(output.String())
+		if result.Output == "" {
+			result.Output = "nothing to clean"
+		}
+	} else {
+		result.Output = fmt.Sprintf("removed %d cache entries and blobs", result.FilesDeleted)
+		if output.Len() > 0 {
+			result.Output += "\n" + strings.TrimSpace(output.String())
+		}
+	}
+	result.Output = withWarning(p.lruWarning(), withWarning(strings.Join(skipped, "\n"), result.Output))
+	return result, nil
+}
+
+// skipOpen drops the blobs and orphaned content files another process has
+// open, returning a note naming each.
+func (s *cacacheState) skipOpen(open map[string]bool) string {
+	blobs, blobNote := skipOpen(open, s.blobs,
+		func(b *cacacheBlob) []string { return []string{b.path} },
+		func(b *cacacheBlob) string { return cacacheKeyLabel(b.entries[0].Key) })
+	orphans, orphanNote := skipOpen(open, s.orphans,
+		func(path string) []string { return []string{path} },
+		func(path string) string { return "orphaned content " + filepath.Base(path) })
+
+	s.blobs, s.orphans, s.orphanSize = blobs, orphans, 0
+	for _, path := range orphans {
+		s.orphanSize += s.orphanSizes[path]
+	}
+	return withWarning(blobNote, orphanNote)
+}
+
+// cacacheDirs returns the _cacache directories under the provider paths.
+// A path that is itself a cacache directory is used as is.
+func (p *NpmProvider) cacacheDirs() []string {
+	var dirs []string
+	for _, path := range p.paths {
+		for _, dir := range []string{filepath.Join(path, cacacheDir), path} {
+			if info, err := os.Stat(filepath.Join(dir, cacacheIndex)); err == nil && info.IsDir() {
+				dirs = append(dirs, dir)
+				break
+			}
+		}
+	}
+	return dirs
+}
+
+// selectBlobs picks blobs to remove, in eviction order.
+func (p *NpmProvider) selectBlobs(blobs []*cacacheBlob, current int64, mode CleanMode) []*cacacheBlob {
+	evictionOrder(p.eviction, blobs, func(b *cacacheBlob) cache.FileInfo {
+		return cache.FileInfo{Path: b.path, Size: b.size, LastUsed: b.lastAccess}
+	})
+
+	cutoff := time.Now().Add(-p.maxAge)
+	target := p.maxSize
+	if mode == CleanModeSmart {
+		target = p.sizeTarget()
+	}
+	var selected []*cacacheBlob
+	for _, b := range blobs {
+		expired := mode == CleanModeSmart && p.maxAge > 0 && b.lastAccess.Before(cutoff)
+		if !expired && current <= target {
+			continue
+		}
+		if p.guardedPath(b.path) {
+			continue
+		}
+		selected = append(selected, b)
+		current -= b.size
+	}
+	return selected
+}
+
+// applyCacache removes orphaned content, then the selected blobs, and rewrites
+// every index bucket that lost entries.
+func (p *NpmProvider) applyCacache(ctx context.Context, state *cacacheState, blobs []*cacacheBlob, dryRun bool, output *strings.Builder) (CleanResult, error) {
+	var (
+		result  CleanResult
+		remover = cache.Remover{Roots: p.paths}
+	)
+	defer func() {
+		for _, e := range remover.Restore() {
+			fmt.Fprintf(output, "warning: %v\n", e)
+		}
+	}()
+
+	dropped := make(map[*cacacheEntry]bool)
+	for _, e := range state.missing {
+		dropped[e] = true
+	}
+
+	for _, path := range state.orphans {
+		if p.guardedPath(path) {
+			continue
+		}
+		if dryRun {
+			fmt.Fprintf(output, "would remove orphaned content: %s\n", filepath.Base(path))
+			result.BytesCleaned += state.orphanSizes[path]
+			continue
+		}
+		if err := remover.Remove(path); err != nil {
+			fmt.Fprintf(output, "error removing %s: %v\n", path, err)
+			continue
+		}
+		result.FilesDeleted++
+		result.BytesCleaned += state.orphanSizes[path]
+	}
+
+	for _, b := range blobs {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		if dryRun {
+			fmt.Fprintf(output, "would remove: %s (%s)\n", cacacheKeyLabel(b.entries[0].Key), size.FormatSize(b.size))
+			result.BytesCleaned += b.size
+			continue
+		}
+		if err := remover.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(output, "error removing %s: %v\n", b.path, err)
+			continue
+		}
+		for _, e := range b.entries {
+			dropped[e] = true
+		}
+		result.BytesCleaned += b.size
+		result.FilesDeleted++
+	}
+	result.Fixups = remover.Fixups()
+
+	if dryRun {
+		return result, nil
+	}
+	for bucket, entries := range state.buckets {
+		if err := rewriteBucket(bucket, entries, dropped); err != nil {
+			fmt.Fprintf(output, "error rewriting index %s: %v\n", bucket, err)
+		}
+	}
+	return result, nil
+}
+
+// guardedPath reports whether path matches an exclude or protect pattern.
+func (p *NpmProvider) guardedPath(path string) bool {
+	for _, root := range p.paths {
+		if p.filter.Excluded(root, path) || p.filter.Protected(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteBucket writes back the bucket's entries that were not dropped. Only
+// buckets that lost an entry are touched; an emptied bucket is removed.
+func rewriteBucket(bucket string, entries []*cacacheEntry, dropped map[*cacacheEntry]bool) error {
+	var kept []string
+	for _, e := range entries {
+		if !dropped[e] {
+			kept = append(kept, e.line)
+		}
+	}
+	if len(kept) == len(entries) {
+		return nil
+	}
+	if len(kept) == 0 {
+		return os.Remove(bucket)
+	}
+
+	tmp := bucket + ".tmp"
+	if err := os.WriteFile(tmp, []byte("\n"+strings.Join(kept, "\n")), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, bucket)
+}
+
+// loadCacache parses every index bucket and matches entries to content files.
+// A blob was last used at the later of its newest entry's write and the time
+// key reads from its content file.
+func loadCacache(ctx context.Context, dir string, key cache.LRUKey) (*cacacheState, error) {
+	state := &cacacheState{
+		buckets:     make(map[string][]*cacacheEntry),
+		orphanSizes: make(map[string]int64),
+	}
+
+	err := filepath.WalkDir(filepath.Join(dir, cacacheIndex), func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		entries, err := readBucket(path)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			state.buckets[path] = entries
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", cacacheIndex, err)
+	}
+
+	contents, err := cacacheContents(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make(map[string]*cacacheBlob)
+	for _, entries := range state.buckets {
+		for _, e := range entries {
+			path := contentPath(dir, *e.Integrity)
+			info, ok := contents[path]
+			if path == "" || !ok {
+				state.missing = append(state.missing, e)
+				continue
+			}
+			b := blobs[path]
+			if b == nil {
+				b = &cacacheBlob{path: path, size: info.Size(), lastAccess: key.Time(info)}
+				blobs[path] = b
+				state.blobs = append(state.blobs, b)
+			}
+			b.entries = append(b.entries, e)
+			if t := time.UnixMilli(e.Time); t.After(b.lastAccess) {
+				b.lastAccess = t
+			}
+		}
+	}
+
+	for path, info := range contents {
+		if blobs[path] == nil {
+			state.orphans = append(state.orphans, path)
+			state.orphanSizes[path] = info.Size()
+			state.orphanSize += info.Size()
+		}
+	}
+	sort.Strings(state.orphans)
+
+	return state, nil
+}
+
+// readBucket returns the live entries of an index bucket: the last valid line
+// per key, skipping deletions (null integrity) and lines whose hash does not match.
+func readBucket(path string) ([]*cacacheEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	latest := make(map[string]*cacacheEntry)
+	var order []string
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		hash, data, ok := strings.Cut(line, "\t")
+		if !ok || hashEntry(data) != hash {
+			continue
+		}
+		var e cacacheEntry
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			continue
+		}
+		e.line = line
+		if _, seen := latest[e.Key]; !seen {
+			order = append(order, e.Key)
+		}
+		latest[e.Key] = &e
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var entries []*cacacheEntry
+	for _, key := range order {
+		if e := latest[key]; e.Integrity != nil && *e.Integrity != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// cacacheContents maps every file under content-v2 to its info.
+func cacacheContents(ctx context.Context, dir string) (map[string]fs.FileInfo, error) {
+	contents := make(map[string]fs.FileInfo)
+	err := filepath.WalkDir(filepath.Join(dir, cacacheContent), func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			contents[path] = info
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read %s: %w", cacacheContent, err)
+	}
+	return contents, nil
+}
+
+// contentPath returns where cacache stores content for an SRI integrity
+// string, using its strongest hash, or "" if none parses.
+func contentPath(dir, integrity string) string {
+	var algo, digest string
+	for _, token := range strings.Fields(integrity) {
+		a, d, ok := strings.Cut(token, "-")
+		if !ok || integrityRank[a] <= integrityRank[algo] {
+			continue
+		}
+		algo, digest = a, d
+	}
+	if algo == "" {
+		return ""
+	}
+
+	digest, _, _ = strings.Cut(digest, "?") // drop SRI options
+	sum, err := base64.StdEncoding.DecodeString(digest)
+	if err != nil || len(sum) < 3 {
+		return ""
+	}
+	h := hex.EncodeToString(sum)
+	return filepath.Join(dir, cacacheContent, algo, h[:2], h[2:4], h[4:])
+}
+
+// hashEntry is the sha1 hex digest cacache prefixes each index line with.
+func hashEntry(data string) string {
+	sum := sha1.Sum([]byte(data)) //nolint:gosec // cacache's line checksum, not a security boundary
+	return hex.EncodeToString(sum[:])
+}
+
+// cacacheKeyLabel shortens npm's request-cache keys to their URL.
+func cacacheKeyLabel(key string) string {
+	return strings.TrimPrefix(key, "make-fetch-happen:request-cache:")
+}
@@ -0,0 +1,238 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bucketPath(cacache, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	h := hex.EncodeToString(sum[:])
+	return filepath.Join(cacache, cacacheIndex, h[:2], h[2:4], h[4:])
+}
+
+func appendIndexLine(t *testing.T, cacache, key string, integrity any, age time.Duration) {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"key":       key,
+		"integrity": integrity,
+		"time":      time.Now().Add(-age).UnixMilli(),
+		"size":      0,
+		"metadata":  map[string]string{"url": key},
+	})
+	require.NoError(t, err)
+
+	bucket := bucketPath(cacache, key)
+	require.NoError(t, os.MkdirAll(filepath.Dir(bucket), 0o755))
+	f, err := os.OpenFile(bucket, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString("\n" + hashEntry(string(data)) + "\t" + string(data))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+// writeCacacheEntry stores content and an index entry for key the way cacache does.
+func writeCacacheEntry(t *testing.T, cacache, key string, content []byte, age time.Duration) string {
+	t.Helper()
+	sum := sha512.Sum512(content)
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	path := contentPath(cacache, integrity)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	if _, err := os.Stat(path); os.IsNotExist(err) { // content is shared, written once
+		require.NoError(t, os.WriteFile(path, content, 0o444))
+	}
+	when := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(path, when, when))
+
+	appendIndexLine(t, cacache, key, integrity, age)
+	return path
+}
+
+func liveKeys(t *testing.T, cacache string) []string {
+	t.Helper()
+	state, err := loadCacache(t.Context(), cacache, config.LRUMtime)
+	require.NoError(t, err)
+	var keys []string
+	for _, b := range state.blobs {
+		for _, e := range b.entries {
+			keys = append(keys, e.Key)
+		}
+	}
+	return keys
+}
+
+func newTestNpmProvider(t *testing.T, dir, maxSize string) *NpmProvider {
+	t.Helper()
+	p, err := NewNpmProvider("npm", config.Provider{Paths: []string{dir}, MaxSize: maxSize, MaxAge: "30d"})
+	require.NoError(t, err)
+	return p
+}
+
+func TestLoadCacache(t *testing.T) {
+	npmDir := t.TempDir()
+	cacache := filepath.Join(npmDir, cacacheDir)
+
+	writeCacacheEntry(t, cacache, "pkg-a", []byte("aaaa"), 0)
+	writeCacacheEntry(t, cacache, "pkg-b", []byte("bbbb"), 0)
+	appendIndexLine(t, cacache, "pkg-b", nil, 0) // npm cache rm tombstone
+	appendIndexLine(t, cacache, "pkg-gone", "sha512-"+base64.StdEncoding.EncodeToString(make([]byte, 64)), 0)
+
+	state, err := loadCacache(t.Context(), cacache, config.LRUMtime)
+	require.NoError(t, err)
+
+	require.Len(t, state.blobs, 1)
+	assert.Equal(t, "pkg-a", state.blobs[0].entries[0].Key)
+	assert.Equal(t, int64(4), state.blobs[0].size)
+	assert.Len(t, state.orphans, 1, "pkg-b content is orphaned by its tombstone")
+	assert.Equal(t, int64(4), state.orphanSize)
+	require.Len(t, state.missing, 1)
+	assert.Equal(t, "pkg-gone", state.missing[0].Key)
+}
+
+func TestReadBucket_SkipsCorruptLines(t *testing.T) {
+	dir := t.TempDir()
+	bucket := filepath.Join(dir, "bucket")
+	good := `{"key":"k","integrity":"sha512-x","time":1}`
+	content := "\n" + hashEntry(good) + "\t" + good + "\nbadhash\t{\"key\":\"k2\"}\ngarbage"
+	require.NoError(t, os.WriteFile(bucket, []byte(content), 0o644))
+
+	entries, err := readBucket(bucket)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "k", entries[0].Key)
+}
+
+func TestContentPath_StrongestAlgorithm(t *testing.T) {
+	sha1Digest := base64.StdEncoding.EncodeToString(make([]byte, 20))
+	sha512Digest := base64.StdEncoding.EncodeToString([]byte{0xab, 0xcd, 0xef, 0x01})
+
+	path := contentPath("/c", "sha1-"+sha1Digest+" sha512-"+sha512Digest)
+	assert.Equal(t, filepath.Join("/c", cacacheContent, "sha512", "ab", "cd", "ef01"), path)
+	assert.Empty(t, contentPath("/c", "md5-xyz"))
+}
+
+func TestNpmProvider_SmartClean(t *testing.T) {
+	npmDir := t.TempDir()
+	cacache := filepath.Join(npmDir, cacacheDir)
+
+	oldPath := writeCacacheEntry(t, cacache, "old", make([]byte, 100), 60*24*time.Hour)
+	newPath := writeCacacheEntry(t, cacache, "new", make([]byte, 50), time.Hour)
+	writeCacacheEntry(t, cacache, "orphan", []byte("orphan"), time.Hour)
+	appendIndexLine(t, cacache, "orphan", nil, 0)
+	appendIndexLine(t, cacache, "gone", "sha512-"+base64.StdEncoding.EncodeToString(make([]byte, 64)), 0)
+
+	p := newTestNpmProvider(t, npmDir, "1G")
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(106), result.BytesCleaned)
+	assert.Equal(t, int64(2), result.FilesDeleted)
+	assert.NoFileExists(t, oldPath)
+	assert.FileExists(t, newPath)
+	assert.Equal(t, []string{"new"}, liveKeys(t, cacache))
+	assert.NoFileExists(t, bucketPath(cacache, "old"))
+	assert.NoFileExists(t, bucketPath(cacache, "gone"), "entries without content are dropped")
+}
+
+func TestNpmProvider_FullClean(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantKeys []string
+		overBy   int64
+	}{
+		{name: "under max_size only collects garbage", overBy: 0, wantKeys: []string{"newer", "older"}},
+		{name: "over max_size removes least recently written", overBy: 60, wantKeys: []string{"newer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			npmDir := t.TempDir()
+			cacache := filepath.Join(npmDir, cacacheDir)
+			writeCacacheEntry(t, cacache, "older", make([]byte, 100), 2*time.Hour)
+			writeCacacheEntry(t, cacache, "newer", make([]byte, 50), time.Hour)
+
+			p := newTestNpmProvider(t, npmDir, "1G")
+			current, err := p.CurrentSize(t.Context())
+			require.NoError(t, err)
+			p.maxSize = current - tt.overBy
+
+			_, err = p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+			require.NoError(t, err)
+
+			keys := liveKeys(t, cacache)
+			assert.ElementsMatch(t, tt.wantKeys, keys)
+		})
+	}
+}
+
+func TestNpmProvider_SharedContentRemovedWithAllEntries(t *testing.T) {
+	npmDir := t.TempDir()
+	cacache := filepath.Join(npmDir, cacacheDir)
+	path := writeCacacheEntry(t, cacache, "tarball-url", make([]byte, 100), 60*24*time.Hour)
+	writeCacacheEntry(t, cacache, "tarball-alias", make([]byte, 100), 60*24*time.Hour)
+
+	p := newTestNpmProvider(t, npmDir, "1G")
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(100), result.BytesCleaned)
+	assert.NoFileExists(t, path)
+	assert.Empty(t, liveKeys(t, cacache))
+}
+
+func TestNpmProvider_DryRun(t *testing.T) {
+	npmDir := t.TempDir()
+	cacache := filepath.Join(npmDir, cacacheDir)
+	key := "make-fetch-happen:request-cache:https://registry.npmjs.org/left-pad/-/left-pad-1.3.0.tgz"
+	path := writeCacacheEntry(t, cacache, key, make([]byte, 100), 60*24*time.Hour)
+
+	p := newTestNpmProvider(t, npmDir, "1G")
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true, Mode: CleanModeSmart})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(100), result.BytesCleaned)
+	assert.Contains(t, result.Output, "would remove: https://registry.npmjs.org/left-pad/-/left-pad-1.3.0.tgz")
+	assert.FileExists(t, path)
+	assert.Equal(t, []string{key}, liveKeys(t, cacache))
+}
+
+func TestNpmProvider_FullCleanRunsCleanCmd(t *testing.T) {
+	npmDir := t.TempDir()
+	cacache := filepath.Join(npmDir, cacacheDir)
+	path := writeCacacheEntry(t, cacache, "tarball-url", make([]byte, 100), 60*24*time.Hour)
+
+	p, err := NewNpmProvider("npm", config.Provider{
+		Paths:    []string{npmDir},
+		MaxSize:  "1B",
+		CleanCmd: "rm -rf " + cacache,
+	})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "would run: rm -rf "+cacache, result.Output)
+	assert.FileExists(t, path)
+
+	result, err = p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.NoDirExists(t, cacache)
+	assert.Positive(t, result.BytesCleaned) // content and index
+}
+
+func TestNewProvider_Npm(t *testing.T) {
+	p, err := NewProvider("npm", config.Provider{Paths: []string{t.TempDir()}, MaxSize: "1G"})
+	require.NoError(t, err)
+	assert.IsType(t, &NpmProvider{}, p)
+}
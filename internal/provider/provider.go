@@ -3,6 +3,8 @@ package provider
 import (
 	"context"
 	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
 )
 
 // CleanMode determines cleaning strategy.
@@ -11,7 +13,7 @@ type CleanMode int
 // CleanMode constants.
 const (
 	CleanModeFull  CleanMode = iota // Delete everything (via command or all files)
-	CleanModeSmart                  // Smart clean: delete files older than max_age, then LRU until under max_size
+	CleanModeSmart                  // Smart clean: delete files older than max_age, then in eviction order until under max_size
 )
 
 // Provider defines the interface for cache providers.
@@ -45,10 +47,40 @@ type DiskSizer interface {
 	DiskImageSize(ctx context.Context) (int64, error)
 }
 
+// Reclaimer is implemented by providers that can tell how much a clean would free
+// without running one, such as the workspace sweeper's stale projects.
+type Reclaimer interface {
+	ReclaimableSize(ctx context.Context) (int64, error)
+}
+
+// UsageCategory is one kind of data within a provider's usage, such as Docker images.
+type UsageCategory struct {
+	Name        string
+	Size        int64
+	Reclaimable int64 // bytes a prune of this category would free
+}
+
+// Breakdowner is implemented by providers that can split their usage into categories.
+type Breakdowner interface {
+	Breakdown(ctx context.Context) ([]UsageCategory, error)
+}
+
+// InUseChecker is implemented by providers that can tell when their owning
+// tool is using the cache, so that a clean would break it.
+type InUseChecker interface {
+	// InUse returns why the cache is in use, or "" if it is not.
+	InUse() string
+	// OnBusy returns what a clean does while the cache is in use: a config.Busy* value.
+	OnBusy() string
+}
+
 // CleanOptions configures cleaning behavior.
 type CleanOptions struct {
-	DryRun bool
-	Mode   CleanMode
+	// AccessLog, when set, holds file opens recorded by a tracker, which
+	// count as uses when newer than the lru_key time.
+	AccessLog *cache.AccessLog
+	DryRun    bool
+	Mode      CleanMode
 }
 
 // CleanResult contains cleaning operation results.
@@ -56,4 +88,5 @@ type CleanResult struct {
 	Output       string
 	BytesCleaned int64
 	FilesDeleted int64
+	Fixups       int64 // read-only directories temporarily made writable to delete their files
 }
@@ -218,7 +218,7 @@ func TestDockerProvider_Available(t *testing.T) {
 	cfg := config.Provider{
 		Paths:    []string{t.TempDir()},
 		MaxSize:  "50G",
-		CleanCmd: "docker system prune -af --volumes",
+		CleanCmd: "docker system prune -af",
 		Enabled:  true,
 	}
 
@@ -267,11 +267,29 @@ func TestNewProvider_FileBased(t *testing.T) {
 	}
 }
 
+func TestNewProvider_ProjectWithoutCleanCmd(t *testing.T) {
+	cfg := config.Provider{
+		Paths:   []string{t.TempDir()},
+		MaxSize: "1G",
+		Enabled: true,
+		Project: "/src/app",
+	}
+
+	p, err := provider.NewProvider("app/build", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := p.(*provider.FileProvider); !ok {
+		t.Errorf("provider type = %T, want *provider.FileProvider", p)
+	}
+}
+
 func TestNewProvider_Docker(t *testing.T) {
 	cfg := config.Provider{
 		Paths:    []string{t.TempDir()},
 		MaxSize:  "50G",
-		CleanCmd: "docker system prune -af --volumes",
+		CleanCmd: "docker system prune -af",
 		Enabled:  true,
 	}
 
@@ -285,6 +303,24 @@ func TestNewProvider_Docker(t *testing.T) {
 	}
 }
 
+func TestNewProvider_Podman(t *testing.T) {
+	cfg := config.Provider{
+		Paths:   []string{t.TempDir()},
+		MaxSize: "50G",
+		Engine:  config.EnginePodman,
+		Enabled: true,
+	}
+
+	p, err := provider.NewProvider("podman", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := p.(*provider.DockerProvider); !ok {
+		t.Errorf("provider type = %T, want *provider.DockerProvider", p)
+	}
+}
+
 func TestLoadProviders(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -372,6 +408,20 @@ func TestBaseProvider_InvalidMaxSize(t *testing.T) {
 	}
 }
 
+func TestBaseProvider_InvalidHeadroom(t *testing.T) {
+	cfg := config.Provider{
+		Paths:    []string{t.TempDir()},
+		MaxSize:  "1G",
+		Headroom: "100%",
+		Enabled:  true,
+	}
+
+	_, err := provider.NewBaseProvider("test", cfg)
+	if err == nil {
+		t.Error("expected error for headroom of 100%")
+	}
+}
+
 func TestBaseProvider_InvalidPath(t *testing.T) {
 	cfg := config.Provider{
 		Paths:   []string{"~nonexistent[invalid"},
@@ -488,7 +538,7 @@ func TestDockerProvider_DryRun(t *testing.T) {
 	cfg := config.Provider{
 		Paths:    []string{t.TempDir()},
 		MaxSize:  "50G",
-		CleanCmd: "docker system prune -af --volumes",
+		CleanCmd: "docker system prune -af",
 		Enabled:  true,
 	}
 
@@ -1047,6 +1097,48 @@ func TestCommandProvider_QuotedArgs(t *testing.T) {
 	}
 }
 
+func TestCommandProvider_EnvExpansion(t *testing.T) {
+	t.Setenv("CB_TEST_GREETING", "hello world")
+
+	cfg := config.Provider{
+		Paths:    []string{t.TempDir()},
+		MaxSize:  "1G",
+		CleanCmd: "echo ${CB_TEST_GREETING} ${CB_TEST_UNSET:-fallback}",
+		Enabled:  true,
+	}
+
+	p, err := provider.NewCommandProvider("test", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := p.Clean(context.Background(), provider.CleanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Output != "hello world fallback" {
+		t.Errorf("output = %q, want %q", result.Output, "hello world fallback")
+	}
+}
+
+func TestCommandProvider_UnsetEnvVar(t *testing.T) {
+	cfg := config.Provider{
+		Paths:    []string{t.TempDir()},
+		MaxSize:  "1G",
+		CleanCmd: "rm -rf ${CB_TEST_UNSET}/cache",
+		Enabled:  true,
+	}
+
+	_, err := provider.NewCommandProvider("test", cfg)
+	if err == nil {
+		t.Fatal("expected error for unset variable in clean_cmd")
+	}
+	if !strings.Contains(err.Error(), "CB_TEST_UNSET is not set") {
+		t.Errorf("error = %v, want to name the unset variable", err)
+	}
+}
+
 func TestCommandProvider_Available(t *testing.T) {
 	// Temporary PATH containing exactly one fake executable.
 	binDir := t.TempDir()
@@ -1179,6 +1271,61 @@ func TestFileProvider_DeleteError(t *testing.T) {
 	}
 }
 
+func TestFileProvider_ReadOnlyParent(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root bypasses directory permissions")
+	}
+
+	tmpDir := t.TempDir()
+	subDir := filepath.Join(tmpDir, "subdir")
+	if err := os.Mkdir(subDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	testFile := filepath.Join(subDir, "test.txt")
+	if err := os.WriteFile(testFile, make([]byte, 2000), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chmod(subDir, 0o500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chmod(subDir, 0o700)
+	})
+
+	cfg := config.Provider{
+		Paths:   []string{tmpDir},
+		MaxSize: "1000B",
+		Enabled: true,
+	}
+
+	p, err := provider.NewFileProvider("test", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := p.Clean(context.Background(), provider.CleanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.FilesDeleted != 1 {
+		t.Errorf("files deleted = %d, want 1", result.FilesDeleted)
+	}
+	if result.Fixups != 1 {
+		t.Errorf("fixups = %d, want 1", result.Fixups)
+	}
+
+	info, err := os.Stat(subDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o500 {
+		t.Errorf("subdir mode = %o, want 500 restored", info.Mode().Perm())
+	}
+}
+
 func TestBaseProvider_MaxAge(t *testing.T) {
 	cfg := config.Provider{
 		Paths:   []string{t.TempDir()},
@@ -1467,7 +1614,7 @@ func TestDockerProvider_SmartClean_NotAvailable(t *testing.T) {
 	cfg := config.Provider{
 		Paths:    []string{t.TempDir()},
 		MaxSize:  "50G",
-		CleanCmd: "docker system prune -af --volumes",
+		CleanCmd: "docker system prune -af",
 		Enabled:  true,
 	}
 
@@ -1494,7 +1641,7 @@ func TestDockerProvider_SmartClean_DryRun(t *testing.T) {
 	cfg := config.Provider{
 		Paths:    []string{t.TempDir()},
 		MaxSize:  "50G",
-		CleanCmd: "docker system prune -af --volumes",
+		CleanCmd: "docker system prune -af",
 		Enabled:  true,
 	}
 
@@ -1519,10 +1666,115 @@ func TestDockerProvider_SmartClean_DryRun(t *testing.T) {
 		t.Errorf("output should contain 'would run', got %q", result.Output)
 	}
 
-	if !strings.Contains(result.Output, "system prune") {
-		t.Errorf("output should contain 'system prune', got %q", result.Output)
+	if !strings.Contains(result.Output, "builder prune") {
+		t.Errorf("output should contain 'builder prune', got %q", result.Output)
 	}
 	if !strings.Contains(result.Output, "until=") {
 		t.Errorf("output should contain 'until=', got %q", result.Output)
 	}
 }
+
+func TestFileProvider_FullClean_HonorsFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]time.Duration{
+		"jars-9/lib.jar":   72 * time.Hour,
+		"modules/old.bin":  48 * time.Hour,
+		"modules/gc.lock":  96 * time.Hour,
+		"modules/new.bin":  time.Hour,
+		"modules/mid.bin":  24 * time.Hour,
+		"modules/keep.bin": 0,
+	}
+	for rel, age := range files {
+		path := filepath.Join(tmpDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, 1000), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Now().Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Provider{
+		Paths:   []string{tmpDir},
+		MaxSize: "3000B",
+		Exclude: []string{"**/*.lock"},
+		Protect: []string{"jars-*"},
+		Enabled: true,
+	}
+
+	p, err := provider.NewFileProvider("test", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := p.CurrentSize(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != 5000 {
+		t.Errorf("current size = %d, want 5000 (excluded lock not counted)", current)
+	}
+
+	result, err := p.Clean(context.Background(), provider.CleanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FilesDeleted != 2 {
+		t.Errorf("files deleted = %d, want 2", result.FilesDeleted)
+	}
+
+	for _, kept := range []string{"jars-9/lib.jar", "modules/gc.lock", "modules/new.bin"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, kept)); err != nil {
+			t.Errorf("%s should be kept: %v", kept, err)
+		}
+	}
+	for _, gone := range []string{"modules/old.bin", "modules/mid.bin"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s should be deleted", gone)
+		}
+	}
+}
+
+func TestJetBrainsProvider_ProtectedVersionKept(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, dir := range []string{"GoLand2023.3", "GoLand2024.1", "GoLand2024.2"} {
+		dirPath := filepath.Join(tmpDir, dir)
+		if err := os.MkdirAll(dirPath, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dirPath, "data.bin"), make([]byte, 100), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Provider{
+		Paths:   []string{tmpDir},
+		MaxSize: "3G",
+		Protect: []string{"GoLand2023.*"},
+		Enabled: true,
+	}
+
+	p, err := provider.NewJetBrainsProvider("jetbrains", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Clean(context.Background(), provider.CleanOptions{Mode: provider.CleanModeFull}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "GoLand2023.3")); err != nil {
+		t.Error("protected GoLand2023.3 should be kept")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "GoLand2024.1")); !os.IsNotExist(err) {
+		t.Error("GoLand2024.1 should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "GoLand2024.2")); err != nil {
+		t.Error("latest GoLand2024.2 should be kept")
+	}
+}
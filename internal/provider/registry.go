@@ -11,21 +11,49 @@ var fileBasedProviders = map[string]bool{
 	"uv":                true,
 	"xcode-deriveddata": true,
 	"xcode-archives":    true,
-	"cargo":             true,
-	"gradle":            true,
 }
 
 // NewProvider creates a provider from config.
 func NewProvider(name string, cfg config.Provider) (Provider, error) {
-	if name == "docker" {
+	if cfg.Type == config.TypeWorkspace {
+		return NewWorkspaceProvider(name, cfg)
+	}
+
+	if cfg.Type == config.TypeVersioned {
+		return NewVersionedProvider(name, cfg)
+	}
+
+	if name == "docker" || cfg.Engine != "" {
 		return NewDockerProvider(name, cfg)
 	}
 
+	if name == "go-mod" {
+		return NewGoModProvider(name, cfg)
+	}
+
+	if name == "npm" {
+		return NewNpmProvider(name, cfg)
+	}
+
+	if name == "cargo" {
+		return NewCargoProvider(name, cfg)
+	}
+
+	if name == "gradle" {
+		return NewGradleProvider(name, cfg)
+	}
+
+	if name == "maven" {
+		return NewMavenProvider(name, cfg)
+	}
+
 	if name == "jetbrains" {
 		return NewJetBrainsProvider(name, cfg)
 	}
 
-	if fileBasedProviders[name] {
+	// Project-local caches (build outputs, tool caches) are plain directories
+	// trimmed file by file; project configs cannot set a clean_cmd.
+	if fileBasedProviders[name] || cfg.Project != "" {
 		return NewFileProvider(name, cfg)
 	}
 
@@ -0,0 +1,412 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/Automaat/cache-buster/pkg/size"
+	"golang.org/x/mod/semver"
+)
+
+// VersionedProvider removes version directories beyond the newest keep per
+// product. Directories are the entries of its paths whose names match a
+// pattern with a "version" and an optional "product" group; names that do
+// not match are never touched. A name found under several paths is one
+// version, kept or removed as a whole.
+type VersionedProvider struct {
+	*BaseProvider
+	pattern *regexp.Regexp
+	// installed, when set, returns the version directory names used by installed
+	// tools. An empty result means installs could not be detected.
+	installed func() map[string]bool
+	order     string
+	keep      int
+}
+
+// NewVersionedProvider creates a provider from a versioned config.
+func NewVersionedProvider(name string, cfg config.Provider) (*VersionedProvider, error) {
+	pattern, err := config.CompileVersionPattern(cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("pattern: %w", err)
+	}
+	if pattern.SubexpIndex("version") < 0 {
+		return nil, fmt.Errorf("pattern %q has no (?P<version>...) group", cfg.Pattern)
+	}
+
+	return newVersionedProvider(name, cfg, pattern, cfg.Order, cfg.Keep)
+}
+
+func newVersionedProvider(name string, cfg config.Provider, pattern *regexp.Regexp, order string, keep int) (*VersionedProvider, error) {
+	base, err := NewBaseProvider(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VersionedProvider{
+		BaseProvider: base,
+		pattern:      pattern,
+		order:        order,
+		keep:         max(keep, 1),
+	}, nil
+}
+
+// versionDir is one version of a product, present under one or more paths.
+type versionDir struct {
+	modTime time.Time // newest across paths
+	product string
+	version string
+	name    string
+	paths   []string
+}
+
+// keptVersion is a version a clean leaves alone, with the reason shown in dry runs.
+type keptVersion struct {
+	reason string
+	dir    versionDir
+}
+
+// versionPlan splits the versions found into what a clean keeps and removes.
+type versionPlan struct {
+	remove      []versionDir
+	uninstalled []versionDir // removed versions of a product with no install detected
+	kept        []keptVersion
+}
+
+// Clean implements Provider. Full mode removes every version beyond the
+// newest keep; smart mode only those also older than max_age. Installed
+// versions are always kept. Products with no install detected keep their
+// newest too, since detection can miss installs; they are reported apart.
+func (p *VersionedProvider) Clean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
+	plan, err := p.plan(opts.Mode)
+	if err != nil {
+		return CleanResult{}, err
+	}
+
+	return p.cleanDirs(ctx, plan, opts)
+}
+
+func (p *VersionedProvider) cleanDirs(ctx context.Context, plan versionPlan, opts CleanOptions) (CleanResult, error) {
+	var output strings.Builder
+
+	if opts.DryRun {
+		for _, k := range plan.kept {
+			fmt.Fprintf(&output, "keep: %s (%s)\n", k.dir.name, k.reason)
+		}
+	}
+
+	if len(plan.remove)+len(plan.uninstalled) == 0 {
+		output.WriteString("no old versions to clean")
+		return CleanResult{Output: output.String()}, nil
+	}
+
+	var (
+		bytesTotal int64
+		deleted    int64
+		removed    [2]int // old versions, versions with no install detected
+		errs       []cache.AccessError
+	)
+	for i, vd := range slices.Concat(plan.remove, plan.uninstalled) {
+		select {
+		case <-ctx.Done():
+			return CleanResult{
+				BytesCleaned: bytesTotal,
+				FilesDeleted: deleted,
+				Output:       "interrupted",
+			}, ctx.Err()
+		default:
+		}
+
+		dirSize, err := cache.CalculateSizeContext(ctx, vd.paths)
+		if err != nil {
+			fmt.Fprintf(&output, "warning: size calculation failed for %s: %v\n", vd.name, err)
+		}
+
+		if opts.DryRun {
+			note := ""
+			if i >= len(plan.remove) {
+				note = ", no install detected"
+			}
+			fmt.Fprintf(&output, "would remove: %s (%s%s)\n", vd.name, size.FormatSize(dirSize.Size), note)
+			bytesTotal += dirSize.Size
+			continue
+		}
+
+		failed := false
+		for _, path := range vd.paths {
+			if err := os.RemoveAll(path); err != nil {
+				fmt.Fprintf(&output, "error removing %s: %v\n", path, err)
+				errs = append(errs, cache.ClassifyError(path, err))
+				failed = true
+				continue
+			}
+			deleted++
+		}
+		if failed {
+			continue
+		}
+
+		bytesTotal += dirSize.Size
+		if i < len(plan.remove) {
+			removed[0]++
+		} else {
+			removed[1]++
+		}
+	}
+
+	if opts.DryRun {
+		return CleanResult{
+			BytesCleaned: bytesTotal,
+			Output:       strings.TrimSpace(output.String()),
+		}, nil
+	}
+
+	summary := fmt.Sprintf("removed %d old version directories", removed[0])
+	if len(plan.uninstalled) > 0 {
+		summary += fmt.Sprintf("\nremoved %d version directories with no install detected", removed[1])
+	}
+	if len(errs) > 0 {
+		summary = formatResultWithErrors(summary, deleted, errs)
+	}
+	result := CleanResult{
+		BytesCleaned: bytesTotal,
+		FilesDeleted: deleted,
+		Output:       summary,
+	}
+	if output.Len() > 0 {
+		result.Output += "\n" + strings.TrimSpace(output.String())
+	}
+
+	return result, nil
+}
+
+// findVersionDirs groups the version directories under the provider paths by
+// product, newest first.
+func (p *VersionedProvider) findVersionDirs() (map[string][]versionDir, error) {
+	byName := make(map[string]*versionDir)
+	productIdx := p.pattern.SubexpIndex("product")
+	versionIdx := p.pattern.SubexpIndex("version")
+
+	for _, basePath := range p.paths {
+		entries, err := os.ReadDir(basePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			matches := p.pattern.FindStringSubmatch(entry.Name())
+			if matches == nil {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+
+			vd, ok := byName[entry.Name()]
+			if !ok {
+				vd = &versionDir{name: entry.Name(), version: matches[versionIdx]}
+				if productIdx >= 0 {
+					vd.product = matches[productIdx]
+				}
+				byName[entry.Name()] = vd
+			}
+			vd.paths = append(vd.paths, filepath.Join(basePath, entry.Name()))
+			if info.ModTime().After(vd.modTime) {
+				vd.modTime = info.ModTime()
+			}
+		}
+	}
+
+	products := make(map[string][]versionDir)
+	for _, vd := range byName {
+		products[vd.product] = append(products[vd.product], *vd)
+	}
+	for _, versions := range products {
+		sort.Slice(versions, func(i, j int) bool {
+			return p.compare(&versions[i], &versions[j]) > 0
+		})
+	}
+
+	return products, nil
+}
+
+// plan decides for every version found whether a clean in mode keeps or removes it.
+func (p *VersionedProvider) plan(mode CleanMode) (versionPlan, error) {
+	products, err := p.findVersionDirs()
+	if err != nil {
+		return versionPlan{}, err
+	}
+
+	var installed map[string]bool
+	if p.installed != nil {
+		installed = p.installed()
+	}
+
+	open, err := p.openFiles()
+	if err != nil {
+		return versionPlan{}, err
+	}
+
+	var cutoff time.Time
+	if mode == CleanModeSmart && p.maxAge > 0 {
+		cutoff = time.Now().Add(-p.maxAge)
+	}
+
+	names := make([]string, 0, len(products))
+	for product := range products {
+		names = append(names, product)
+	}
+	sort.Strings(names)
+
+	var plan versionPlan
+	for _, product := range names {
+		versions := products[product]
+		productInstalled := len(installed) == 0 || slices.ContainsFunc(versions, func(vd versionDir) bool {
+			return installed[vd.name]
+		})
+
+		for i, vd := range versions {
+			reason, err := p.keepReason(vd, i, installed[vd.name], productInstalled, cutoff)
+			if err != nil {
+				return versionPlan{}, err
+			}
+			if reason == "" && holdsOpenFile(open, vd.paths...) {
+				reason = "has files open in another process"
+			}
+			switch {
+			case reason != "":
+				plan.kept = append(plan.kept, keptVersion{dir: vd, reason: reason})
+			case productInstalled:
+				plan.remove = append(plan.remove, vd)
+			default:
+				plan.uninstalled = append(plan.uninstalled, vd)
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// keepReason returns why the version at rank i (0 = newest) of its product is
+// kept, or "" if it may be removed.
+func (p *VersionedProvider) keepReason(vd versionDir, i int, installed, productInstalled bool, cutoff time.Time) (string, error) {
+	if installed {
+		return "installed", nil
+	}
+	if i < p.keep {
+		newest := "newest"
+		if p.keep > 1 {
+			newest = fmt.Sprintf("newest %d", p.keep)
+		}
+		if vd.product != "" {
+			newest += " of " + vd.product
+		}
+		if !productInstalled {
+			newest += ", no install detected"
+		}
+		return newest, nil
+	}
+	if !cutoff.IsZero() && vd.modTime.After(cutoff) {
+		return "modified within max_age", nil
+	}
+	for _, path := range vd.paths {
+		guarded, err := p.filter.Guards(filepath.Dir(path), path)
+		if err != nil {
+			return "", err
+		}
+		if guarded {
+			return "holds excluded or protected paths", nil
+		}
+	}
+	return "", nil
+}
+
+// compare orders two version directories of one product by the provider's order.
+func (p *VersionedProvider) compare(a, b *versionDir) int {
+	switch p.order {
+	case config.OrderCalver:
+		return compareVersions(a.version, b.version)
+	case config.OrderMtime:
+		if c := a.modTime.Compare(b.modTime); c != 0 {
+			return c
+		}
+		return strings.Compare(a.version, b.version)
+	default:
+		return compareSemver(a.version, b.version)
+	}
+}
+
+// compareSemver compares versions with or without a leading "v" by semver
+// rules. Versions that are not valid semver fall back to compareVersions.
+func compareSemver(v1, v2 string) int {
+	s1, s2 := "v"+strings.TrimPrefix(v1, "v"), "v"+strings.TrimPrefix(v2, "v")
+	if semver.IsValid(s1) && semver.IsValid(s2) {
+		return semver.Compare(s1, s2)
+	}
+	return compareVersions(v1, v2)
+}
+
+// compareVersions compares semantic versions like "2024.1" and "2024.10".
+// Returns -1 if v1 < v2, 0 if equal, 1 if v1 > v2.
+//
+// A missing segment counts as 0. A segment that is non-numeric or out of
+// int range is compared lexically rather than silently coerced to 0, so
+// malformed versions order deterministically instead of collapsing together.
+func compareVersions(v1, v2 string) int {
+	parts1 := strings.Split(v1, ".")
+	parts2 := strings.Split(v2, ".")
+
+	maxLen := max(len(parts2), len(parts1))
+
+	for i := range maxLen {
+		n1, raw1, ok1 := versionSegment(parts1, i)
+		n2, raw2, ok2 := versionSegment(parts2, i)
+
+		if !ok1 || !ok2 {
+			if c := strings.Compare(raw1, raw2); c != 0 {
+				return c
+			}
+			continue
+		}
+
+		if n1 < n2 {
+			return -1
+		}
+		if n1 > n2 {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// versionSegment returns the i-th dot-separated version segment as an int.
+// A missing segment is treated as 0 with ok=true; a present segment that
+// fails to parse (non-numeric or out of range) returns ok=false along with
+// its raw text for lexical fallback comparison.
+func versionSegment(parts []string, i int) (n int, raw string, ok bool) {
+	if i >= len(parts) {
+		return 0, "", true
+	}
+	raw = parts[i]
+	n, err := strconv.Atoi(raw)
+	return n, raw, err == nil
+}
@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeVersionDir creates dir/name holding one file of size bytes, with the
+// directory modified age ago.
+func writeVersionDir(t *testing.T, dir, name string, size int, age time.Duration) {
+	t.Helper()
+	writeAged(t, filepath.Join(dir, name, "data.bin"), size, age)
+	when := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, name), when, when))
+}
+
+func newTestVersionedProvider(t *testing.T, dir, pattern, order string, keep int) *VersionedProvider {
+	t.Helper()
+	p, err := NewVersionedProvider("test", config.Provider{
+		Type:    config.TypeVersioned,
+		Paths:   []string{dir},
+		MaxSize: "1G",
+		MaxAge:  "30d",
+		Pattern: pattern,
+		Order:   order,
+		Keep:    keep,
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func remainingDirs(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestVersionedProvider_Semver(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"18.9.0", "18.10.0", "20.1.0-rc.1", "20.1.0", "current"} {
+		writeVersionDir(t, dir, name, 100, time.Hour)
+	}
+
+	p := newTestVersionedProvider(t, dir, `(?P<version>\d+\.\d+\.\d+(?:-[0-9A-Za-z.]+)?)`, "", 2)
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(200), result.BytesCleaned)
+	assert.Equal(t, int64(2), result.FilesDeleted)
+	assert.Equal(t, "removed 2 old version directories", result.Output)
+	assert.ElementsMatch(t, []string{"20.1.0", "20.1.0-rc.1", "current"}, remainingDirs(t, dir))
+}
+
+func TestVersionedProvider_RemoveErrorKeepsSummary(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root bypasses directory permissions")
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"1.0.0", "2.0.0", "3.0.0"} {
+		writeVersionDir(t, dir, name, 100, time.Hour)
+	}
+	locked := filepath.Join(dir, "1.0.0")
+	require.NoError(t, os.Chmod(locked, 0o500))
+	t.Cleanup(func() { _ = os.Chmod(locked, 0o700) })
+
+	p := newTestVersionedProvider(t, dir, `(?P<version>[\d.]+)`, config.OrderSemver, 1)
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(100), result.BytesCleaned)
+	assert.Equal(t, int64(1), result.FilesDeleted)
+	assert.True(t, strings.HasPrefix(result.Output, "removed 1 old version directories (1 permission denied)\nerror removing "+locked), result.Output)
+}
+
+func TestVersionedProvider_CalverPerProduct(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"chromium-1140", "chromium-1148", "firefox-1463", "firefox-1471", "ffmpeg-1010"} {
+		writeVersionDir(t, dir, name, 100, time.Hour)
+	}
+
+	p := newTestVersionedProvider(t, dir, `(?P<product>[a-z]+)-(?P<version>\d+)`, config.OrderCalver, 1)
+	_, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"chromium-1148", "firefox-1471", "ffmpeg-1010"}, remainingDirs(t, dir))
+}
+
+func TestVersionedProvider_Mtime(t *testing.T) {
+	dir := t.TempDir()
+	writeVersionDir(t, dir, "0f3a9c", 100, time.Hour)
+	writeVersionDir(t, dir, "8b21de", 100, 48*time.Hour)
+	writeVersionDir(t, dir, "c47e10", 100, 24*time.Hour)
+
+	p := newTestVersionedProvider(t, dir, `(?P<version>[0-9a-f]{6})`, config.OrderMtime, 1)
+	_, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"0f3a9c"}, remainingDirs(t, dir))
+}
+
+func TestVersionedProvider_SmartCleanHonorsMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	writeVersionDir(t, dir, "33.0.0", 100, time.Hour)
+	writeVersionDir(t, dir, "34.0.0", 100, time.Hour)
+	writeVersionDir(t, dir, "30.0.3", 100, 90*24*time.Hour)
+
+	p := newTestVersionedProvider(t, dir, `(?P<version>[\d.]+)`, config.OrderSemver, 1)
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "keep: 34.0.0 (newest)\nkeep: 33.0.0 (modified within max_age)\nwould remove: 30.0.3 (100 B)", result.Output)
+
+	_, err = p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"33.0.0", "34.0.0"}, remainingDirs(t, dir))
+}
+
+func TestVersionedProvider_PatternMatchesWholeName(t *testing.T) {
+	dir := t.TempDir()
+	writeVersionDir(t, dir, "1.0.0", 100, time.Hour)
+	writeVersionDir(t, dir, "1.0.0.bak", 100, time.Hour)
+	writeVersionDir(t, dir, "2.0.0", 100, time.Hour)
+
+	p := newTestVersionedProvider(t, dir, `(?P<version>\d+\.\d+\.\d+)`, "", 1)
+	_, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"1.0.0.bak", "2.0.0"}, remainingDirs(t, dir))
+}
+
+func TestNewProvider_Versioned(t *testing.T) {
+	p, err := NewProvider("playwright", config.Provider{
+		Type:    config.TypeVersioned,
+		Paths:   []string{t.TempDir()},
+		MaxSize: "1G",
+		Pattern: `(?P<version>\d+)`,
+		Keep:    1,
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &VersionedProvider{}, p)
+
+	_, err = NewProvider("bad", config.Provider{
+		Type:    config.TypeVersioned,
+		Paths:   []string{t.TempDir()},
+		MaxSize: "1G",
+		Pattern: `\d+`,
+	})
+	require.ErrorContains(t, err, "no (?P<version>...) group")
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		v1   string
+		v2   string
+		want int
+	}{
+		{"equal", "2024.1", "2024.1", 0},
+		{"minor less", "2024.1", "2024.2", -1},
+		{"minor greater", "2024.2", "2024.1", 1},
+		{"double digit minor", "2024.9", "2024.10", -1},
+		{"major greater", "2025.1", "2024.9", 1},
+		{"missing segment counts as zero", "2024", "2024.1", -1},
+		{"missing segment equal", "2024", "2024.0", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, compareVersions(tt.v1, tt.v2))
+		})
+	}
+}
+
+// TestCompareVersions_MalformedSegment verifies a non-numeric segment is not
+// silently coerced to 0: two versions differing only in a malformed segment
+// must order deterministically rather than compare equal.
+func TestCompareVersions_MalformedSegment(t *testing.T) {
+	assert.NotEqual(t, 0, compareVersions("2024.x", "2024.y"),
+		"distinct non-numeric segments must not collapse to equal")
+	assert.Equal(t, -compareVersions("2024.y", "2024.x"),
+		compareVersions("2024.x", "2024.y"), "comparison must be antisymmetric")
+}
+
+// TestCompareVersions_OutOfRangeSegment verifies a numeric segment too large
+// for int falls back to lexical comparison instead of silently becoming 0.
+func TestCompareVersions_OutOfRangeSegment(t *testing.T) {
+	huge := "99999999999999999999999999"
+	assert.NotEqual(t, 0, compareVersions("2024."+huge, "2024.1"),
+		"out-of-range segment must not collapse to 0")
+}
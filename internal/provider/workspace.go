@@ -0,0 +1,410 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/cache"
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/Automaat/cache-buster/pkg/size"
+)
+
+// workspaceMaxDepth bounds how deep below a workspace root projects are searched for.
+const workspaceMaxDepth = 5
+
+// ecosystem maps a project marker file to the artifact directories it produces.
+type ecosystem struct {
+	markers   []string
+	artifacts []string
+}
+
+// ecosystems lists the project types the workspace sweeper recognizes. Go is
+// absent on purpose: its build output lives in GOCACHE, covered by go-build.
+var ecosystems = []ecosystem{
+	{markers: []string{"package.json"}, artifacts: []string{"node_modules", ".next"}},
+	{markers: []string{"Cargo.toml"}, artifacts: []string{"target"}},
+	{markers: []string{"pyproject.toml", "requirements.txt"}, artifacts: []string{".venv", ".tox", ".pytest_cache", ".mypy_cache"}},
+	{
+		markers:   []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"},
+		artifacts: []string{"build", ".gradle"},
+	},
+}
+
+// artifactNames is the set of all artifact directory names, skipped when
+// looking for source edits and nested projects.
+var artifactNames = func() map[string]bool {
+	names := make(map[string]bool)
+	for _, e := range ecosystems {
+		for _, a := range e.artifacts {
+			names[a] = true
+		}
+	}
+	return names
+}()
+
+// workspaceProject is one checkout whose artifact directories are cleaned together.
+type workspaceProject struct {
+	lastActive time.Time
+	workspace  string // configured root the project was found under; filter patterns are relative to it
+	root       string
+	artifacts  []string
+	size       int64
+}
+
+// WorkspaceProvider sweeps build artifacts (node_modules, target, .venv, ...)
+// from projects under the configured roots that have been inactive for max_age.
+type WorkspaceProvider struct {
+	*BaseProvider
+	// scanned holds the projects the last CurrentSize found, so that the
+	// ReclaimableSize status asks for next does not walk every tree again.
+	scanned []workspaceProject
+}
+
+// NewWorkspaceProvider creates a provider that cleans stale project artifacts.
+func NewWorkspaceProvider(name string, cfg config.Provider) (*WorkspaceProvider, error) {
+	base, err := NewBaseProvider(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WorkspaceProvider{
+		BaseProvider: base,
+	}, nil
+}
+
+// CurrentSize returns the total size of artifact directories across all projects.
+func (p *WorkspaceProvider) CurrentSize(ctx context.Context) (int64, error) {
+	projects, err := p.findProjects(ctx)
+	if err != nil {
+		return 0, err
+	}
+	p.scanned = projects
+
+	var total int64
+	for _, proj := range projects {
+		total += proj.size
+	}
+	return total, nil
+}
+
+// ReclaimableSize returns the artifact size of projects inactive for longer
+// than max_age, reusing the scan of a preceding CurrentSize.
+func (p *WorkspaceProvider) ReclaimableSize(ctx context.Context) (int64, error) {
+	projects := p.scanned
+	p.scanned = nil
+	if projects == nil {
+		var err error
+		if projects, err = p.findProjects(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	var total int64
+	for _, proj := range p.staleProjects(projects) {
+		total += proj.size
+	}
+	return total, nil
+}
+
+// Clean implements Provider. Only projects inactive for longer than max_age are
+// touched. Full mode removes artifacts of every stale project; smart mode stops
+// once the total is under max_size less headroom, going in eviction order.
+func (p *WorkspaceProvider) Clean(ctx context.Context, opts CleanOptions) (CleanResult, error) {
+	projects, err := p.findProjects(ctx)
+	if err != nil {
+		return CleanResult{}, err
+	}
+
+	var total int64
+	for _, proj := range projects {
+		total += proj.size
+	}
+
+	open, err := p.openFiles()
+	if err != nil {
+		return CleanResult{}, err
+	}
+	stale, skipped := skipOpen(open, p.staleProjects(projects),
+		func(proj workspaceProject) []string { return proj.artifacts },
+		func(proj workspaceProject) string { return proj.root })
+	if len(stale) == 0 {
+		return CleanResult{Output: withWarning(skipped, "no stale projects")}, nil
+	}
+
+	var (
+		result  CleanResult
+		removed int
+		output  strings.Builder
+	)
+
+	evictionOrder(p.eviction, stale, func(proj workspaceProject) cache.FileInfo {
+		return cache.FileInfo{Path: proj.root, Size: proj.size, LastUsed: proj.lastActive}
+	})
+	target := p.sizeTarget()
+	for _, proj := range stale {
+		if opts.Mode == CleanModeSmart && total <= target {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			result.Output = "interrupted"
+			return result, ctx.Err()
+		default:
+		}
+
+		inactive := time.Since(proj.lastActive).Truncate(time.Hour)
+		names := artifactBaseNames(proj.artifacts)
+
+		if opts.DryRun {
+			fmt.Fprintf(&output, "would remove: %s [%s] (%s, inactive %s)\n",
+				proj.root, names, size.FormatSize(proj.size), inactive)
+			result.BytesCleaned += proj.size
+			total -= proj.size
+			removed++
+			continue
+		}
+
+		failed := false
+		for _, dir := range proj.artifacts {
+			if err := os.RemoveAll(dir); err != nil {
+				fmt.Fprintf(&output, "error removing %s: %v\n", dir, err)
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		result.BytesCleaned += proj.size
+		total -= proj.size
+		removed++
+	}
+
+	if opts.DryRun {
+		result.Output = withWarning(skipped, strings.TrimSpace(output.String()))
+		return result, nil
+	}
+
+	result.Output = fmt.Sprintf("removed artifacts from %d stale projects", removed)
+	if output.Len() > 0 {
+		result.Output += "\n" + strings.TrimSpace(output.String())
+	}
+	result.Output = withWarning(skipped, result.Output)
+	return result, nil
+}
+
+// staleProjects returns projects inactive for longer than max_age, least recently active first.
+// Projects whose artifacts hold excluded or protected paths are left out.
+func (p *WorkspaceProvider) staleProjects(projects []workspaceProject) []workspaceProject {
+	cutoff := time.Now().Add(-p.maxAge)
+
+	var stale []workspaceProject
+	for _, proj := range projects {
+		if !proj.lastActive.Before(cutoff) || p.guarded(proj) {
+			continue
+		}
+		stale = append(stale, proj)
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].lastActive.Before(stale[j].lastActive)
+	})
+	return stale
+}
+
+func (p *WorkspaceProvider) guarded(proj workspaceProject) bool {
+	for _, dir := range proj.artifacts {
+		if guarded, err := p.filter.Guards(proj.workspace, dir); err != nil || guarded {
+			return true
+		}
+	}
+	return false
+}
+
+// findProjects walks the workspace roots for directories containing a marker
+// file and collects their existing artifact directories and last activity.
+// A project nested in another, like a package of a monorepo, is folded into
+// the outermost one, so it is judged and cleaned by the activity of the whole.
+func (p *WorkspaceProvider) findProjects(ctx context.Context) ([]workspaceProject, error) {
+	var projects []workspaceProject
+
+	for _, root := range p.paths {
+		outer := -1 // index of the outermost project enclosing the walk position
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if path != root && (d.Name() == ".git" || artifactNames[d.Name()] || p.filter.Excluded(root, path)) {
+				return filepath.SkipDir
+			}
+			if depth(root, path) > workspaceMaxDepth {
+				return filepath.SkipDir
+			}
+
+			artifacts := projectArtifacts(path)
+			if len(artifacts) == 0 {
+				return nil
+			}
+
+			if outer >= 0 && strings.HasPrefix(path, projects[outer].root+string(filepath.Separator)) {
+				return projects[outer].addArtifacts(ctx, artifacts)
+			}
+
+			proj, err := scanProject(ctx, path, artifacts)
+			if err != nil {
+				return err
+			}
+			proj.workspace = root
+			projects = append(projects, proj)
+			outer = len(projects) - 1
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("scan workspace %s: %w", root, err)
+		}
+	}
+
+	return projects, nil
+}
+
+// projectArtifacts returns the existing artifact directories of the project at dir,
+// or nil if dir has no recognized marker file.
+func projectArtifacts(dir string) []string {
+	var artifacts []string
+	seen := make(map[string]bool)
+
+	for _, e := range ecosystems {
+		if !hasAnyFile(dir, e.markers) {
+			continue
+		}
+		for _, name := range e.artifacts {
+			path := filepath.Join(dir, name)
+			if seen[path] {
+				continue
+			}
+			if info, err := os.Lstat(path); err == nil && info.IsDir() {
+				artifacts = append(artifacts, path)
+				seen[path] = true
+			}
+		}
+	}
+	return artifacts
+}
+
+// scanProject sizes a project's artifacts and determines when it was last active:
+// the newest of its git metadata, source files, and artifact files.
+func scanProject(ctx context.Context, root string, artifacts []string) (workspaceProject, error) {
+	proj := workspaceProject{root: root}
+
+	if err := proj.addArtifacts(ctx, artifacts); err != nil {
+		return proj, err
+	}
+
+	for _, name := range []string{"HEAD", "index", "logs/HEAD", "FETCH_HEAD"} {
+		if info, err := os.Stat(filepath.Join(root, ".git", name)); err == nil && info.ModTime().After(proj.lastActive) {
+			proj.lastActive = info.ModTime()
+		}
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && (d.Name() == ".git" || artifactNames[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(proj.lastActive) {
+			proj.lastActive = info.ModTime()
+		}
+		return nil
+	})
+	return proj, err
+}
+
+// addArtifacts adds artifact directories to the project, counting their size
+// and the newest file in them as activity.
+func (proj *workspaceProject) addArtifacts(ctx context.Context, artifacts []string) error {
+	listing, err := cache.ListFilesContext(ctx, artifacts)
+	if err != nil {
+		return err
+	}
+	proj.artifacts = append(proj.artifacts, artifacts...)
+	for _, f := range listing.Files {
+		proj.size += f.Size
+		if f.ModTime.After(proj.lastActive) {
+			proj.lastActive = f.ModTime
+		}
+	}
+	return nil
+}
+
+// walkWorkspaceFiles calls fn for every file named one of names under roots,
+// skipping VCS metadata, vendored code, and build artifacts.
+func walkWorkspaceFiles(ctx context.Context, roots, names []string, fn func(path string) error) error {
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && (d.Name() == ".git" || d.Name() == "vendor" || artifactNames[d.Name()]) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if slices.Contains(names, d.Name()) {
+				return fn(path)
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("scan workspace %s: %w", root, err)
+		}
+	}
+	return nil
+}
+
+func hasAnyFile(dir string, names []string) bool {
+	for _, name := range names {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+func depth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+func artifactBaseNames(dirs []string) string {
+	names := make([]string, len(dirs))
+	for i, d := range dirs {
+		names[i] = filepath.Base(d)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,296 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Automaat/cache-buster/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeProject creates a project with the given marker and artifact dirs, each
+// artifact holding one file of size bytes, and ages every file by age.
+func makeProject(t *testing.T, dir, marker string, artifacts []string, size int, age time.Duration) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, marker), []byte("{}"), 0o600))
+	for _, a := range artifacts {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, a), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, a, "blob"), make([]byte, size), 0o600))
+	}
+
+	when := time.Now().Add(-age)
+	require.NoError(t, filepath.WalkDir(dir, func(path string, _ os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Chtimes(path, when, when)
+	}))
+}
+
+func newTestWorkspaceProvider(t *testing.T, root, maxSize string) *WorkspaceProvider {
+	t.Helper()
+	p, err := NewWorkspaceProvider("workspace", config.Provider{
+		Type:    config.TypeWorkspace,
+		Paths:   []string{root},
+		MaxSize: maxSize,
+		MaxAge:  "30d",
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestWorkspaceProvider_FindProjects(t *testing.T) {
+	root := t.TempDir()
+	makeProject(t, filepath.Join(root, "web"), "package.json", []string{"node_modules"}, 100, 0)
+	makeProject(t, filepath.Join(root, "group", "crate"), "Cargo.toml", []string{"target"}, 200, 0)
+	makeProject(t, filepath.Join(root, "svc"), "pyproject.toml", []string{".venv"}, 300, 0)
+	// A build dir without a gradle marker is not an artifact.
+	makeProject(t, filepath.Join(root, "docs"), "README.md", []string{"build"}, 400, 0)
+
+	p := newTestWorkspaceProvider(t, root, "1G")
+	projects, err := p.findProjects(t.Context())
+	require.NoError(t, err)
+
+	roots := make(map[string]int64)
+	for _, proj := range projects {
+		roots[proj.root] = proj.size
+	}
+	assert.Equal(t, map[string]int64{
+		filepath.Join(root, "web"):            100,
+		filepath.Join(root, "group", "crate"): 200,
+		filepath.Join(root, "svc"):            300,
+	}, roots)
+
+	current, err := p.CurrentSize(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), current)
+}
+
+func TestWorkspaceProvider_SkipsNestedArtifacts(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "app")
+	makeProject(t, app, "package.json", []string{"node_modules"}, 100, 0)
+	// A dependency's package.json inside node_modules is not a project.
+	makeProject(t, filepath.Join(app, "node_modules", "dep"), "package.json", []string{"node_modules"}, 50, 0)
+
+	p := newTestWorkspaceProvider(t, root, "1G")
+	projects, err := p.findProjects(t.Context())
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	assert.Equal(t, app, projects[0].root)
+	assert.Equal(t, int64(152), projects[0].size) // includes the dependency's package.json
+}
+
+func TestWorkspaceProvider_NestedPackagesFoldIntoMonorepo(t *testing.T) {
+	root := t.TempDir()
+	mono := filepath.Join(root, "mono")
+	makeProject(t, filepath.Join(mono, "packages", "foo"), "package.json", []string{"node_modules"}, 50, 60*24*time.Hour)
+	makeProject(t, mono, "package.json", []string{"node_modules"}, 100, 60*24*time.Hour)
+	// Work at the monorepo root keeps its stale-looking package too.
+	require.NoError(t, os.WriteFile(filepath.Join(mono, "index.js"), []byte("x"), 0o600))
+
+	p := newTestWorkspaceProvider(t, root, "1")
+	projects, err := p.findProjects(t.Context())
+	require.NoError(t, err)
+	require.Len(t, projects, 1)
+	assert.Equal(t, mono, projects[0].root)
+	assert.Equal(t, int64(150), projects[0].size) // both node_modules
+	assert.Len(t, projects[0].artifacts, 2)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Zero(t, result.BytesCleaned)
+	assert.DirExists(t, filepath.Join(mono, "packages", "foo", "node_modules"))
+}
+
+func TestWorkspaceProvider_RecentEditKeepsProject(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "app")
+	makeProject(t, app, "package.json", []string{"node_modules"}, 100, 60*24*time.Hour)
+	require.NoError(t, os.WriteFile(filepath.Join(app, "index.js"), []byte("x"), 0o600))
+
+	p := newTestWorkspaceProvider(t, root, "1")
+	reclaimable, err := p.ReclaimableSize(t.Context())
+	require.NoError(t, err)
+	assert.Zero(t, reclaimable)
+}
+
+func TestWorkspaceProvider_RecentCommitKeepsProject(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "app")
+	makeProject(t, app, "Cargo.toml", []string{"target"}, 100, 60*24*time.Hour)
+	require.NoError(t, os.MkdirAll(filepath.Join(app, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(app, ".git", "index"), []byte("x"), 0o600))
+
+	p := newTestWorkspaceProvider(t, root, "1")
+	reclaimable, err := p.ReclaimableSize(t.Context())
+	require.NoError(t, err)
+	assert.Zero(t, reclaimable)
+}
+
+func TestWorkspaceProvider_ReclaimableReusesScan(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "app")
+	makeProject(t, app, "Cargo.toml", []string{"target"}, 100, 60*24*time.Hour)
+
+	p := newTestWorkspaceProvider(t, root, "1")
+	current, err := p.CurrentSize(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), current)
+
+	// A rescan would no longer find the project.
+	require.NoError(t, os.Remove(filepath.Join(app, "Cargo.toml")))
+	reclaimable, err := p.ReclaimableSize(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), reclaimable)
+
+	reclaimable, err = p.ReclaimableSize(t.Context())
+	require.NoError(t, err)
+	assert.Zero(t, reclaimable, "the scan is used once")
+}
+
+func TestWorkspaceProvider_Clean(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        CleanMode
+		maxSize     string
+		wantCleaned int64
+		wantKept    []string
+	}{
+		{
+			name:        "full removes every stale project",
+			mode:        CleanModeFull,
+			maxSize:     "1G",
+			wantCleaned: 300,
+			wantKept:    []string{"active"},
+		},
+		{
+			name:        "smart stops under max_size, oldest first",
+			mode:        CleanModeSmart,
+			maxSize:     "400",
+			wantCleaned: 200,
+			wantKept:    []string{"active", "newer"},
+		},
+		{
+			name:        "smart under max_size is a no-op",
+			mode:        CleanModeSmart,
+			maxSize:     "1G",
+			wantCleaned: 0,
+			wantKept:    []string{"active", "newer", "oldest"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			makeProject(t, filepath.Join(root, "oldest"), "package.json", []string{"node_modules"}, 200, 90*24*time.Hour)
+			makeProject(t, filepath.Join(root, "newer"), "Cargo.toml", []string{"target"}, 100, 40*24*time.Hour)
+			makeProject(t, filepath.Join(root, "active"), "package.json", []string{"node_modules"}, 150, 0)
+
+			p := newTestWorkspaceProvider(t, root, tt.maxSize)
+			result, err := p.Clean(t.Context(), CleanOptions{Mode: tt.mode})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCleaned, result.BytesCleaned)
+
+			var kept []string
+			for _, name := range []string{"active", "newer", "oldest"} {
+				artifacts := projectArtifacts(filepath.Join(root, name))
+				if len(artifacts) > 0 {
+					kept = append(kept, name)
+				}
+				// Sources stay regardless.
+				assert.DirExists(t, filepath.Join(root, name))
+			}
+			assert.Equal(t, tt.wantKept, kept)
+		})
+	}
+}
+
+func TestWorkspaceProvider_EvictionAndHeadroom(t *testing.T) {
+	tests := []struct {
+		name     string
+		eviction string
+		headroom string
+		wantKept []string
+	}{
+		{name: "lru frees the least recently active", headroom: "0", wantKept: []string{"active", "big"}},
+		{name: "largest frees the biggest", eviction: config.EvictLargest, headroom: "0", wantKept: []string{"active", "small"}},
+		{name: "headroom frees below max_size", headroom: "50%", wantKept: []string{"active"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			makeProject(t, filepath.Join(root, "small"), "package.json", []string{"node_modules"}, 100, 90*24*time.Hour)
+			makeProject(t, filepath.Join(root, "big"), "Cargo.toml", []string{"target"}, 300, 40*24*time.Hour)
+			makeProject(t, filepath.Join(root, "active"), "package.json", []string{"node_modules"}, 150, 0)
+
+			p, err := NewWorkspaceProvider("workspace", config.Provider{
+				Type:     config.TypeWorkspace,
+				Paths:    []string{root},
+				MaxSize:  "500",
+				MaxAge:   "30d",
+				Eviction: tt.eviction,
+				Headroom: tt.headroom,
+			})
+			require.NoError(t, err)
+			_, err = p.Clean(t.Context(), CleanOptions{Mode: CleanModeSmart})
+			require.NoError(t, err)
+
+			var kept []string
+			for _, name := range []string{"active", "big", "small"} {
+				if len(projectArtifacts(filepath.Join(root, name))) > 0 {
+					kept = append(kept, name)
+				}
+			}
+			assert.Equal(t, tt.wantKept, kept)
+		})
+	}
+}
+
+func TestWorkspaceProvider_CleanDryRun(t *testing.T) {
+	root := t.TempDir()
+	stale := filepath.Join(root, "stale")
+	makeProject(t, stale, "Cargo.toml", []string{"target"}, 100, 90*24*time.Hour)
+
+	p := newTestWorkspaceProvider(t, root, "1G")
+	result, err := p.Clean(t.Context(), CleanOptions{DryRun: true, Mode: CleanModeFull})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(100), result.BytesCleaned)
+	assert.Contains(t, result.Output, "would remove: "+stale+" [target]")
+	assert.DirExists(t, filepath.Join(stale, "target"))
+}
+
+func TestWorkspaceProvider_ProtectedProjectKept(t *testing.T) {
+	root := t.TempDir()
+	makeProject(t, filepath.Join(root, "keep"), "package.json", []string{"node_modules"}, 100, 90*24*time.Hour)
+
+	p, err := NewWorkspaceProvider("workspace", config.Provider{
+		Type:    config.TypeWorkspace,
+		Paths:   []string{root},
+		MaxSize: "1G",
+		MaxAge:  "30d",
+		Protect: []string{"**/keep/node_modules"},
+	})
+	require.NoError(t, err)
+
+	result, err := p.Clean(t.Context(), CleanOptions{Mode: CleanModeFull})
+	require.NoError(t, err)
+	assert.Zero(t, result.BytesCleaned)
+	assert.DirExists(t, filepath.Join(root, "keep", "node_modules"))
+}
+
+func TestNewProvider_WorkspaceType(t *testing.T) {
+	p, err := NewProvider("code", config.Provider{
+		Type:    config.TypeWorkspace,
+		Paths:   []string{t.TempDir()},
+		MaxSize: "1G",
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &WorkspaceProvider{}, p)
+}